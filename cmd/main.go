@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
 	"time"
+	_ "time/tzdata" // embed the zone database so due_timezone works on minimal images
 	"todo-list/config"
 	"todo-list/internal/handlers"
+	"todo-list/internal/models"
 	"todo-list/internal/repos"
 	"todo-list/internal/services"
 	"todo-list/pkg/database"
+	"todo-list/pkg/signedtoken"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-chi/chi/v5"
@@ -32,9 +37,102 @@ func main() {
 
 	todoRepo := repos.NewTodoRepository(db)
 	userRepo := repos.NewUserRepository(db)
-	todoService := services.NewTodoService(todoRepo)
-	todoHandler := handlers.NewTodoHandler(todoService)
-	authHandler := handlers.NewAuthHandler(userRepo, sessionManager)
+	listRepo := repos.NewListRepository(db)
+	todoService := services.NewTodoService(todoRepo, listRepo)
+	orgRepo := repos.NewOrganizationRepository(db)
+	shareService := services.NewShareService(repos.NewShareRepository(db), userRepo, orgRepo)
+	todoHandler := handlers.NewTodoHandler(todoService, shareService)
+	tagRepo := repos.NewTagRepository(db)
+	tagService := services.NewTagService(tagRepo)
+	tagHandler := handlers.NewTagHandler(tagService, shareService)
+	listService := services.NewListService(listRepo)
+	listHandler := handlers.NewListHandler(listService, todoService, shareService)
+	commentHandler := handlers.NewCommentHandler(services.NewCommentService(repos.NewCommentRepository(db)), todoService, shareService)
+	blobStore, err := config.BlobStoreFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	attachmentService := services.NewAttachmentService(repos.NewAttachmentRepository(db), blobStore,
+		config.SizeFromEnv("MAX_ATTACHMENT_SIZE", services.DefaultMaxAttachmentSize))
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, todoService, shareService)
+	workflowService := services.NewWorkflowService(repos.NewWorkflowRepository(db), todoRepo)
+	workflowHandler := handlers.NewWorkflowHandler(workflowService, listService, shareService)
+	shareHandler := handlers.NewShareHandler(shareService, todoService, listService)
+	notificationService := services.NewNotificationService(repos.NewNotificationRepository(db))
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	assignmentService := services.NewAssignmentService(todoService, shareService, userRepo, notificationService)
+	assignmentHandler := handlers.NewAssignmentHandler(assignmentService, todoService, shareService)
+	organizationHandler := handlers.NewOrganizationHandler(services.NewOrganizationService(orgRepo, userRepo), sessionManager)
+	// TOTP_ISSUER is the name authenticator apps show next to the username
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Todo List"
+	}
+	twoFactorService := services.NewTwoFactorService(repos.NewTwoFactorRepository(db), issuer)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, userRepo)
+	mailer, err := config.MailerFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	emailTokenSecret, err := config.EmailTokenSecretFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	accountService := services.NewAccountService(userRepo, mailer, signedtoken.New(emailTokenSecret))
+	authHandler := handlers.NewAuthHandler(userRepo, sessionManager, twoFactorService, accountService)
+	tokenService := services.NewTokenService(repos.NewTokenRepository(db), userRepo)
+	tokenHandler := handlers.NewTokenHandler(tokenService)
+
+	// AUTH_MODE=jwt replaces the session cookie by short-lived access tokens and refresh
+	// tokens, so requests are authenticated without a database lookup. auth also accepts
+	// personal access tokens, loggedIn only a login.
+	auth := func(next http.HandlerFunc) http.HandlerFunc {
+		return config.AuthMiddleware(next, sessionManager, tokenService)
+	}
+	loggedIn := func(next http.HandlerFunc) http.HandlerFunc {
+		return config.SessionMiddleware(next, sessionManager)
+	}
+	login, logout := authHandler.Login(), authHandler.Logout()
+	var jwtService *services.JWTService
+	var jwtHandler *handlers.JWTHandler
+	switch mode := os.Getenv("AUTH_MODE"); mode {
+	case "", "session":
+	case "jwt":
+		keys, err := config.JWTKeysFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		jwtService = services.NewJWTService(repos.NewRefreshTokenRepository(db), userRepo, keys,
+			config.DurationFromEnv("JWT_ACCESS_TTL", 15*time.Minute),
+			config.DurationFromEnv("JWT_REFRESH_TTL", 30*24*time.Hour))
+		jwtHandler = handlers.NewJWTHandler(jwtService, userRepo, twoFactorService)
+		auth = func(next http.HandlerFunc) http.HandlerFunc {
+			return config.JWTMiddleware(next, jwtService, tokenService)
+		}
+		loggedIn = func(next http.HandlerFunc) http.HandlerFunc {
+			return config.JWTMiddleware(next, jwtService, nil)
+		}
+		login, logout = jwtHandler.Login(), jwtHandler.Logout()
+	default:
+		log.Fatalf("unknown AUTH_MODE %q, expected session or jwt", mode)
+	}
+	adminHandler := handlers.NewAdminHandler(services.NewAdminService(userRepo), sessionManager, jwtService)
+	accountHandler := handlers.NewAccountHandler(accountService, userRepo, sessionManager, jwtService)
+
+	// users named in ADMIN_USERS (comma separated) get the admin role
+	for _, username := range config.ListFromEnv("ADMIN_USERS") {
+		if err := userRepo.SetRole(username, models.RoleAdmin); err != nil {
+			log.Printf("failed to make %q an admin: %v", username, err)
+		}
+	}
+
+	// permanently delete todos that have been in the trash for longer than TRASH_RETENTION
+	trashRetention := config.DurationFromEnv("TRASH_RETENTION", 30*24*time.Hour)
+	go todoService.RunTrashPurge(context.Background(), trashRetention, time.Hour)
+	// spread the positions of lists out again once manual ordering made them too long
+	go todoService.RunPositionRebalance(context.Background(), time.Hour)
+	// delete the files attached to todos that were purged
+	go attachmentService.RunAttachmentCleanup(context.Background(), time.Hour)
 
 	// Set up router
 	r := chi.NewRouter()
@@ -44,14 +142,107 @@ func main() {
 
 	// Routes
 	r.Post("/register", authHandler.Register())
-	r.Post("/login", authHandler.Login())
-	r.Post("/logout", authHandler.Logout())
-	r.Get("/home", config.SessionMiddleware(handlers.Home(), sessionManager))
-	r.Get("/todos", config.SessionMiddleware(todoHandler.GetTodos(), sessionManager))
-	r.Post("/todos", config.SessionMiddleware(todoHandler.CreateTodo(), sessionManager))
-	r.Put("/todos/{id}", config.SessionMiddleware(todoHandler.UpdateTodo(), sessionManager))
-	r.Delete("/todos/{id}", config.SessionMiddleware(todoHandler.DeleteTodo(), sessionManager))
-	r.Get("/todos/{id}", config.SessionMiddleware(todoHandler.GetTodo(), sessionManager))
+	r.Post("/login", login)
+	r.Post("/logout", logout)
+	r.Post("/email/verify", accountHandler.VerifyEmail())
+	r.Post("/password/forgot", accountHandler.ForgotPassword())
+	r.Post("/password/reset", accountHandler.ResetPassword())
+	if jwtHandler != nil {
+		r.Post("/token/refresh", jwtHandler.Refresh())
+	} else {
+		r.Post("/login/2fa", authHandler.LoginTwoFactor())
+	}
+	r.Get("/home", auth(handlers.Home()))
+	r.Get("/todos", auth(todoHandler.GetTodos()))
+	r.Post("/todos", auth(todoHandler.CreateTodo()))
+	r.Get("/todos/search", auth(todoHandler.SearchTodos()))
+	r.Post("/todos/bulk", auth(todoHandler.BulkTodos()))
+	r.Get("/todos/next", auth(todoHandler.NextTodos()))
+	r.Put("/todos/{id}", auth(todoHandler.UpdateTodo()))
+	r.Delete("/todos/{id}", auth(todoHandler.DeleteTodo()))
+	r.Get("/todos/{id}", auth(todoHandler.GetTodo()))
+	r.Get("/todos/{id}/subtree", auth(todoHandler.GetSubtree()))
+	r.Post("/todos/{id}/move", auth(todoHandler.MoveTodo()))
+	r.Get("/todos/{id}/history", auth(todoHandler.GetHistory()))
+	r.Get("/todos/{id}/dependencies", auth(todoHandler.GetDependencies()))
+	r.Post("/todos/{id}/dependencies", auth(todoHandler.AddDependency()))
+	r.Delete("/todos/{id}/dependencies/{blockerId}", auth(todoHandler.RemoveDependency()))
+	r.Get("/todos/{id}/comments", auth(commentHandler.GetComments()))
+	r.Post("/todos/{id}/comments", auth(commentHandler.CreateComment()))
+	r.Put("/todos/{id}/comments/{commentId}", auth(commentHandler.UpdateComment()))
+	r.Delete("/todos/{id}/comments/{commentId}", auth(commentHandler.DeleteComment()))
+	r.Get("/todos/{id}/attachments", auth(attachmentHandler.GetAttachments()))
+	r.Post("/todos/{id}/attachments", auth(attachmentHandler.UploadAttachment()))
+	r.Get("/todos/{id}/attachments/{attachmentId}", auth(attachmentHandler.DownloadAttachment()))
+	r.Delete("/todos/{id}/attachments/{attachmentId}", auth(attachmentHandler.DeleteAttachment()))
+	r.Get("/todos/{id}/shares", auth(shareHandler.GetTodoShares()))
+	r.Put("/todos/{id}/shares/{username}", auth(shareHandler.ShareTodo()))
+	r.Delete("/todos/{id}/shares/{username}", auth(shareHandler.UnshareTodo()))
+	r.Put("/todos/{id}/assignee", auth(assignmentHandler.AssignTodo()))
+	r.Delete("/todos/{id}/assignee", auth(assignmentHandler.UnassignTodo()))
+	r.Get("/me/assigned", auth(assignmentHandler.GetAssigned()))
+	r.Get("/me/notifications", auth(notificationHandler.GetNotifications()))
+	r.Post("/me/notifications/read", auth(notificationHandler.MarkAllRead()))
+	r.Post("/me/notifications/{id}/read", auth(notificationHandler.MarkRead()))
+	r.Get("/orgs", auth(organizationHandler.GetOrganizations()))
+	r.Post("/orgs", auth(organizationHandler.CreateOrganization()))
+	r.Get("/orgs/{id}", auth(organizationHandler.GetOrganization()))
+	r.Get("/orgs/{id}/members", auth(organizationHandler.GetMembers()))
+	r.Put("/orgs/{id}/members/{username}", auth(organizationHandler.SetMemberRole()))
+	r.Delete("/orgs/{id}/members/{username}", auth(organizationHandler.RemoveMember()))
+	r.Post("/orgs/{id}/invitations", auth(organizationHandler.Invite()))
+	r.Get("/me/invitations", auth(organizationHandler.GetInvitations()))
+	r.Post("/me/invitations/{id}/accept", auth(organizationHandler.AcceptInvitation()))
+	r.Delete("/me/invitations/{id}", auth(organizationHandler.DeclineInvitation()))
+	r.Get("/me/org", auth(organizationHandler.GetActiveOrganization()))
+	r.Put("/me/org", auth(organizationHandler.SwitchOrganization()))
+	r.Get("/trash", auth(todoHandler.GetTrash()))
+	r.Post("/trash/{id}/restore", auth(todoHandler.RestoreTodo()))
+	r.Delete("/trash/{id}", auth(todoHandler.PurgeTodo()))
+	r.Post("/undo", auth(todoHandler.Undo()))
+	r.Post("/redo", auth(todoHandler.Redo()))
+	r.Get("/tags", auth(tagHandler.GetTags()))
+	r.Post("/tags", auth(tagHandler.CreateTag()))
+	r.Get("/tags/{id}", auth(tagHandler.GetTag()))
+	r.Put("/tags/{id}", auth(tagHandler.UpdateTag()))
+	r.Delete("/tags/{id}", auth(tagHandler.DeleteTag()))
+	r.Get("/lists", auth(listHandler.GetLists()))
+	r.Post("/lists", auth(listHandler.CreateList()))
+	r.Get("/lists/{id}", auth(listHandler.GetList()))
+	r.Put("/lists/{id}", auth(listHandler.UpdateList()))
+	r.Delete("/lists/{id}", auth(listHandler.DeleteList()))
+	r.Get("/lists/{id}/todos", auth(listHandler.GetListTodos()))
+	r.Post("/lists/{id}/todos", auth(listHandler.CreateListTodo()))
+	r.Get("/lists/{id}/shares", auth(shareHandler.GetListShares()))
+	r.Put("/lists/{id}/shares/{username}", auth(shareHandler.ShareList()))
+	r.Delete("/lists/{id}/shares/{username}", auth(shareHandler.UnshareList()))
+	r.Get("/workflow", auth(workflowHandler.GetWorkflow()))
+	r.Put("/workflow", auth(workflowHandler.UpdateWorkflow()))
+	r.Get("/board", auth(workflowHandler.GetBoard()))
+	// tokens and two-factor authentication are managed with a login only, so a leaked token cannot create more
+	r.Get("/me/tokens", loggedIn(tokenHandler.GetTokens()))
+	r.Post("/me/tokens", loggedIn(tokenHandler.CreateToken()))
+	r.Delete("/me/tokens/{id}", loggedIn(tokenHandler.DeleteToken()))
+	r.Get("/me/2fa", loggedIn(twoFactorHandler.GetStatus()))
+	r.Post("/me/2fa/enroll", loggedIn(twoFactorHandler.Enroll()))
+	r.Post("/me/2fa/enable", loggedIn(twoFactorHandler.Enable()))
+	r.Post("/me/2fa/disable", loggedIn(twoFactorHandler.Disable()))
+	r.Post("/me/2fa/recovery-codes", loggedIn(twoFactorHandler.RegenerateRecoveryCodes()))
+	r.Put("/me/email", loggedIn(accountHandler.SetEmail()))
+	r.Post("/me/email/verification", loggedIn(accountHandler.ResendVerification()))
+
+	// Admin routes
+	r.Group(func(r chi.Router) {
+		admin := func(next http.HandlerFunc) http.HandlerFunc {
+			return loggedIn(config.AdminMiddleware(next, userRepo))
+		}
+		r.Get("/admin/users", admin(adminHandler.GetUsers()))
+		r.Get("/admin/users/{id}", admin(adminHandler.GetUser()))
+		r.Post("/admin/users/{id}/disable", admin(adminHandler.DisableUser()))
+		r.Post("/admin/users/{id}/enable", admin(adminHandler.EnableUser()))
+		r.Post("/admin/users/{id}/logout", admin(adminHandler.LogoutUser()))
+		r.Post("/admin/users/{id}/password", admin(adminHandler.ResetPassword()))
+	})
 
 	// Start the server
 	log.Println("Server is running on http://localhost:8080")
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"net/http"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+)
+
+// AdminMiddleware ensures the authenticated user is an admin, it goes behind the
+// middleware that sets "userID". The role is read from the database on every request
+// so taking it away takes effect right away.
+func AdminMiddleware(next http.HandlerFunc, userRepo *repos.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var user models.User
+		if err := userRepo.GetUserByID(userID, &user); err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if user.Role != models.RoleAdmin || user.DisabledAt != nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
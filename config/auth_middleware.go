@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// AuthMiddleware ensures the user is authenticated, either by a personal access token
+// sent as "Authorization: Bearer <token>" or by the session cookie. Requests with a
+// token set the same "userID" as SessionMiddleware and work on the personal todos.
+func AuthMiddleware(next http.HandlerFunc, sessionManager *scs.SessionManager, tokens *services.TokenService) http.HandlerFunc {
+	withSession := SessionMiddleware(next, sessionManager)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			withSession(w, r)
+			return
+		}
+		raw, ok := bearerToken(w, r)
+		if !ok {
+			return
+		}
+		personalToken(next, tokens, raw, w, r)
+	}
+}
+
+// JWTMiddleware ensures the user is authenticated by an access token of the jwt auth mode.
+// Access tokens are checked by their signature only, so requests do not hit the database.
+// The organization to work on is sent in the X-Org-ID header. Personal access tokens are
+// accepted as well unless tokens is nil.
+func JWTMiddleware(next http.HandlerFunc, jwtService *services.JWTService, tokens *services.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := bearerToken(w, r)
+		if !ok {
+			return
+		}
+		if services.IsPersonalToken(raw) {
+			if tokens == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			personalToken(next, tokens, raw, w, r)
+			return
+		}
+
+		userID, err := jwtService.Verify(raw)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ctx := context.WithValue(r.Context(), "userID", userID)
+		if value := r.Header.Get("X-Org-ID"); value != "" {
+			orgID, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || orgID == 0 {
+				http.Error(w, "Invalid X-Org-ID header", http.StatusBadRequest)
+				return
+			}
+			ctx = context.WithValue(ctx, "orgID", uint(orgID))
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// bearerToken reads the token of the Authorization header, answering 401 when there is none
+func bearerToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	raw, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	raw = strings.TrimSpace(raw)
+	if !found || raw == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	return raw, true
+}
+
+// personalToken authenticates a request by a personal access token, read tokens may only make GET requests
+func personalToken(next http.HandlerFunc, tokens *services.TokenService, raw string, w http.ResponseWriter, r *http.Request) {
+	token, err := tokens.Authenticate(raw)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to check the token", http.StatusInternalServerError)
+		return
+	}
+	if token.Scope != models.TokenWrite && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Token does not allow changes", http.StatusForbidden)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "userID", token.UserID)
+	next(w, r.WithContext(ctx))
+}
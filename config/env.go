@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"todo-list/pkg/jwt"
+)
+
+// DurationFromEnv reads a duration like "72h" or a number of days like "30d"
+// from an environment variable, fallback is used when it is unset or invalid
+func DurationFromEnv(name string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	log.Printf("invalid %s %q, using %s", name, value, fallback)
+	return fallback
+}
+
+// SizeFromEnv reads a number of bytes like "1048576", "512KB" or "10MB" from an
+// environment variable, fallback is used when it is unset or invalid
+func SizeFromEnv(name string, fallback int64) int64 {
+	value := strings.ToUpper(strings.TrimSpace(os.Getenv(name)))
+	if value == "" {
+		return fallback
+	}
+	unit := int64(1)
+	for suffix, size := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			value, unit = strings.TrimSpace(number), size
+			break
+		}
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+		return n * unit
+	}
+	log.Printf("invalid %s %q, using %d bytes", name, os.Getenv(name), fallback)
+	return fallback
+}
+
+// ListFromEnv reads a comma separated list from an environment variable,
+// empty entries are dropped
+func ListFromEnv(name string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// JWTKeysFromEnv reads the keys of the jwt auth mode from JWT_KEYS, a comma separated list
+// of kid:algorithm:base64 entries like "2025-01:EdDSA:<32 byte seed>,2024-07:HS256:<secret>".
+// The first key signs new tokens, the others only verify tokens issued before a rotation.
+func JWTKeysFromEnv() (*jwt.KeySet, error) {
+	var keys []jwt.Key
+	for _, entry := range ListFromEnv("JWT_KEYS") {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid:algorithm:base64", entry)
+		}
+		material, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 in JWT_KEYS entry of key %q: %w", parts[0], err)
+		}
+		var key jwt.Key
+		switch parts[1] {
+		case jwt.HS256:
+			key, err = jwt.NewHS256Key(parts[0], material)
+		case jwt.EdDSA:
+			key, err = jwt.NewEdDSAKey(parts[0], material)
+		default:
+			err = fmt.Errorf("unsupported algorithm %q in JWT_KEYS, expected HS256 or EdDSA", parts[1])
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("AUTH_MODE=jwt needs at least one key in JWT_KEYS")
+	}
+	return jwt.NewKeySet(keys[0], keys[1:]...), nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"os"
+	"todo-list/pkg/mail"
+)
+
+// MailerFromEnv creates the mailer selected by MAIL_BACKEND. "log" (the default) writes
+// mails to the log, "file" stores them as .eml files below MAIL_DIR and "smtp" sends them
+// to SMTP_ADDR, logging in with SMTP_USERNAME and SMTP_PASSWORD when they are set. Mails
+// come from MAIL_FROM.
+func MailerFromEnv() (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "todo-list@localhost"
+	}
+	switch backend := os.Getenv("MAIL_BACKEND"); backend {
+	case "", "log":
+		return mail.NewLogMailer(log.Default()), nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "data/mail"
+		}
+		return mail.NewFileMailer(dir, from), nil
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			return nil, fmt.Errorf("MAIL_BACKEND=smtp needs SMTP_ADDR, e.g. smtp.example.com:587")
+		}
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Addr:     addr,
+			From:     from,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_BACKEND %q, expected log, file or smtp", backend)
+	}
+}
+
+// EmailTokenSecretFromEnv reads the secret that signs the tokens of verification and
+// password reset emails from EMAIL_TOKEN_SECRET. Without one a random secret is used,
+// so mailed tokens stop working when the server restarts.
+func EmailTokenSecretFromEnv() ([]byte, error) {
+	if secret := os.Getenv("EMAIL_TOKEN_SECRET"); secret != "" {
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("EMAIL_TOKEN_SECRET needs at least 32 characters")
+		}
+		return []byte(secret), nil
+	}
+	log.Println("EMAIL_TOKEN_SECRET is not set, mailed tokens stop working on restart")
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	return secret, err
+}
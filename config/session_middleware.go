@@ -18,6 +18,10 @@ func SessionMiddleware(next http.HandlerFunc, sessionManager *scs.SessionManager
 		// set the userID in the request context so that it can be used to create TODO items.
 		sessionUserID := sessionManager.Get(r.Context(), "userID")
 		ctx := context.WithValue(r.Context(), "userID", sessionUserID)
+		// the organization the user switched to, requests without one work on the personal todos
+		if orgID, ok := sessionManager.Get(r.Context(), "orgID").(uint); ok && orgID != 0 {
+			ctx = context.WithValue(ctx, "orgID", orgID)
+		}
 		next(w, r.WithContext(ctx))
 	}
 }
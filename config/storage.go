@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"todo-list/pkg/storage"
+)
+
+// BlobStoreFromEnv creates the blob store selected by STORAGE_BACKEND. "local" (the default)
+// keeps files below STORAGE_DIR, "s3" uses the bucket S3_BUCKET at S3_ENDPOINT with
+// S3_REGION, S3_ACCESS_KEY, S3_SECRET_KEY and S3_PATH_STYLE.
+func BlobStoreFromEnv() (storage.BlobStore, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "data/attachments"
+		}
+		return storage.NewLocalStore(dir), nil
+	case "s3":
+		pathStyle, _ := strconv.ParseBool(os.Getenv("S3_PATH_STYLE"))
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PathStyle: pathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q, expected local or s3", backend)
+	}
+}
@@ -4,7 +4,6 @@ go 1.23.4
 
 require (
 	github.com/alexedwards/scs/v2 v2.8.0
-	github.com/go-chi/chi v1.5.5
 	github.com/go-chi/chi/v5 v5.1.0
 	github.com/stretchr/testify v1.8.1
 	golang.org/x/crypto v0.30.0
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// AccountHandler serves email verification and the password reset of users who forgot
+// their password. jwt is nil unless the jwt auth mode is used.
+type AccountHandler struct {
+	service        *services.AccountService
+	userRepo       *repos.UserRepository
+	sessionManager *scs.SessionManager
+	jwt            *services.JWTService
+}
+
+func NewAccountHandler(service *services.AccountService, userRepo *repos.UserRepository, sessionManager *scs.SessionManager, jwt *services.JWTService) *AccountHandler {
+	return &AccountHandler{service, userRepo, sessionManager, jwt}
+}
+
+// SetEmail changes the email address of the logged-in user and mails a verification token,
+// PUT /me/email {"email": "alice@example.com"}
+func (h *AccountHandler) SetEmail() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.currentUser(w, r)
+		if !ok {
+			return
+		}
+		var input struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.SetEmail(r.Context(), &user, input.Email); err != nil {
+			writeAccountError(w, err, "Failed to change email")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// ResendVerification mails a new verification token to the address of the logged-in user
+func (h *AccountHandler) ResendVerification() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.service.SendVerification(r.Context(), &user); err != nil {
+			writeAccountError(w, err, "Failed to send verification email")
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// VerifyEmail confirms an address with the mailed token, POST /email/verify {"token": "..."}
+func (h *AccountHandler) VerifyEmail() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.service.VerifyEmail(input.Token)
+		if err != nil {
+			writeAccountError(w, err, "Failed to verify email")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// ForgotPassword mails a reset token to a verified address, POST /password/forgot {"email": "..."}.
+// The answer is 202 whether or not there is a user with the address.
+func (h *AccountHandler) ForgotPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.ForgotPassword(r.Context(), input.Email); err != nil {
+			http.Error(w, "Failed to send password reset", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// ResetPassword sets a new password with a mailed token and ends every session of the user,
+// POST /password/reset {"token": "...", "password": "..."}
+func (h *AccountHandler) ResetPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.service.ResetPassword(input.Token, input.Password)
+		if err != nil {
+			writeAccountError(w, err, "Failed to reset password")
+			return
+		}
+		if _, err := endSessions(r.Context(), h.sessionManager, h.jwt, user.ID); err != nil {
+			http.Error(w, "Failed to end the sessions of the user", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *AccountHandler) currentUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	var user models.User
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return user, false
+	}
+	if err := h.userRepo.GetUserByID(userID, &user); err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return user, false
+	}
+	return user, true
+}
+
+func writeAccountError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidEmail):
+		http.Error(w, "Invalid email", http.StatusBadRequest)
+	case errors.Is(err, services.ErrEmailTaken):
+		http.Error(w, "Email is taken", http.StatusConflict)
+	case errors.Is(err, services.ErrNoEmail):
+		http.Error(w, "No email address set", http.StatusBadRequest)
+	case errors.Is(err, services.ErrEmailVerified):
+		http.Error(w, "Email is verified already", http.StatusConflict)
+	case errors.Is(err, services.ErrInvalidAccountToken):
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+	case errors.Is(err, services.ErrEmptyPassword):
+		http.Error(w, "Password is required", http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
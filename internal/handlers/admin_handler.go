@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminHandler serves the /admin API, its routes are wrapped in config.AdminMiddleware.
+// jwt is nil unless the jwt auth mode is used.
+type AdminHandler struct {
+	service        *services.AdminService
+	sessionManager *scs.SessionManager
+	jwt            *services.JWTService
+}
+
+func NewAdminHandler(service *services.AdminService, sessionManager *scs.SessionManager, jwt *services.JWTService) *AdminHandler {
+	return &AdminHandler{service, sessionManager, jwt}
+}
+
+// GetUsers lists the users in id order, GET /admin/users?q=ali&limit=50&after=120.
+// q searches the usernames, after continues behind the last id of the previous page.
+func (h *AdminHandler) GetUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit := repos.DefaultPageLimit
+		if v := query.Get("limit"); v != "" {
+			var err error
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > repos.MaxPageLimit {
+				http.Error(w, fmt.Sprintf("Invalid limit, expected a number between 1 and %d", repos.MaxPageLimit), http.StatusBadRequest)
+				return
+			}
+		}
+		var after uint64
+		if v := query.Get("after"); v != "" {
+			var err error
+			if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+				http.Error(w, "Invalid after, expected a user id", http.StatusBadRequest)
+				return
+			}
+		}
+
+		users, err := h.service.GetUsers(query.Get("q"), uint(after), limit)
+		if err != nil {
+			http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(users)
+	}
+}
+
+// GetUser returns a single user
+func (h *AdminHandler) GetUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.userFromURL(w, r)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// DisableUser keeps a user from logging in and ends their sessions
+func (h *AdminHandler) DisableUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.userFromURL(w, r)
+		if !ok {
+			return
+		}
+		adminID, _ := r.Context().Value("userID").(uint)
+
+		if err := h.service.DisableUser(adminID, &user); err != nil {
+			if errors.Is(err, services.ErrDisableSelf) {
+				http.Error(w, "Admins cannot disable themselves", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to disable user", http.StatusInternalServerError)
+			return
+		}
+		if _, err := endSessions(r.Context(), h.sessionManager, h.jwt, user.ID); err != nil {
+			http.Error(w, "Failed to end the sessions of the user", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// EnableUser lets a disabled user log in again
+func (h *AdminHandler) EnableUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.userFromURL(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.service.EnableUser(&user); err != nil {
+			http.Error(w, "Failed to enable user", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// LogoutUser ends every session of a user, the response tells how many there were
+func (h *AdminHandler) LogoutUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.userFromURL(w, r)
+		if !ok {
+			return
+		}
+
+		ended, err := endSessions(r.Context(), h.sessionManager, h.jwt, user.ID)
+		if err != nil {
+			http.Error(w, "Failed to end the sessions of the user", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"sessions": ended})
+	}
+}
+
+// ResetPassword sets a new password for a user and ends their sessions,
+// POST /admin/users/{id}/password {"password": "..."}
+func (h *AdminHandler) ResetPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.userFromURL(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.ResetPassword(&user, input.Password); err != nil {
+			if errors.Is(err, services.ErrEmptyPassword) {
+				http.Error(w, "Password is required", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+			return
+		}
+		if _, err := endSessions(r.Context(), h.sessionManager, h.jwt, user.ID); err != nil {
+			http.Error(w, "Failed to end the sessions of the user", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// userFromURL loads the user of the {id} URL parameter
+func (h *AdminHandler) userFromURL(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return models.User{}, false
+	}
+	user, err := h.service.GetUser(uint(id))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return user, false
+	}
+	return user, true
+}
+
+// endSessions destroys every session of a user in the session store, and in the jwt auth
+// mode revokes their refresh tokens, and returns how many there were. jwt is nil otherwise.
+func endSessions(ctx context.Context, sessionManager *scs.SessionManager, jwt *services.JWTService, userID uint) (int, error) {
+	ended := 0
+	err := sessionManager.Iterate(ctx, func(ctx context.Context) error {
+		if id, ok := sessionManager.Get(ctx, "userID").(uint); !ok || id != userID {
+			return nil
+		}
+		ended++
+		return sessionManager.Destroy(ctx)
+	})
+	if err != nil || jwt == nil {
+		return ended, err
+	}
+	revoked, err := jwt.RevokeUser(userID)
+	return ended + revoked, err
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+)
+
+type AssignmentHandler struct {
+	service     *services.AssignmentService
+	todoService *services.TodoService
+	shares      *services.ShareService
+}
+
+func NewAssignmentHandler(service *services.AssignmentService, todoService *services.TodoService, shares *services.ShareService) *AssignmentHandler {
+	return &AssignmentHandler{service, todoService, shares}
+}
+
+// AssignTodo gives a todo to another user, PUT /todos/{id}/assignee {"username": "alice"}
+func (h *AssignmentHandler) AssignTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Username string `json:"username"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Username == "" {
+			http.Error(w, "Missing username", http.StatusBadRequest)
+			return
+		}
+		h.assign(w, r, input.Username)
+	}
+}
+
+// UnassignTodo removes the assignee of a todo
+func (h *AssignmentHandler) UnassignTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.assign(w, r, "")
+	}
+}
+
+// GetAssigned lists the todos assigned to the authenticated user in the personal todos or
+// the active organization, accepting the same query parameters as GET /todos
+func (h *AssignmentHandler) GetAssigned() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := requestScope(w, r, h.shares)
+		if !ok {
+			return
+		}
+		filter, err := parseTodoFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := h.service.GetAssigned(scope, filter)
+		if errors.Is(err, repos.ErrInvalidCursor) {
+			http.Error(w, "Cursor does not match the requested sort order", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch todos", http.StatusInternalServerError)
+			return
+		}
+
+		writeTodoPage(w, r, page)
+	}
+}
+
+func (h *AssignmentHandler) assign(w http.ResponseWriter, r *http.Request, username string) {
+	todo, userID, ok := todoFromURL(w, r, h.shares, h.todoService.GetTodo, "Todo not found", services.PermissionView)
+	if !ok {
+		return
+	}
+
+	err := h.service.Assign(userID, &todo, username)
+	switch {
+	case errors.Is(err, services.ErrUnknownUser):
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrAssigneeNoAccess):
+		http.Error(w, "The assignee cannot see this todo, share it with them first", http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrReassign):
+		http.Error(w, "Only the owner or the current assignee may reassign this todo", http.StatusForbidden)
+		return
+	case err != nil:
+		writeTodoError(w, err, "Failed to assign todo")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(todo)
+}
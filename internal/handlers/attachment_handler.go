@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+	"todo-list/pkg/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AttachmentHandler struct {
+	service     *services.AttachmentService
+	todoService *services.TodoService
+	shares      *services.ShareService
+}
+
+func NewAttachmentHandler(service *services.AttachmentService, todoService *services.TodoService, shares *services.ShareService) *AttachmentHandler {
+	return &AttachmentHandler{service, todoService, shares}
+}
+
+// GetAttachments lists the attachments of a todo
+func (h *AttachmentHandler) GetAttachments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, _, ok := todoFromURL(w, r, h.shares, h.todoService.GetTodo, "Todo not found", services.PermissionView)
+		if !ok {
+			return
+		}
+
+		attachments, err := h.service.GetAttachments(todo.ID)
+		if err != nil {
+			http.Error(w, "Failed to fetch attachments", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(attachments)
+	}
+}
+
+// UploadAttachment stores the multipart form field "file" as an attachment of a todo
+func (h *AttachmentHandler) UploadAttachment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, userID, ok := todoFromURL(w, r, h.shares, h.todoService.GetTodo, "Todo not found", services.PermissionEdit)
+		if !ok {
+			return
+		}
+
+		// leave room for the multipart headers around the file
+		r.Body = http.MaxBytesReader(w, r.Body, h.service.MaxSize()+1<<20)
+		reader, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, "Expected a multipart/form-data upload", http.StatusBadRequest)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				http.Error(w, "Missing file field", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				writeUploadError(w, err)
+				return
+			}
+			if part.FormName() != "file" {
+				part.Close()
+				continue
+			}
+
+			attachment := models.Attachment{TodoID: todo.ID, UploaderID: userID, FileName: fileName(part.FileName())}
+			if err := h.service.AddAttachment(r.Context(), &attachment, part); err != nil {
+				writeUploadError(w, err)
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(attachment)
+			return
+		}
+	}
+}
+
+// DownloadAttachment returns the content of an attachment
+func (h *AttachmentHandler) DownloadAttachment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		attachment, ok := h.attachmentFromURL(w, r, services.PermissionView)
+		if !ok {
+			return
+		}
+
+		content, err := h.service.OpenAttachment(r.Context(), &attachment)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Attachment content not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch attachment", http.StatusInternalServerError)
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", attachment.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		io.Copy(w, content)
+	}
+}
+
+// DeleteAttachment removes an attachment and its content
+func (h *AttachmentHandler) DeleteAttachment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		attachment, ok := h.attachmentFromURL(w, r, services.PermissionEdit)
+		if !ok {
+			return
+		}
+
+		if err := h.service.RemoveAttachment(r.Context(), &attachment); err != nil {
+			http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// attachmentFromURL loads the attachment of the {attachmentId} URL parameter on a todo
+// the logged-in user has the permission on
+func (h *AttachmentHandler) attachmentFromURL(w http.ResponseWriter, r *http.Request, need services.Permission) (models.Attachment, bool) {
+	todo, _, ok := todoFromURL(w, r, h.shares, h.todoService.GetTodo, "Todo not found", need)
+	if !ok {
+		return models.Attachment{}, false
+	}
+
+	attachment, err := h.service.GetAttachment(todo.ID, chi.URLParam(r, "attachmentId"))
+	if err != nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return attachment, false
+	}
+	return attachment, true
+}
+
+func writeUploadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, services.ErrAttachmentTooLarge), errors.As(err, &tooLarge):
+		http.Error(w, "Attachment is too large", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, services.ErrAttachmentType):
+		http.Error(w, fmt.Sprintf("Unsupported attachment type, expected one of %s", strings.Join(services.AttachmentTypes, ", ")), http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, "Failed to upload attachment", http.StatusInternalServerError)
+	}
+}
+
+// fileName keeps the base name of an uploaded file, clients may send full paths
+func fileName(name string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
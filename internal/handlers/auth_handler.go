@@ -2,37 +2,60 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 	"todo-list/internal/models"
 	"todo-list/internal/repos"
+	"todo-list/internal/services"
 
 	"github.com/alexedwards/scs/v2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// pendingLoginTimeout is how long a user has to send the code after the password
+const pendingLoginTimeout = 5 * time.Minute
+
+// maxCodeAttempts is how many wrong codes a pending login may send before the password is asked again
+const maxCodeAttempts = 5
+
 type AuthHandler struct {
 	userRepo       *repos.UserRepository
 	sessionManager *scs.SessionManager
+	twoFactor      *services.TwoFactorService
+	accounts       *services.AccountService
 }
 
-func NewAuthHandler(userRepo *repos.UserRepository, sessionManager *scs.SessionManager) *AuthHandler {
-	return &AuthHandler{userRepo, sessionManager}
+func NewAuthHandler(userRepo *repos.UserRepository, sessionManager *scs.SessionManager, twoFactor *services.TwoFactorService, accounts *services.AccountService) *AuthHandler {
+	return &AuthHandler{userRepo, sessionManager, twoFactor, accounts}
 }
 
-// Register creates a new user
+// Register creates a new user. An optional email address gets a verification token mailed.
 func (h *AuthHandler) Register() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var user models.User
 		var creds struct {
 			Username string `json:"username"`
 			Password string `json:"password"`
+			Email    string `json:"email"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
 			http.Error(w, "Invalid input", http.StatusBadRequest)
 			return
 		}
+		if creds.Email != "" {
+			email, err := services.NormalizeEmail(creds.Email)
+			if err == nil {
+				err = h.accounts.CheckEmailAvailable(email, 0)
+			}
+			if err != nil {
+				writeAccountError(w, err, "Failed to create user")
+				return
+			}
+			user.Email = &email
+		}
 
 		// Hash the password
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(creds.Password), bcrypt.DefaultCost)
@@ -49,6 +72,11 @@ func (h *AuthHandler) Register() http.HandlerFunc {
 			http.Error(w, "Failed to create user", http.StatusInternalServerError)
 			return
 		}
+		if user.Email != nil {
+			if err := h.accounts.SendVerification(r.Context(), &user); err != nil {
+				log.Printf("failed to mail the email verification of user %d: %v", user.ID, err)
+			}
+		}
 
 		w.WriteHeader(http.StatusCreated)
 		fmt.Fprintln(w, "User registered successfully!")
@@ -62,51 +90,149 @@ func Home() http.HandlerFunc {
 	}
 }
 
-// Login authenticates a user and starts a session
+// Login authenticates a user and starts a session. Users with two-factor authentication
+// get 202 and a session that is only logged in once LoginTwoFactor checked their code.
 func (h *AuthHandler) Login() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var creds struct {
-			Username string `json:"username"`
-			Password string `json:"password"`
+		user, _, ok := checkCredentials(w, r, h.userRepo)
+		if !ok {
+			return
 		}
-		if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+
+		if user.TOTPEnabled {
+			if err := h.sessionManager.RenewToken(r.Context()); err != nil {
+				http.Error(w, "Failed to start session", http.StatusInternalServerError)
+				return
+			}
+			h.sessionManager.Remove(r.Context(), "username")
+			h.sessionManager.Remove(r.Context(), "userID")
+			h.sessionManager.Put(r.Context(), "pendingUserID", user.ID)
+			h.sessionManager.Put(r.Context(), "pendingSince", time.Now().Unix())
+			h.sessionManager.Put(r.Context(), "pendingAttempts", 0)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]bool{"two_factor_required": true})
+			return
+		}
+
+		// Start session
+		h.sessionManager.Put(r.Context(), "username", user.Username)
+		h.sessionManager.Put(r.Context(), "userID", user.ID)
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "Login successful!")
+	}
+}
+
+// LoginTwoFactor is the second step of the login of users with two-factor authentication,
+// POST /login/2fa {"code": "123456"} with the code of the authenticator app or a recovery code.
+// Five wrong codes in a row lock the codes of the user for a while, whichever logins they came from.
+func (h *AuthHandler) LoginTwoFactor() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := h.sessionManager.Get(r.Context(), "pendingUserID").(uint)
+		since := time.Unix(h.sessionManager.GetInt64(r.Context(), "pendingSince"), 0)
+		if !ok || time.Since(since) > pendingLoginTimeout {
+			h.clearPendingLogin(r)
+			http.Error(w, "Log in with your password first", http.StatusUnauthorized)
+			return
+		}
+
+		var input struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 			http.Error(w, "Invalid input", http.StatusBadRequest)
 			return
 		}
 
 		var user models.User
-		if err := h.userRepo.GetUser(creds.Username, &user); err != nil {
-			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		if err := h.userRepo.GetUserByID(userID, &user); err != nil {
+			h.clearPendingLogin(r)
+			http.Error(w, "Log in with your password first", http.StatusUnauthorized)
 			return
 		}
-
-		// Verify password
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password)); err != nil {
-			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		if user.DisabledAt != nil {
+			h.clearPendingLogin(r)
+			http.Error(w, "Account disabled", http.StatusForbidden)
 			return
 		}
 
-		fmt.Println("actual user id in db is value from db after login:::: ", user.ID)
+		if err := h.twoFactor.VerifyLogin(&user, input.Code); err != nil {
+			if errors.Is(err, services.ErrTwoFactorLocked) {
+				h.clearPendingLogin(r)
+				http.Error(w, "Too many wrong codes, try again later", http.StatusTooManyRequests)
+				return
+			}
+			if !errors.Is(err, services.ErrInvalidCode) {
+				http.Error(w, "Failed to check the code", http.StatusInternalServerError)
+				return
+			}
+			attempts := h.sessionManager.GetInt(r.Context(), "pendingAttempts") + 1
+			if attempts >= maxCodeAttempts {
+				h.clearPendingLogin(r)
+			} else {
+				h.sessionManager.Put(r.Context(), "pendingAttempts", attempts)
+			}
+			http.Error(w, "Invalid code", http.StatusUnauthorized)
+			return
+		}
 
-		// Start session
+		if err := h.sessionManager.RenewToken(r.Context()); err != nil {
+			http.Error(w, "Failed to start session", http.StatusInternalServerError)
+			return
+		}
+		h.clearPendingLogin(r)
 		h.sessionManager.Put(r.Context(), "username", user.Username)
 		h.sessionManager.Put(r.Context(), "userID", user.ID)
 
-		// sessionUsername := sessionManager.GetString(r.Context(), "username")
-		// sessionUserID := sessionManager.Get(r.Context(), "userID")
-		// fmt.Println("sessionUsername value from db after login:::: ", sessionUsername)
-		// fmt.Println("sessionUserID value from db after login:::: ", sessionUserID)
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintln(w, "Login successful!")
 	}
 }
 
+func (h *AuthHandler) clearPendingLogin(r *http.Request) {
+	h.sessionManager.Remove(r.Context(), "pendingUserID")
+	h.sessionManager.Remove(r.Context(), "pendingSince")
+	h.sessionManager.Remove(r.Context(), "pendingAttempts")
+}
+
+// checkCredentials reads the username and password of a login request and loads the
+// user, answering the request when they do not match or the user is disabled. It also
+// returns the optional two-factor code of the request.
+func checkCredentials(w http.ResponseWriter, r *http.Request, userRepo *repos.UserRepository) (models.User, string, bool) {
+	var creds struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return models.User{}, "", false
+	}
+
+	var user models.User
+	if err := userRepo.GetUser(creds.Username, &user); err != nil {
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return user, "", false
+	}
+
+	// Verify password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password)); err != nil {
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return user, "", false
+	}
+	if user.DisabledAt != nil {
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return user, "", false
+	}
+	return user, creds.Code, true
+}
+
 // Logout ends the user's session sessionManager *scs.SessionManager
 func (h *AuthHandler) Logout() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := h.sessionManager.Destroy(r.Context())
-		// session := sessionManager.GetString(r.Context(), "username")
-		// fmt.Println("session value from db after Logout and sessionManager.Destroy: ", session)
 		if err != nil {
 			http.Error(w, "Failed to log out", http.StatusInternalServerError)
 			return
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+)
+
+// bulkRequest is the body of POST /todos/bulk
+type bulkRequest struct {
+	Mode       string                   `json:"mode"` // "atomic" (default) or "partial"
+	Operations []services.BulkOperation `json:"operations"`
+}
+
+// bulkItemResult is the outcome of one operation on one todo
+type bulkItemResult struct {
+	Op     int    `json:"op"`
+	ID     uint   `json:"id"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type bulkResponse struct {
+	Applied bool             `json:"applied"` // false when an atomic request was rolled back
+	Results []bulkItemResult `json:"results"`
+}
+
+// BulkTodos applies a list of operations to many todos in one transaction
+func (h *TodoHandler) BulkTodos() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req bulkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = "atomic"
+		}
+		if req.Mode != "atomic" && req.Mode != "partial" {
+			http.Error(w, "Invalid mode, expected atomic or partial", http.StatusBadRequest)
+			return
+		}
+		if err := validateBulk(req.Operations); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		atomic := req.Mode == "atomic"
+
+		// the same permission checks as UpdateTodo and DeleteTodo, todos that fail them are
+		// not handed to the service
+		var rejected []bulkItemResult
+		ops := make([]services.BulkOperation, len(req.Operations))
+		for i, op := range req.Operations {
+			ops[i] = op
+			ops[i].IDs = nil
+			need := services.PermissionEdit
+			if op.Op == services.BulkDelete {
+				need = services.PermissionOwner
+			}
+			for _, id := range op.IDs {
+				todo, err := h.service.GetTodo(fmt.Sprint(id))
+				if err != nil {
+					rejected = append(rejected, bulkItemResult{Op: i, ID: id, Status: http.StatusNotFound, Error: "Todo not found"})
+					continue
+				}
+				permission, err := h.shares.TodoPermission(userID, &todo)
+				switch {
+				case err != nil:
+					http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+					return
+				case permission < need:
+					rejected = append(rejected, bulkItemResult{Op: i, ID: id, Status: http.StatusUnauthorized, Error: "Unauthorized"})
+				default:
+					ops[i].IDs = append(ops[i].IDs, id)
+				}
+			}
+		}
+		if atomic && len(rejected) > 0 {
+			writeBulkResponse(w, http.StatusBadRequest, bulkResponse{Applied: false, Results: rejected})
+			return
+		}
+
+		results, applied, err := h.service.Bulk(userID, ops, atomic)
+		if err != nil {
+			http.Error(w, "Failed to apply bulk operations", http.StatusInternalServerError)
+			return
+		}
+
+		response := bulkResponse{Applied: applied, Results: rejected}
+		for _, result := range results {
+			item := bulkItemResult{Op: result.Op, ID: result.ID, Status: http.StatusOK}
+			if result.Err != nil {
+				item.Error, item.Status = todoError(result.Err, "Failed to update todo")
+			}
+			response.Results = append(response.Results, item)
+		}
+		status := http.StatusOK
+		if !applied {
+			status = http.StatusBadRequest
+		}
+		writeBulkResponse(w, status, response)
+	}
+}
+
+// validateBulk checks the shape of the operations before any todo is loaded
+func validateBulk(ops []services.BulkOperation) error {
+	if len(ops) == 0 {
+		return errors.New("No operations given")
+	}
+	items := 0
+	for i, op := range ops {
+		if len(op.IDs) == 0 {
+			return fmt.Errorf("Operation %d has no ids", i)
+		}
+		items += len(op.IDs)
+		switch op.Op {
+		case services.BulkComplete, services.BulkUncomplete, services.BulkDelete:
+		case services.BulkMove:
+			if op.ListID == nil {
+				return fmt.Errorf("Operation %d: move needs a list_id", i)
+			}
+		case services.BulkTag:
+			if len(op.AddTagIDs) == 0 && len(op.RemoveTagIDs) == 0 {
+				return fmt.Errorf("Operation %d: tag needs add_tag_ids or remove_tag_ids", i)
+			}
+		case services.BulkUpdate:
+			var fields models.Todo
+			if len(op.Fields) == 0 || json.Unmarshal(op.Fields, &fields) != nil {
+				return fmt.Errorf("Operation %d: update needs fields", i)
+			}
+			if err := validateTodo(&fields); err != nil {
+				return fmt.Errorf("Operation %d: %v", i, err)
+			}
+		default:
+			return fmt.Errorf("Operation %d: unknown op %q, expected one of complete, uncomplete, delete, move, tag, update", i, op.Op)
+		}
+	}
+	if items > services.MaxBulkItems {
+		return fmt.Errorf("Too many todos, at most %d per request", services.MaxBulkItems)
+	}
+	return nil
+}
+
+func writeBulkResponse(w http.ResponseWriter, status int, response bulkResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CommentHandler struct {
+	service     *services.CommentService
+	todoService *services.TodoService
+	shares      *services.ShareService
+}
+
+func NewCommentHandler(service *services.CommentService, todoService *services.TodoService, shares *services.ShareService) *CommentHandler {
+	return &CommentHandler{service, todoService, shares}
+}
+
+// GetComments lists the comments of a todo, oldest first
+func (h *CommentHandler) GetComments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, _, ok := todoFromURL(w, r, h.shares, h.todoService.GetTodo, "Todo not found", services.PermissionView)
+		if !ok {
+			return
+		}
+
+		comments, err := h.service.GetComments(todo.ID)
+		if err != nil {
+			http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(comments)
+	}
+}
+
+// CreateComment adds a comment to a todo, POST /todos/{id}/comments {"body": "Markdown"}
+func (h *CommentHandler) CreateComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, userID, ok := todoFromURL(w, r, h.shares, h.todoService.GetTodo, "Todo not found", services.PermissionView)
+		if !ok {
+			return
+		}
+
+		body, ok := decodeCommentBody(w, r)
+		if !ok {
+			return
+		}
+
+		comment := models.Comment{TodoID: todo.ID, AuthorID: userID, Body: body}
+		if err := h.service.AddComment(&comment); err != nil {
+			http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(comment)
+	}
+}
+
+// UpdateComment changes the body of a comment, only its author may edit it
+func (h *CommentHandler) UpdateComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, comment, userID, ok := h.commentFromURL(w, r)
+		if !ok {
+			return
+		}
+		if comment.AuthorID != userID {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, ok := decodeCommentBody(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.service.EditComment(&comment, body); err != nil {
+			http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(comment)
+	}
+}
+
+// DeleteComment removes a comment, allowed for its author and the owner of the todo
+func (h *CommentHandler) DeleteComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, comment, userID, ok := h.commentFromURL(w, r)
+		if !ok {
+			return
+		}
+		if comment.AuthorID != userID {
+			permission, err := h.shares.TodoPermission(userID, &todo)
+			if !authorize(w, permission, err, services.PermissionOwner) {
+				return
+			}
+		}
+
+		if err := h.service.RemoveComment(&comment); err != nil {
+			http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// commentFromURL loads the comment of the {commentId} URL parameter on a todo the
+// logged-in user may see
+func (h *CommentHandler) commentFromURL(w http.ResponseWriter, r *http.Request) (models.Todo, models.Comment, uint, bool) {
+	todo, userID, ok := todoFromURL(w, r, h.shares, h.todoService.GetTodo, "Todo not found", services.PermissionView)
+	if !ok {
+		return todo, models.Comment{}, 0, false
+	}
+
+	comment, err := h.service.GetComment(todo.ID, chi.URLParam(r, "commentId"))
+	if err != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return todo, comment, 0, false
+	}
+	return todo, comment, userID, true
+}
+
+// decodeCommentBody reads and validates {"body": "..."} from the request
+func decodeCommentBody(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var input struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return "", false
+	}
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		http.Error(w, "Comment body is required", http.StatusBadRequest)
+		return "", false
+	}
+	if utf8.RuneCountInString(body) > models.MaxCommentLength {
+		http.Error(w, fmt.Sprintf("Comment body is longer than %d characters", models.MaxCommentLength), http.StatusBadRequest)
+		return "", false
+	}
+	return body, true
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// GetDependencies returns the dependency graph around a todo
+func (h *TodoHandler) GetDependencies() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, _, ok := h.todoWith(w, r, services.PermissionView)
+		if !ok {
+			return
+		}
+
+		graph, err := h.service.GetDependencyGraph(&todo)
+		if err != nil {
+			http.Error(w, "Failed to fetch dependencies", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(graph)
+	}
+}
+
+// AddDependency marks a todo as blocked by another one, POST /todos/{id}/dependencies {"blocked_by_id": 2}
+func (h *TodoHandler) AddDependency() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, userID, ok := h.todoWith(w, r, services.PermissionEdit)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			BlockedByID uint `json:"blocked_by_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		err := h.service.AddDependency(userID, &todo, input.BlockedByID)
+		switch {
+		case errors.Is(err, services.ErrInvalidDependency):
+			http.Error(w, "Invalid blocked_by_id", http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrDependencyCycle):
+			http.Error(w, "Dependency would create a cycle", http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "Failed to add dependency", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(models.TodoDependency{TodoID: todo.ID, BlockedByID: input.BlockedByID})
+	}
+}
+
+// RemoveDependency unblocks a todo, DELETE /todos/{id}/dependencies/{blockerId}
+func (h *TodoHandler) RemoveDependency() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, userID, ok := h.todoWith(w, r, services.PermissionEdit)
+		if !ok {
+			return
+		}
+
+		blockerID, err := strconv.ParseUint(chi.URLParam(r, "blockerId"), 10, 64)
+		if err != nil {
+			http.Error(w, "Dependency not found", http.StatusNotFound)
+			return
+		}
+
+		err = h.service.RemoveDependency(userID, &todo, uint(blockerID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Dependency not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to remove dependency", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// NextTodos returns the open todos of the authenticated user, or of the active organization,
+// in dependency order. Todos with an empty blocked_by can be started now.
+func (h *TodoHandler) NextTodos() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := requestScope(w, r, h.shares)
+		if !ok {
+			return
+		}
+
+		next, err := h.service.NextTodos(scope)
+		if err != nil {
+			http.Error(w, "Failed to fetch todos", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(next)
+	}
+}
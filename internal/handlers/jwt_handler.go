@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+)
+
+// JWTHandler replaces the login and logout of AuthHandler in the jwt auth mode
+type JWTHandler struct {
+	service   *services.JWTService
+	userRepo  *repos.UserRepository
+	twoFactor *services.TwoFactorService
+}
+
+func NewJWTHandler(service *services.JWTService, userRepo *repos.UserRepository, twoFactor *services.TwoFactorService) *JWTHandler {
+	return &JWTHandler{service, userRepo, twoFactor}
+}
+
+// Login checks the password like AuthHandler.Login and returns an access and a refresh token.
+// Users with two-factor authentication send their code in the same request,
+// {"username": "alice", "password": "...", "code": "123456"}. Five wrong codes in a row
+// lock the codes of the user for a while.
+func (h *JWTHandler) Login() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, code, ok := checkCredentials(w, r, h.userRepo)
+		if !ok {
+			return
+		}
+		if user.TOTPEnabled {
+			if code == "" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]bool{"two_factor_required": true})
+				return
+			}
+			if err := h.twoFactor.VerifyLogin(&user, code); err != nil {
+				if errors.Is(err, services.ErrInvalidCode) {
+					http.Error(w, "Invalid code", http.StatusUnauthorized)
+					return
+				}
+				if errors.Is(err, services.ErrTwoFactorLocked) {
+					http.Error(w, "Too many wrong codes, try again later", http.StatusTooManyRequests)
+					return
+				}
+				http.Error(w, "Failed to check the code", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		tokens, err := h.service.Login(&user)
+		if err != nil {
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	}
+}
+
+// Refresh exchanges a refresh token for new tokens, POST /token/refresh {"refresh_token": "..."}.
+// Every refresh token works once, using one twice logs out every client of that login.
+func (h *JWTHandler) Refresh() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := refreshToken(w, r)
+		if !ok {
+			return
+		}
+
+		tokens, err := h.service.Refresh(raw)
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReuse) {
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to refresh tokens", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	}
+}
+
+// Logout revokes the refresh token and the ones it was exchanged for, POST /logout {"refresh_token": "..."}
+func (h *JWTHandler) Logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := refreshToken(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.service.Logout(raw); err != nil {
+			http.Error(w, "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func refreshToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.RefreshToken == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return "", false
+	}
+	return input.RefreshToken, true
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ListHandler struct {
+	service     *services.ListService
+	todoService *services.TodoService
+	shares      *services.ShareService
+}
+
+func NewListHandler(service *services.ListService, todoService *services.TodoService, shares *services.ShareService) *ListHandler {
+	return &ListHandler{service, todoService, shares}
+}
+
+// GetLists retrieves all lists of the authenticated user, or of the active organization
+func (h *ListHandler) GetLists() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := requestScope(w, r, h.shares)
+		if !ok {
+			return
+		}
+
+		lists, err := h.service.GetLists(scope)
+		if err != nil {
+			http.Error(w, "Failed to fetch lists", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lists)
+	}
+}
+
+// CreateList adds a new list, in an organization only its admins may do so
+func (h *ListHandler) CreateList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := requestScope(w, r, h.shares)
+		if !ok {
+			return
+		}
+		if scope.OrgID != nil {
+			permission, err := h.shares.OrgPermission(scope.UserID, *scope.OrgID)
+			if !authorize(w, permission, err, services.PermissionOwner) {
+				return
+			}
+		}
+
+		var list models.List
+		if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		list.ID = 0
+		list.UserID = scope.UserID
+		list.OrgID = scope.OrgID
+		list.Name = strings.TrimSpace(list.Name)
+		if list.Name == "" {
+			http.Error(w, "List name is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.AddList(&list); err != nil {
+			http.Error(w, "Failed to create list", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+// GetList returns a single list
+func (h *ListHandler) GetList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, _, ok := h.listWith(w, r, services.PermissionView)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+// UpdateList renames a list
+func (h *ListHandler) UpdateList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, _, ok := h.listWith(w, r, services.PermissionOwner)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		list.Name = strings.TrimSpace(input.Name)
+		if list.Name == "" {
+			http.Error(w, "List name is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.EditList(&list); err != nil {
+			http.Error(w, "Failed to update list", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+// DeleteList removes a list, its todos are moved to the inbox
+func (h *ListHandler) DeleteList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, _, ok := h.listWith(w, r, services.PermissionOwner)
+		if !ok {
+			return
+		}
+
+		if err := h.service.RemoveList(&list); err != nil {
+			if errors.Is(err, services.ErrInboxList) {
+				http.Error(w, "The inbox list cannot be deleted", http.StatusConflict)
+				return
+			}
+			http.Error(w, "Failed to delete list", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetListTodos retrieves the todos of a list, accepting the same query parameters as GET /todos
+func (h *ListHandler) GetListTodos() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, _, ok := h.listWith(w, r, services.PermissionView)
+		if !ok {
+			return
+		}
+
+		filter, err := parseTodoFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.ListID = &list.ID
+
+		page, err := h.todoService.GetTodoList(repos.Scope{UserID: list.UserID, OrgID: list.OrgID}, filter)
+		if errors.Is(err, repos.ErrInvalidCursor) {
+			http.Error(w, "Cursor does not match the requested sort order", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch todos", http.StatusInternalServerError)
+			return
+		}
+
+		writeTodoPage(w, r, page)
+	}
+}
+
+// CreateListTodo adds a new todo to a list
+func (h *ListHandler) CreateListTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, userID, ok := h.listWith(w, r, services.PermissionEdit)
+		if !ok {
+			return
+		}
+
+		var todo models.Todo
+		if err := json.NewDecoder(r.Body).Decode(&todo); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		if err := validateTodo(&todo); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// todos of a shared list belong to its owner, the ones of an organization to their creator
+		todo.UserID = list.UserID
+		if list.OrgID != nil {
+			todo.UserID = userID
+		}
+		todo.ListID = &list.ID
+		todo.OrgID = list.OrgID
+
+		if err := h.todoService.AddTodo(userID, &todo); err != nil {
+			writeTodoError(w, err, "Failed to create todo")
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(todo)
+	}
+}
+
+// listWith loads the list from the URL and makes sure the logged-in user has the permission on it
+func (h *ListHandler) listWith(w http.ResponseWriter, r *http.Request, need services.Permission) (models.List, uint, bool) {
+	return listFromURL(w, r, h.shares, h.service.GetList, need)
+}
+
+// listFromURL loads the list of the {id} URL parameter with load and checks that the
+// logged-in user has the needed permission on it. Otherwise it writes the error response
+// and returns false.
+func listFromURL(w http.ResponseWriter, r *http.Request, shares *services.ShareService,
+	load func(id string) (models.List, error), need services.Permission) (models.List, uint, bool) {
+	list, err := load(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "List not found", http.StatusNotFound)
+		return list, 0, false
+	}
+
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return list, 0, false
+	}
+	permission, err := shares.ListPermission(userID, &list)
+	if !authorize(w, permission, err, need) {
+		return list, 0, false
+	}
+	return list, userID, true
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"todo-list/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	service *services.NotificationService
+}
+
+func NewNotificationHandler(service *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service}
+}
+
+// GetNotifications lists the notifications of the authenticated user, newest first,
+// only the unread ones with ?unread=true
+func (h *NotificationHandler) GetNotifications() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		unread, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+
+		notifications, err := h.service.GetNotifications(userID, unread)
+		if err != nil {
+			http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(notifications)
+	}
+}
+
+// MarkRead marks a notification as read, POST /me/notifications/{id}/read
+func (h *NotificationHandler) MarkRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		notification, err := h.service.MarkRead(userID, chi.URLParam(r, "id"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to update notification", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(notification)
+	}
+}
+
+// MarkAllRead marks every notification of the authenticated user as read
+func (h *NotificationHandler) MarkAllRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		marked, err := h.service.MarkAllRead(userID)
+		if err != nil {
+			http.Error(w, "Failed to update notifications", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int64{"marked": marked})
+	}
+}
@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type OrganizationHandler struct {
+	service        *services.OrganizationService
+	sessionManager *scs.SessionManager
+}
+
+func NewOrganizationHandler(service *services.OrganizationService, sessionManager *scs.SessionManager) *OrganizationHandler {
+	return &OrganizationHandler{service, sessionManager}
+}
+
+// CreateOrganization adds a new organization, the authenticated user becomes its owner
+func (h *OrganizationHandler) CreateOrganization() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var input struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		org := models.Organization{Name: strings.TrimSpace(input.Name)}
+		if org.Name == "" {
+			http.Error(w, "Organization name is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.CreateOrganization(userID, &org); err != nil {
+			http.Error(w, "Failed to create organization", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(org)
+	}
+}
+
+// GetOrganizations lists the organizations of the authenticated user with their role
+func (h *OrganizationHandler) GetOrganizations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		orgs, err := h.service.GetOrganizations(userID)
+		if err != nil {
+			http.Error(w, "Failed to fetch organizations", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(orgs)
+	}
+}
+
+// GetOrganization returns a single organization
+func (h *OrganizationHandler) GetOrganization() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, _, ok := h.orgFromURL(w, r)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(org)
+	}
+}
+
+// GetMembers lists the members of an organization
+func (h *OrganizationHandler) GetMembers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, _, ok := h.orgFromURL(w, r)
+		if !ok {
+			return
+		}
+
+		members, err := h.service.GetMembers(org.ID)
+		if err != nil {
+			http.Error(w, "Failed to fetch members", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(members)
+	}
+}
+
+// SetMemberRole changes the role of a member, PUT /orgs/{id}/members/{username} {"role": "admin"}
+func (h *OrganizationHandler) SetMemberRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, userID, ok := h.orgFromURL(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		membership, err := h.service.SetRole(userID, &org, chi.URLParam(r, "username"), input.Role)
+		if err != nil {
+			writeOrgError(w, err, "Failed to change the role")
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(membership)
+	}
+}
+
+// RemoveMember takes a user out of an organization, members may remove themselves to leave it
+func (h *OrganizationHandler) RemoveMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, userID, ok := h.orgFromURL(w, r)
+		if !ok {
+			return
+		}
+
+		username := chi.URLParam(r, "username")
+		if err := h.service.RemoveMember(userID, &org, username); err != nil {
+			writeOrgError(w, err, "Failed to remove member")
+			return
+		}
+		// a member leaving the active organization goes back to their personal todos
+		if username == h.sessionManager.GetString(r.Context(), "username") &&
+			h.sessionManager.Get(r.Context(), "orgID") == org.ID {
+			h.sessionManager.Remove(r.Context(), "orgID")
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Invite asks a user to join an organization, POST /orgs/{id}/invitations {"username": "bob", "role": "member"}
+func (h *OrganizationHandler) Invite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, userID, ok := h.orgFromURL(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Username string `json:"username"`
+			Role     string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Role == "" {
+			input.Role = models.OrgMember
+		}
+
+		invitation, err := h.service.Invite(userID, &org, input.Username, input.Role)
+		if err != nil {
+			writeOrgError(w, err, "Failed to invite user")
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(invitation)
+	}
+}
+
+// GetInvitations lists the open invitations of the authenticated user
+func (h *OrganizationHandler) GetInvitations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		invitations, err := h.service.GetInvitations(userID)
+		if err != nil {
+			http.Error(w, "Failed to fetch invitations", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invitations)
+	}
+}
+
+// AcceptInvitation joins the organization of an invitation
+func (h *OrganizationHandler) AcceptInvitation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		membership, err := h.service.AcceptInvitation(userID, chi.URLParam(r, "id"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Invitation not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to accept invitation", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(membership)
+	}
+}
+
+// DeclineInvitation deletes an invitation of the authenticated user
+func (h *OrganizationHandler) DeclineInvitation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		err := h.service.DeclineInvitation(userID, chi.URLParam(r, "id"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Invitation not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to decline invitation", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetActiveOrganization returns the organization the user switched to, null for the personal todos
+func (h *OrganizationHandler) GetActiveOrganization() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var active *models.Organization
+		if orgID, ok := r.Context().Value("orgID").(uint); ok {
+			org, err := h.service.GetOrganization(strconv.FormatUint(uint64(orgID), 10), userID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(w, "Failed to fetch organization", http.StatusInternalServerError)
+				return
+			}
+			if org.Role != "" {
+				active = &org
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(active)
+	}
+}
+
+// SwitchOrganization sets the organization the following requests work on,
+// PUT /me/org {"org_id": 3}. A null org_id switches back to the personal todos.
+func (h *OrganizationHandler) SwitchOrganization() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var input struct {
+			OrgID *uint `json:"org_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		var active *models.Organization
+		if input.OrgID == nil {
+			h.sessionManager.Remove(r.Context(), "orgID")
+		} else {
+			org, err := h.service.GetOrganization(strconv.FormatUint(uint64(*input.OrgID), 10), userID)
+			if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && org.Role == "") {
+				http.Error(w, "Organization not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to switch organization", http.StatusInternalServerError)
+				return
+			}
+			h.sessionManager.Put(r.Context(), "orgID", org.ID)
+			active = &org
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(active)
+	}
+}
+
+// orgFromURL loads the organization of the {id} URL parameter with the role of the
+// logged-in user, who has to be one of its members
+func (h *OrganizationHandler) orgFromURL(w http.ResponseWriter, r *http.Request) (models.Organization, uint, bool) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return models.Organization{}, 0, false
+	}
+
+	org, err := h.service.GetOrganization(chi.URLParam(r, "id"), userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return org, userID, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch organization", http.StatusInternalServerError)
+		return org, userID, false
+	}
+	if org.Role == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return org, userID, false
+	}
+	return org, userID, true
+}
+
+func writeOrgError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidOrgRole):
+		http.Error(w, "Invalid role, expected owner, admin or member", http.StatusBadRequest)
+	case errors.Is(err, services.ErrUnknownUser):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Member not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrOrgRole):
+		http.Error(w, "Your role does not allow this change", http.StatusForbidden)
+	case errors.Is(err, services.ErrAlreadyMember):
+		http.Error(w, "User is already a member", http.StatusConflict)
+	case errors.Is(err, services.ErrLastOwner):
+		http.Error(w, "An organization needs at least one owner", http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// requestScope returns the data a request works on: the organization the user switched to
+// with PUT /me/org, otherwise their personal todos and lists. Users who were removed from
+// the active organization have to switch back before they can continue.
+func requestScope(w http.ResponseWriter, r *http.Request, shares *services.ShareService) (repos.Scope, bool) {
+	userID, ok := r.Context().Value("userID").(uint)
+	// missing userID in the request context, which should exist from being set in SessionMiddleware
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return repos.Scope{}, false
+	}
+
+	scope := repos.PersonalScope(userID)
+	orgID, ok := r.Context().Value("orgID").(uint)
+	if !ok {
+		return scope, true
+	}
+	permission, err := shares.OrgPermission(userID, orgID)
+	if err != nil {
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return scope, false
+	}
+	if permission == services.PermissionNone {
+		http.Error(w, "Not a member of the active organization", http.StatusForbidden)
+		return scope, false
+	}
+	scope.OrgID = &orgID
+	return scope, true
+}
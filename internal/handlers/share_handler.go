@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type ShareHandler struct {
+	service     *services.ShareService
+	todoService *services.TodoService
+	listService *services.ListService
+}
+
+func NewShareHandler(service *services.ShareService, todoService *services.TodoService, listService *services.ListService) *ShareHandler {
+	return &ShareHandler{service, todoService, listService}
+}
+
+// GetTodoShares lists who a todo is shared with
+func (h *ShareHandler) GetTodoShares() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, _, ok := todoFromURL(w, r, h.service, h.todoService.GetTodo, "Todo not found", services.PermissionOwner)
+		if !ok {
+			return
+		}
+		writeShares(w, func() ([]models.Share, error) { return h.service.GetTodoShares(todo.ID) })
+	}
+}
+
+// ShareTodo shares a todo and its subtasks, PUT /todos/{id}/shares/{username} {"role": "editor"}
+func (h *ShareHandler) ShareTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, _, ok := todoFromURL(w, r, h.service, h.todoService.GetTodo, "Todo not found", services.PermissionOwner)
+		if !ok {
+			return
+		}
+		saveShare(w, r, func(username, role string) (models.Share, error) {
+			return h.service.ShareTodo(&todo, username, role)
+		})
+	}
+}
+
+// UnshareTodo takes the access to a todo away, allowed for its owner and for the user
+// it is shared with
+func (h *ShareHandler) UnshareTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, userID, ok := todoFromURL(w, r, h.service, h.todoService.GetTodo, "Todo not found", services.PermissionView)
+		if !ok {
+			return
+		}
+		h.removeShare(w, r, userID, func() (services.Permission, error) {
+			return h.service.TodoPermission(userID, &todo)
+		}, func(recipientID uint) error {
+			return h.service.UnshareTodo(todo.ID, recipientID)
+		})
+	}
+}
+
+// GetListShares lists who a list is shared with
+func (h *ShareHandler) GetListShares() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, _, ok := listFromURL(w, r, h.service, h.listService.GetList, services.PermissionOwner)
+		if !ok {
+			return
+		}
+		writeShares(w, func() ([]models.Share, error) { return h.service.GetListShares(list.ID) })
+	}
+}
+
+// ShareList shares a list and all of its todos, PUT /lists/{id}/shares/{username} {"role": "viewer"}
+func (h *ShareHandler) ShareList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, _, ok := listFromURL(w, r, h.service, h.listService.GetList, services.PermissionOwner)
+		if !ok {
+			return
+		}
+		saveShare(w, r, func(username, role string) (models.Share, error) {
+			return h.service.ShareList(&list, username, role)
+		})
+	}
+}
+
+// UnshareList takes the access to a list away, allowed for its owner and for the user
+// it is shared with
+func (h *ShareHandler) UnshareList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, userID, ok := listFromURL(w, r, h.service, h.listService.GetList, services.PermissionView)
+		if !ok {
+			return
+		}
+		h.removeShare(w, r, userID, func() (services.Permission, error) {
+			return h.service.ListPermission(userID, &list)
+		}, func(recipientID uint) error {
+			return h.service.UnshareList(list.ID, recipientID)
+		})
+	}
+}
+
+// authorize checks a permission computed by the ShareService against the one an endpoint
+// needs. Otherwise it writes the error response and returns false.
+func authorize(w http.ResponseWriter, permission services.Permission, err error, need services.Permission) bool {
+	if err != nil {
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return false
+	}
+	if permission < need {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func writeShares(w http.ResponseWriter, fetch func() ([]models.Share, error)) {
+	shares, err := fetch()
+	if err != nil {
+		http.Error(w, "Failed to fetch shares", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shares)
+}
+
+// saveShare reads {"role": "..."} and shares with the {username} URL parameter
+func saveShare(w http.ResponseWriter, r *http.Request, share func(username, role string) (models.Share, error)) {
+	var input struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := share(chi.URLParam(r, "username"), input.Role)
+	switch {
+	case errors.Is(err, services.ErrInvalidRole):
+		http.Error(w, "Invalid role, expected viewer or editor", http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrUnknownUser):
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrShareWithOwner):
+		http.Error(w, "Cannot share with the owner", http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrShareOrgData):
+		http.Error(w, "Todos and lists of an organization cannot be shared, invite the user instead", http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Failed to share", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// removeShare deletes the share with the {username} URL parameter. Users may leave a share
+// themselves, removing anyone else needs the owner permission.
+func (h *ShareHandler) removeShare(w http.ResponseWriter, r *http.Request, userID uint,
+	permission func() (services.Permission, error), unshare func(recipientID uint) error) {
+	recipient, err := h.service.FindUser(chi.URLParam(r, "username"))
+	if errors.Is(err, services.ErrUnknownUser) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to remove share", http.StatusInternalServerError)
+		return
+	}
+	if recipient.ID != userID {
+		granted, err := permission()
+		if !authorize(w, granted, err, services.PermissionOwner) {
+			return
+		}
+	}
+
+	err = unshare(recipient.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Share not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to remove share", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
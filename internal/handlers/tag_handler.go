@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TagHandler struct {
+	service *services.TagService
+	shares  *services.ShareService
+}
+
+func NewTagHandler(service *services.TagService, shares *services.ShareService) *TagHandler {
+	return &TagHandler{service, shares}
+}
+
+// GetTags lists the tags of the authenticated user
+func (h *TagHandler) GetTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		// missing userID in the request context, which should exist from being set in SessionMiddleware
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tags, err := h.service.GetTags(userID)
+		if err != nil {
+			http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tags)
+	}
+}
+
+// CreateTag adds a new tag
+func (h *TagHandler) CreateTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var tag models.Tag
+		if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		tag.ID = 0
+		tag.UserID = userID
+
+		if !h.saveTag(w, &tag, h.service.AddTag) {
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(tag)
+	}
+}
+
+// GetTag returns a single tag
+func (h *TagHandler) GetTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tag, ok := h.ownedTag(w, r)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tag)
+	}
+}
+
+// UpdateTag renames or recolors a tag
+func (h *TagHandler) UpdateTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tag, ok := h.ownedTag(w, r)
+		if !ok {
+			return
+		}
+
+		id, userID := tag.ID, tag.UserID
+		if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		tag.ID, tag.UserID = id, userID
+
+		if !h.saveTag(w, &tag, h.service.EditTag) {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(tag)
+	}
+}
+
+// DeleteTag removes a tag and detaches it from all todos
+func (h *TagHandler) DeleteTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tag, ok := h.ownedTag(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.service.RemoveTag(&tag); err != nil {
+			http.Error(w, "Failed to delete tag", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ownedTag loads the tag from the URL and makes sure it belongs to the logged-in user
+func (h *TagHandler) ownedTag(w http.ResponseWriter, r *http.Request) (models.Tag, bool) {
+	tag, err := h.service.GetTag(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return tag, false
+	}
+
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return tag, false
+	}
+	permission, err := h.shares.TagPermission(userID, &tag)
+	return tag, authorize(w, permission, err, services.PermissionOwner)
+}
+
+// saveTag validates the tag and stores it with the given service method
+func (h *TagHandler) saveTag(w http.ResponseWriter, tag *models.Tag, save func(*models.Tag) error) bool {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		http.Error(w, "Tag name is required", http.StatusBadRequest)
+		return false
+	}
+
+	if err := save(tag); err != nil {
+		if errors.Is(err, services.ErrDuplicateTag) {
+			http.Error(w, "Tag already exists", http.StatusConflict)
+			return false
+		}
+		http.Error(w, "Failed to save tag", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"todo-list/internal/models"
+	"todo-list/internal/repos"
 	"todo-list/internal/services"
 
 	"github.com/go-chi/chi/v5"
@@ -12,40 +17,80 @@ import (
 
 type TodoHandler struct {
 	service *services.TodoService
+	shares  *services.ShareService
 }
 
-func NewTodoHandler(service *services.TodoService) *TodoHandler {
-	return &TodoHandler{service}
+func NewTodoHandler(service *services.TodoService, shares *services.ShareService) *TodoHandler {
+	return &TodoHandler{service, shares}
 }
 
-// GetTodos retrieves all todos for the authenticated user
+// GetTodos retrieves all todos for the authenticated user, or of the active organization
 func (h *TodoHandler) GetTodos() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userID, ok := r.Context().Value("userID").(uint)
-		// missing userID in the request context, which should exist from being set in SessionMiddleware
+		scope, ok := requestScope(w, r, h.shares)
 		if !ok {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		todos, err := h.service.GetTodoList(userID)
+		filter, err := parseTodoFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
+		page, err := h.service.GetTodoList(scope, filter)
+
+		if errors.Is(err, repos.ErrInvalidCursor) {
+			http.Error(w, "Cursor does not match the requested sort order", http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to fetch todos", http.StatusInternalServerError)
 			return
 		}
 
+		writeTodoPage(w, r, page)
+	}
+}
+
+// SearchTodos runs a ranked full-text search, GET /todos/search?q=...&limit=...
+func (h *TodoHandler) SearchTodos() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := requestScope(w, r, h.shares)
+		if !ok {
+			return
+		}
+
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		if query == "" {
+			http.Error(w, "Missing search query q", http.StatusBadRequest)
+			return
+		}
+		limit := repos.DefaultPageLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			var err error
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > repos.MaxPageLimit {
+				http.Error(w, fmt.Sprintf("Invalid limit, expected a number between 1 and %d", repos.MaxPageLimit), http.StatusBadRequest)
+				return
+			}
+		}
+
+		results, err := h.service.SearchTodos(scope, query, limit)
+		if err != nil {
+			http.Error(w, "Failed to search todos", http.StatusInternalServerError)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(todos)
+		json.NewEncoder(w).Encode(results)
 	}
 }
 
-// CreateTodo adds a new todo
+// CreateTodo adds a new todo, to the active organization when the user switched to one
 func (h *TodoHandler) CreateTodo() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userID, ok := r.Context().Value("userID").(uint)
-		// missing userID in the request context, which should exist from being set in SessionMiddleware
+		scope, ok := requestScope(w, r, h.shares)
 		if !ok {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
@@ -55,12 +100,18 @@ func (h *TodoHandler) CreateTodo() http.HandlerFunc {
 			return
 		}
 
+		if err := validateTodo(&todo); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Associate todo with logged-in user
-		todo.UserID = userID
+		todo.UserID = scope.UserID
+		todo.OrgID = scope.OrgID
 
-		if err := h.service.AddTodo(&todo); err != nil {
+		if err := h.service.AddTodo(scope.UserID, &todo); err != nil {
 			fmt.Println("error when trying to add todo ", todo, err)
-			http.Error(w, "Failed to create todo", http.StatusInternalServerError)
+			writeTodoError(w, err, "Failed to create todo")
 			return
 		}
 
@@ -72,29 +123,32 @@ func (h *TodoHandler) CreateTodo() http.HandlerFunc {
 // UpdateTodo updates an existing todo
 func (h *TodoHandler) UpdateTodo() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
-
-		todo, err := h.service.GetTodo(id)
-		if err != nil {
-			http.Error(w, "Todo not found", http.StatusNotFound)
+		todo, userID, ok := h.todoWith(w, r, services.PermissionEdit)
+		if !ok {
 			return
 		}
 
-		userID, ok := r.Context().Value("userID").(uint)
-		// missing userID in the request context, which should exist from being set in SessionMiddleware
-
-		if !ok || todo.UserID != userID {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		id, ownerID := todo.ID, todo.UserID
+		if err := json.NewDecoder(r.Body).Decode(&todo); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
 			return
 		}
+		// the id and the owner cannot be changed, also not by an editor the todo was shared with
+		todo.ID, todo.UserID = id, ownerID
 
-		if err := json.NewDecoder(r.Body).Decode(&todo); err != nil {
-			http.Error(w, "Invalid input", http.StatusBadRequest)
+		if err := validateTodo(&todo); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		if err := h.service.EditTodo(&todo); err != nil {
-			http.Error(w, "Failed to update todo", http.StatusInternalServerError)
+		// PUT /todos/{id}?complete_children=true also completes all subtasks,
+		// ?force=true completes a todo whose blockers are still open
+		var opts services.EditOptions
+		opts.CompleteChildren, _ = strconv.ParseBool(r.URL.Query().Get("complete_children"))
+		opts.Force, _ = strconv.ParseBool(r.URL.Query().Get("force"))
+
+		if err := h.service.EditTodo(userID, &todo, opts); err != nil {
+			writeTodoError(w, err, "Failed to update todo")
 			return
 		}
 
@@ -106,39 +160,88 @@ func (h *TodoHandler) UpdateTodo() http.HandlerFunc {
 // DeleteTodo removes a todo
 func (h *TodoHandler) DeleteTodo() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
+		todo, userID, ok := h.todoWith(w, r, services.PermissionOwner)
+		if !ok {
+			return
+		}
 
-		todo, err := h.service.GetTodo(id)
-		if err != nil {
-			http.Error(w, "Todo not found", http.StatusNotFound)
+		if err := h.service.RemoveTodo(userID, fmt.Sprint(todo.ID)); err != nil {
+			http.Error(w, "Failed to delete todo", http.StatusInternalServerError)
 			return
 		}
 
-		userID, ok := r.Context().Value("userID").(uint)
-		// missing userID in the request context, which should exist from being set in SessionMiddleware
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// MoveTodo places a todo between others, POST /todos/{id}/move {"after_id": 1, "before_id": 2}.
+// One anchor is enough, the todo joins the list and parent of its anchors.
+func (h *TodoHandler) MoveTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, userID, ok := h.todoWith(w, r, services.PermissionEdit)
+		if !ok {
+			return
+		}
 
-		if !ok || todo.UserID != userID {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		var anchors struct {
+			BeforeID *uint `json:"before_id"`
+			AfterID  *uint `json:"after_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&anchors); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if anchors.BeforeID == nil && anchors.AfterID == nil {
+			http.Error(w, "Missing before_id or after_id", http.StatusBadRequest)
 			return
 		}
 
-		if err := h.service.RemoveTodo(id); err != nil {
-			http.Error(w, "Failed to delete todo", http.StatusInternalServerError)
+		if err := h.service.MoveTodo(userID, &todo, anchors.BeforeID, anchors.AfterID); err != nil {
+			writeTodoError(w, err, "Failed to move todo")
 			return
 		}
 
-		w.WriteHeader(http.StatusNoContent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(todo)
 	}
 }
 
-func (h *TodoHandler) GetTodo() http.HandlerFunc {
+// GetSubtree returns a todo with all of its subtasks nested under "children"
+func (h *TodoHandler) GetSubtree() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		todo, _, ok := todoFromURL(w, r, h.shares, h.service.GetSubtree, "Todo not found", services.PermissionView)
+		if !ok {
+			return
+		}
 
-		id := chi.URLParam(r, "id")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(todo)
+	}
+}
+
+// GetHistory returns the change log of a todo, also while it is in the trash
+func (h *TodoHandler) GetHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, _, ok := todoFromURL(w, r, h.shares, h.todoOrTrashed, "Todo not found", services.PermissionView)
+		if !ok {
+			return
+		}
 
-		todo, err := h.service.GetTodo(id)
+		history, err := h.service.GetHistory(fmt.Sprint(todo.ID))
 		if err != nil {
-			http.Error(w, "Todo not found", http.StatusNotFound)
+			http.Error(w, "Failed to fetch history", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(history)
+	}
+}
+
+func (h *TodoHandler) GetTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, _, ok := h.todoWith(w, r, services.PermissionView)
+		if !ok {
 			return
 		}
 
@@ -147,3 +250,219 @@ func (h *TodoHandler) GetTodo() http.HandlerFunc {
 		json.NewEncoder(w).Encode(todo)
 	}
 }
+
+// todoWith loads the todo from the URL and makes sure the logged-in user has the permission on it
+func (h *TodoHandler) todoWith(w http.ResponseWriter, r *http.Request, need services.Permission) (models.Todo, uint, bool) {
+	return todoFromURL(w, r, h.shares, h.service.GetTodo, "Todo not found", need)
+}
+
+// todoOrTrashed loads a todo whether or not it is in the trash
+func (h *TodoHandler) todoOrTrashed(id string) (models.Todo, error) {
+	todo, err := h.service.GetTodo(id)
+	if err != nil {
+		todo, err = h.service.GetTrashedTodo(id)
+	}
+	return todo, err
+}
+
+// todoFromURL loads the todo of the {id} URL parameter with load and checks that the
+// logged-in user has the needed permission on it. Otherwise it writes the error response
+// and returns false.
+func todoFromURL(w http.ResponseWriter, r *http.Request, shares *services.ShareService,
+	load func(id string) (models.Todo, error), notFound string, need services.Permission) (models.Todo, uint, bool) {
+	todo, err := load(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, notFound, http.StatusNotFound)
+		return todo, 0, false
+	}
+
+	userID, ok := r.Context().Value("userID").(uint)
+	// missing userID in the request context, which should exist from being set in SessionMiddleware
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return todo, 0, false
+	}
+	permission, err := shares.TodoPermission(userID, &todo)
+	if !authorize(w, permission, err, need) {
+		return todo, 0, false
+	}
+	return todo, userID, true
+}
+
+// writeTodoError maps errors of the todo service to a response,
+// anything unexpected is reported with the fallback message
+func writeTodoError(w http.ResponseWriter, err error, fallback string) {
+	message, code := todoError(err, fallback)
+	http.Error(w, message, code)
+}
+
+// todoError maps the validation errors of the todo service to a message and status code
+func todoError(err error, fallback string) (string, int) {
+	switch {
+	case errors.Is(err, repos.ErrUnknownTag):
+		return "Unknown tag in tag_ids", http.StatusBadRequest
+	case errors.Is(err, services.ErrUnknownList):
+		return "Unknown list_id", http.StatusBadRequest
+	case errors.Is(err, services.ErrInvalidParent):
+		return "Invalid parent_id", http.StatusBadRequest
+	case errors.Is(err, services.ErrInvalidAnchor):
+		return "Invalid before_id or after_id", http.StatusBadRequest
+	case errors.Is(err, services.ErrUnknownStatus):
+		return "Unknown status_id", http.StatusBadRequest
+	case errors.Is(err, services.ErrInvalidTransition):
+		return "The workflow does not allow this status change", http.StatusConflict
+	case errors.Is(err, services.ErrBlocked):
+		return "Todo is blocked by open todos, use force=true to complete it anyway", http.StatusConflict
+	}
+	return fallback, http.StatusInternalServerError
+}
+
+// validateTodo checks the client supplied fields of a todo before it is saved
+func validateTodo(todo *models.Todo) error {
+	if !todo.Priority.Valid() {
+		return errors.New("Invalid priority, expected one of none, low, medium, high, urgent")
+	}
+	if todo.DueTimezone != "" {
+		if _, err := time.LoadLocation(todo.DueTimezone); err != nil {
+			return errors.New("Invalid due_timezone")
+		}
+	}
+	if todo.Recurrence != "" {
+		if _, err := services.ParseRecurrence(todo.Recurrence); err != nil {
+			return fmt.Errorf("Invalid recurrence: %v", err)
+		}
+	}
+	return nil
+}
+
+// parseTodoFilter reads the GET /todos query parameters,
+// dates are expected in RFC 3339 format e.g. 2024-12-31T23:59:59Z
+func parseTodoFilter(r *http.Request) (repos.TodoFilter, error) {
+	var filter repos.TodoFilter
+	query := r.URL.Query()
+
+	dates := []struct {
+		param string
+		dest  **time.Time
+	}{
+		{"due_before", &filter.DueBefore},
+		{"due_after", &filter.DueAfter},
+		{"created_before", &filter.CreatedBefore},
+		{"created_after", &filter.CreatedAfter},
+		{"updated_before", &filter.UpdatedBefore},
+		{"updated_after", &filter.UpdatedAfter},
+	}
+	for _, date := range dates {
+		if v := query.Get(date.param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("Invalid %s, expected RFC 3339 date", date.param)
+			}
+			*date.dest = &t
+		}
+	}
+	if v := query.Get("list_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, errors.New("Invalid list_id")
+		}
+		listID := uint(id)
+		filter.ListID = &listID
+	}
+	if v := query.Get("parent_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, errors.New("Invalid parent_id")
+		}
+		parentID := uint(id)
+		filter.ParentID = &parentID
+	}
+	if v := query.Get("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("Invalid overdue, expected true or false")
+		}
+		filter.Overdue = overdue
+	}
+	if v := query.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("Invalid completed, expected true or false")
+		}
+		filter.Completed = &completed
+	}
+
+	// tag=a&tag=b, tag_mode=any|all
+	filter.Tags = query["tag"]
+	switch query.Get("tag_mode") {
+	case "", "any":
+	case "all":
+		filter.TagMatchAll = true
+	default:
+		return filter, errors.New("Invalid tag_mode, expected any or all")
+	}
+
+	// sort=priority|due_date|created_at|updated_at|position, order=asc|desc
+	if v := query.Get("sort"); v != "" {
+		if _, ok := repos.TodoSortFields[v]; !ok {
+			return filter, errors.New("Invalid sort, expected one of priority, due_date, created_at, updated_at, position")
+		}
+		filter.SortBy = v
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		return filter, errors.New("Invalid order, expected asc or desc")
+	}
+
+	// limit=1..200, cursor=<next or prev cursor of the previous page>
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > repos.MaxPageLimit {
+			return filter, fmt.Errorf("Invalid limit, expected a number between 1 and %d", repos.MaxPageLimit)
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := repos.DecodeCursor(v)
+		if err != nil {
+			return filter, errors.New("Invalid cursor")
+		}
+		filter.Cursor = cursor
+	}
+	return filter, nil
+}
+
+// writeTodoPage writes the todos of a page as a JSON array. The cursors of the
+// neighbouring pages are returned in the X-Next-Cursor and X-Prev-Cursor headers
+// and as ready to use URLs in the Link header.
+func writeTodoPage(w http.ResponseWriter, r *http.Request, page repos.TodoPage) {
+	var links []string
+	for _, link := range []struct{ rel, cursor, header string }{
+		{"next", page.NextCursor, "X-Next-Cursor"},
+		{"prev", page.PrevCursor, "X-Prev-Cursor"},
+	} {
+		if link.cursor == "" {
+			continue
+		}
+		w.Header().Set(link.header, link.cursor)
+
+		u := *r.URL
+		query := u.Query()
+		query.Set("cursor", link.cursor)
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), link.rel))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	todos := page.Todos
+	if todos == nil {
+		todos = []models.Todo{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(todos)
+}
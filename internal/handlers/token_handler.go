@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"todo-list/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	service *services.TokenService
+}
+
+func NewTokenHandler(service *services.TokenService) *TokenHandler {
+	return &TokenHandler{service}
+}
+
+// GetTokens lists the personal access tokens of the authenticated user, without the tokens themselves
+func (h *TokenHandler) GetTokens() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tokens, err := h.service.GetTokens(userID)
+		if err != nil {
+			http.Error(w, "Failed to fetch tokens", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	}
+}
+
+// CreateToken creates a personal access token, POST /me/tokens
+// {"name": "CI", "scope": "write", "expires_at": "2030-01-01T00:00:00Z"}.
+// The response is the only time the token is shown.
+func (h *TokenHandler) CreateToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var input struct {
+			Name      string     `json:"name"`
+			Scope     string     `json:"scope"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		token, err := h.service.CreateToken(userID, input.Name, input.Scope, input.ExpiresAt)
+		switch {
+		case errors.Is(err, services.ErrTokenName):
+			http.Error(w, "Name is required", http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrInvalidTokenScope):
+			http.Error(w, "Invalid scope, expected read or write", http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrTokenExpiry):
+			http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, "Failed to create token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(token)
+	}
+}
+
+// DeleteToken revokes a personal access token, DELETE /me/tokens/{id}
+func (h *TokenHandler) DeleteToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		err := h.service.DeleteToken(userID, chi.URLParam(r, "id"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to delete token", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"todo-list/internal/services"
+)
+
+// GetTrash lists the deleted todos of the authenticated user, or of the active organization
+func (h *TodoHandler) GetTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := requestScope(w, r, h.shares)
+		if !ok {
+			return
+		}
+
+		todos, err := h.service.GetTrash(scope)
+		if err != nil {
+			http.Error(w, "Failed to fetch trash", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(todos)
+	}
+}
+
+// RestoreTodo moves a todo and the subtasks deleted with it out of the trash
+func (h *TodoHandler) RestoreTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, userID, ok := todoFromURL(w, r, h.shares, h.service.GetTrashedTodo, "Todo not found in trash", services.PermissionOwner)
+		if !ok {
+			return
+		}
+
+		if err := h.service.RestoreTodo(userID, &todo); err != nil {
+			http.Error(w, "Failed to restore todo", http.StatusInternalServerError)
+			return
+		}
+
+		restored, err := h.service.GetTodo(fmt.Sprint(todo.ID))
+		if err != nil {
+			http.Error(w, "Failed to restore todo", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(restored)
+	}
+}
+
+// PurgeTodo permanently deletes a todo from the trash
+func (h *TodoHandler) PurgeTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, userID, ok := todoFromURL(w, r, h.shares, h.service.GetTrashedTodo, "Todo not found in trash", services.PermissionOwner)
+		if !ok {
+			return
+		}
+
+		if err := h.service.PurgeTodo(userID, &todo); err != nil {
+			http.Error(w, "Failed to delete todo", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
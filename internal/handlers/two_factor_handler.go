@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+)
+
+// TwoFactorHandler manages the two-factor authentication of the logged-in user under /me/2fa,
+// the second login step is AuthHandler.LoginTwoFactor
+type TwoFactorHandler struct {
+	service  *services.TwoFactorService
+	userRepo *repos.UserRepository
+}
+
+func NewTwoFactorHandler(service *services.TwoFactorService, userRepo *repos.UserRepository) *TwoFactorHandler {
+	return &TwoFactorHandler{service, userRepo}
+}
+
+// GetStatus tells whether two-factor authentication is on and how many recovery codes are left
+func (h *TwoFactorHandler) GetStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var remaining int64
+		if user.TOTPEnabled {
+			var err error
+			if remaining, err = h.service.RemainingRecoveryCodes(&user); err != nil {
+				http.Error(w, "Failed to fetch recovery codes", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": user.TOTPEnabled, "recovery_codes_left": remaining})
+	}
+}
+
+// Enroll starts setting up two-factor authentication and returns the secret and the
+// otpauth:// URI to show as a QR code. Enrolling again replaces the secret.
+func (h *TwoFactorHandler) Enroll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		enrollment, err := h.service.Enroll(&user)
+		if err != nil {
+			writeTwoFactorError(w, err, "Failed to start enrollment")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(enrollment)
+	}
+}
+
+// Enable turns on two-factor authentication with a code of the authenticator app,
+// POST /me/2fa/enable {"code": "123456"}. The recovery codes are only shown in the response.
+func (h *TwoFactorHandler) Enable() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, code, ok := h.userAndCode(w, r)
+		if !ok {
+			return
+		}
+
+		codes, err := h.service.Enable(&user, code)
+		if err != nil {
+			writeTwoFactorError(w, err, "Failed to enable two-factor authentication")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+	}
+}
+
+// Disable turns off two-factor authentication, POST /me/2fa/disable {"code": "123456"}
+// with a code of the authenticator app or a recovery code
+func (h *TwoFactorHandler) Disable() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, code, ok := h.userAndCode(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.service.Disable(&user, code); err != nil {
+			writeTwoFactorError(w, err, "Failed to disable two-factor authentication")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, POST /me/2fa/recovery-codes {"code": "123456"}
+func (h *TwoFactorHandler) RegenerateRecoveryCodes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, code, ok := h.userAndCode(w, r)
+		if !ok {
+			return
+		}
+
+		codes, err := h.service.RegenerateRecoveryCodes(&user, code)
+		if err != nil {
+			writeTwoFactorError(w, err, "Failed to create recovery codes")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+	}
+}
+
+func (h *TwoFactorHandler) currentUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	var user models.User
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return user, false
+	}
+	if err := h.userRepo.GetUserByID(userID, &user); err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return user, false
+	}
+	return user, true
+}
+
+// userAndCode loads the logged-in user and reads the {"code": "..."} of the request
+func (h *TwoFactorHandler) userAndCode(w http.ResponseWriter, r *http.Request) (models.User, string, bool) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return user, "", false
+	}
+	var input struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return user, "", false
+	}
+	return user, input.Code, true
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorEnabled):
+		http.Error(w, "Two-factor authentication is enabled already", http.StatusConflict)
+	case errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		http.Error(w, "Start the enrollment first", http.StatusBadRequest)
+	case errors.Is(err, services.ErrTwoFactorNotEnabled):
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidCode):
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"todo-list/internal/services"
+)
+
+// Undo reverts the most recent todo change of the authenticated user
+func (h *TodoHandler) Undo() http.HandlerFunc {
+	return h.replay(h.service.Undo, "Nothing to undo")
+}
+
+// Redo applies the most recently undone todo change of the authenticated user again
+func (h *TodoHandler) Redo() http.HandlerFunc {
+	return h.replay(h.service.Redo, "Nothing to redo")
+}
+
+func (h *TodoHandler) replay(apply func(userId uint, shares *services.ShareService) (services.UndoResult, error), empty string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(uint)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		result, err := apply(userID, h.shares)
+		switch {
+		case errors.Is(err, services.ErrNothingToUndo):
+			http.Error(w, empty, http.StatusConflict)
+			return
+		case errors.Is(err, services.ErrUndoConflict):
+			http.Error(w, "Change cannot be applied, a todo was permanently deleted", http.StatusConflict)
+			return
+		case errors.Is(err, services.ErrUndoStale):
+			http.Error(w, "Change cannot be applied, a todo was changed since", http.StatusConflict)
+			return
+		case errors.Is(err, services.ErrUndoForbidden):
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		case errors.Is(err, services.ErrUnknownStatus):
+			http.Error(w, "Change cannot be applied, its status was removed", http.StatusConflict)
+			return
+		case errors.Is(err, services.ErrInvalidTransition):
+			http.Error(w, "The workflow does not allow this status change", http.StatusConflict)
+			return
+		case errors.Is(err, services.ErrBlocked):
+			http.Error(w, "Todo is blocked by open todos", http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "Failed to apply change", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
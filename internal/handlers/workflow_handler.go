@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+)
+
+type WorkflowHandler struct {
+	service     *services.WorkflowService
+	listService *services.ListService
+	shares      *services.ShareService
+}
+
+func NewWorkflowHandler(service *services.WorkflowService, listService *services.ListService, shares *services.ShareService) *WorkflowHandler {
+	return &WorkflowHandler{service, listService, shares}
+}
+
+// GetWorkflow returns the statuses of the default workflow, or of the list given by ?list_id=
+func (h *WorkflowHandler) GetWorkflow() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, listID, ok := h.workflowScope(w, r, services.PermissionView)
+		if !ok {
+			return
+		}
+
+		statuses, err := h.service.GetWorkflow(userID, listID)
+		if err != nil {
+			http.Error(w, "Failed to fetch workflow", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
+	}
+}
+
+// UpdateWorkflow replaces a workflow, PUT /workflow?list_id=1 with
+// [{"name": "Todo", "transitions": ["Done"]}, {"name": "Done", "is_terminal": true}].
+// Without list_id the default workflow of the user is replaced.
+func (h *WorkflowHandler) UpdateWorkflow() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, listID, ok := h.workflowScope(w, r, services.PermissionOwner)
+		if !ok {
+			return
+		}
+
+		var statuses []models.WorkflowStatus
+		if err := json.NewDecoder(r.Body).Decode(&statuses); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		err := h.service.SaveWorkflow(userID, listID, statuses)
+		switch {
+		case errors.Is(err, services.ErrInvalidWorkflow):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrStatusInUse):
+			http.Error(w, "A removed status is still used by todos", http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "Failed to update workflow", http.StatusInternalServerError)
+			return
+		}
+
+		statuses, err = h.service.GetWorkflow(userID, listID)
+		if err != nil {
+			http.Error(w, "Failed to fetch workflow", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(statuses)
+	}
+}
+
+// GetBoard returns the todos grouped by workflow status, of one list with ?list_id=
+// or of every list using the default workflow
+func (h *WorkflowHandler) GetBoard() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, listID, ok := h.workflowScope(w, r, services.PermissionView)
+		if !ok {
+			return
+		}
+
+		board, err := h.service.GetBoard(userID, listID)
+		if err != nil {
+			http.Error(w, "Failed to fetch board", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(board)
+	}
+}
+
+// workflowScope reads the user and the optional ?list_id=, which needs the given permission.
+// For a list the owner of the list is returned, the workflow of a shared list is theirs.
+func (h *WorkflowHandler) workflowScope(w http.ResponseWriter, r *http.Request, need services.Permission) (uint, *uint, bool) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, nil, false
+	}
+
+	v := r.URL.Query().Get("list_id")
+	if v == "" {
+		return userID, nil, true
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return 0, nil, false
+	}
+	list, err := h.listService.GetList(strconv.FormatUint(id, 10))
+	if err != nil {
+		http.Error(w, "List not found", http.StatusNotFound)
+		return 0, nil, false
+	}
+	permission, err := h.shares.ListPermission(userID, &list)
+	if !authorize(w, permission, err, need) {
+		return 0, nil, false
+	}
+	listID := uint(id)
+	return list.UserID, &listID, true
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Attachment is a file uploaded to a todo, its content lives in the blob store
+// gorm.Model definition
+type Attachment struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TodoID      uint      `json:"todo_id" gorm:"not null;index"` // Kept after the todo is purged until the cleanup removes the blob
+	UploaderID  uint      `json:"uploader_id" gorm:"not null"`
+	FileName    string    `json:"file_name" gorm:"not null"`
+	ContentType string    `json:"content_type" gorm:"not null"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-" gorm:"not null;uniqueIndex"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// MaxCommentLength limits the Markdown source of a comment
+const MaxCommentLength = 10000
+
+// Comment is a Markdown message in the discussion of a todo
+// gorm.Model definition
+type Comment struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TodoID    uint       `json:"todo_id" gorm:"not null;index"`
+	AuthorID  uint       `json:"author_id" gorm:"not null;index"`
+	Body      string     `json:"body" gorm:"type:text;not null"` // Markdown source
+	BodyHTML  string     `json:"body_html" gorm:"-"`             // Body rendered to safe HTML
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	EditedAt  *time.Time `json:"edited_at"` // Set when the body was changed after posting
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// TodoDependency records that a todo is blocked by another todo of the same user
+// until the blocker is completed
+type TodoDependency struct {
+	TodoID      uint      `json:"todo_id" gorm:"primaryKey"`
+	BlockedByID uint      `json:"blocked_by_id" gorm:"primaryKey;index"`
+	CreatedAt   time.Time `json:"created_at"`
+}
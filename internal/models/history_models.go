@@ -0,0 +1,60 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Actions recorded in the history of a todo
+const (
+	HistoryCreated   = "created"
+	HistoryUpdated   = "updated"
+	HistoryCompleted = "completed"
+	HistoryDeleted   = "deleted"
+	HistoryRestored  = "restored"
+	HistoryPurged    = "purged"
+)
+
+// TodoHistory is one entry of the append-only change log of a todo
+// gorm.Model definition
+type TodoHistory struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	TodoID    uint         `json:"todo_id" gorm:"not null;index"` // Kept after the todo is purged
+	ActorID   *uint        `json:"actor_id"`                      // User who made the change, nil for automatic changes like the trash purge
+	Action    string       `json:"action" gorm:"not null"`
+	Changes   FieldChanges `json:"changes,omitempty" gorm:"type:text"`
+	CreatedAt time.Time    `json:"created_at" gorm:"index"`
+}
+
+// FieldChange holds the JSON values of a field before and after a change,
+// Before is null for created todos
+type FieldChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// FieldChanges maps the JSON names of the changed fields to their change, stored as JSON text
+type FieldChanges map[string]FieldChange
+
+func (c FieldChanges) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+func (c *FieldChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case []byte:
+		return json.Unmarshal(v, c)
+	}
+	return fmt.Errorf("cannot scan %T into FieldChanges", value)
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// List groups todos into a named collection such as "Work" or "Groceries".
+// Every user owns exactly one inbox list which receives todos created without a list,
+// and so does every organization.
+// gorm.Model definition
+type List struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	IsInbox   bool      `json:"is_inbox" gorm:"not null;default:false"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"` // Owner of the list, its creator for lists of an organization
+	OrgID     *uint     `json:"org_id" gorm:"index"`           // Organization owning the list, nil for personal lists
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Todos     []Todo    `json:"-" gorm:"foreignKey:ListID"` // One-to-many relationship
+}
+
+// InboxListName is the name given to the default list of a user
+const InboxListName = "Inbox"
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Types of notifications
+const (
+	NotificationAssigned   = "assigned"   // a todo was assigned to the user
+	NotificationUnassigned = "unassigned" // a todo assigned to the user was given to someone else or nobody
+)
+
+// Notification tells a user about something another user did to a todo
+// gorm.Model definition
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"` // Recipient of the notification
+	ActorID   uint       `json:"actor_id" gorm:"not null"`
+	TodoID    uint       `json:"todo_id" gorm:"not null;index"`
+	Type      string     `json:"type" gorm:"not null"`
+	Message   string     `json:"message" gorm:"not null"`
+	ReadAt    *time.Time `json:"read_at"` // Set once the recipient marked it as read
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Roles of the members of an organization
+const (
+	OrgOwner  = "owner"  // may do everything, including granting and revoking the admin and owner roles
+	OrgAdmin  = "admin"  // may manage lists, invite members and remove members
+	OrgMember = "member" // may read and change the todos of the organization's lists
+)
+
+// Organization is a team whose lists, and the todos in them, are visible to all of its members
+// gorm.Model definition
+type Organization struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	Role      string    `json:"role,omitempty" gorm:"->;-:migration"` // Role of the requesting user, read with the organization
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Membership connects a user to an organization
+// gorm.Model definition
+type Membership struct {
+	OrgID     uint      `json:"org_id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey;index"`
+	Username  string    `json:"username" gorm:"->;-:migration"` // Read with the membership
+	Role      string    `json:"role" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Invitation asks a user to join an organization, it becomes a membership once accepted
+// gorm.Model definition
+type Invitation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	OrgID     uint      `json:"org_id" gorm:"not null;uniqueIndex:idx_invitations_org_user"`
+	OrgName   string    `json:"org_name,omitempty" gorm:"->;-:migration"`                     // Read with the invitation
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_invitations_org_user"` // Invited user
+	InviterID uint      `json:"inviter_id" gorm:"not null"`
+	Role      string    `json:"role" gorm:"not null"` // Role the user gets when accepting
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Roles a todo or list can be shared with
+const (
+	ShareViewer = "viewer" // may read the todos, comment and download attachments
+	ShareEditor = "editor" // may also change the todos, add todos to a shared list and upload attachments
+)
+
+// Share gives another user access to a todo, including its subtasks, or to every todo of a list.
+// Exactly one of TodoID and ListID is set.
+// gorm.Model definition
+type Share struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TodoID    *uint     `json:"todo_id,omitempty" gorm:"uniqueIndex:idx_shares_todo_user"`
+	ListID    *uint     `json:"list_id,omitempty" gorm:"uniqueIndex:idx_shares_list_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;index;uniqueIndex:idx_shares_todo_user;uniqueIndex:idx_shares_list_user"` // Recipient of the share
+	Username  string    `json:"username" gorm:"->;-:migration"`                                                                  // Username of the recipient, read with the share
+	Role      string    `json:"role" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag is a user defined label that can be attached to many todos
+// gorm.Model definition
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Color     string    `json:"color,omitempty"`                                        // Optional display color, e.g. "#ff8800"
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_tags_user_name"` // Tags are scoped per user
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -1,30 +1,129 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// Priority of a todo, stored as an integer so that it sorts naturally
+// and exposed as a name in JSON
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+func (p Priority) Valid() bool {
+	return p >= PriorityNone && p <= PriorityUrgent
+}
+
+func (p Priority) String() string {
+	if !p.Valid() {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// ParsePriority converts a priority name such as "high" into a Priority
+func ParsePriority(name string) (Priority, error) {
+	for i, n := range priorityNames {
+		if n == name {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("unknown priority %q", name)
+}
+
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON accepts a priority name. Unknown names decode to an invalid
+// Priority instead of failing so the handler can report a proper validation error.
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	if name == "" {
+		*p = PriorityNone
+		return nil
+	}
+	parsed, err := ParsePriority(name)
+	if err != nil {
+		parsed = -1
+	}
+	*p = parsed
+	return nil
+}
+
 // Todo struct for the To-Do item, using GORM's model struct
 // gorm.Model definition
 type Todo struct {
-	ID          uint   `json:"id" gorm:"primaryKey"`
-	Title       string `json:"title" gorm:"not null"`
-	Description string `json:"description"`
-	IsCompleted bool   `json:"is_completed"`
-	UserID      uint   `json:"user_id" gorm:"not null"` // Foreign key to associate with User
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Title       string     `json:"title" gorm:"not null"`
+	Description string     `json:"description"`
+	IsCompleted bool       `json:"is_completed"`
+	StatusID    *uint      `json:"status_id" gorm:"index"` // Workflow status, IsCompleted follows whether it is terminal
+	Priority    Priority   `json:"priority" gorm:"not null;default:0;index"`
+	DueDate     *time.Time `json:"due_date" gorm:"index"`    // Stored in UTC, nil when the todo has no deadline
+	DueTimezone string     `json:"due_timezone,omitempty"`   // Optional IANA zone the due date was set in, e.g. "Europe/Berlin"
+	UserID      uint       `json:"user_id" gorm:"not null"`  // Foreign key to associate with User
+	AssigneeID  *uint      `json:"assignee_id" gorm:"index"` // User responsible for the todo, changed with PUT /todos/{id}/assignee
+	ListID      *uint      `json:"list_id" gorm:"index"`     // List the todo belongs to, the inbox when not given
+	OrgID       *uint      `json:"org_id" gorm:"index"`      // Organization of the list, nil for personal todos
+	ParentID    *uint      `json:"parent_id" gorm:"index"`   // Parent todo when this is a subtask
+	Recurrence  string     `json:"recurrence,omitempty"`     // RFC 5545 RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO"
+	Position    string     `json:"position" gorm:"index"`    // Sort key among the todos of the same list and parent
+	Tags        []Tag      `json:"tags" gorm:"many2many:todo_tags;"`
+	TagIDs      []uint     `json:"tag_ids,omitempty" gorm:"-"` // Input only: replaces the attached tags when present
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`            // Set while the todo is in the trash
+	Children    []Todo         `json:"children,omitempty" gorm:"-"`        // Only filled when a subtree is requested
+	Progress    *Progress      `json:"progress,omitempty" gorm:"-"`        // Only set for todos that have subtasks
+	Next        *Todo          `json:"next_occurrence,omitempty" gorm:"-"` // Set when completing a recurring todo created its next occurrence
 }
 
+// Progress summarises the direct subtasks of a todo
+type Progress struct {
+	Completed int64 `json:"completed"`
+	Total     int64 `json:"total"`
+}
+
+// Roles of a user
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin" // may use the /admin API to manage users
+)
+
 // User represents a user in the system
 // gorm.Model definition
 type User struct {
-	ID        uint   `gorm:"primaryKey"`
-	Username  string `gorm:"unique;not null"`
-	Password  string `gorm:"not null"` // Hashed password
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Todos     []Todo `json:"todos" gorm:"foreignKey:UserID"` // One-to-many relationship
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	Username        string     `json:"username" gorm:"unique;not null"`
+	Password        string     `json:"-" gorm:"not null"`        // Hashed password
+	Email           *string    `json:"email" gorm:"uniqueIndex"` // Lower case, nil for users without one
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`        // Password resets are only mailed to verified addresses
+	Role            string     `json:"role" gorm:"not null;default:user"`
+	DisabledAt      *time.Time `json:"disabled_at"` // Disabled users cannot log in
+	TOTPSecret      string     `json:"-"`           // Set on enrollment of two-factor authentication, used once it is enabled
+	TOTPEnabled     bool       `json:"totp_enabled" gorm:"not null;default:false"`
+	TOTPLastStep    int64      `json:"-" gorm:"not null;default:0"` // Time step of the last accepted code, codes cannot be used twice
+	TOTPFailures    int        `json:"-" gorm:"not null;default:0"` // Wrong codes in a row at logins
+	TOTPLockedUntil *time.Time `json:"-"`                           // Codes are refused until then after too many wrong ones
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Todos           []Todo     `json:"todos,omitempty" gorm:"foreignKey:UserID"` // One-to-many relationship
 }
 
 // Session represents a session in the database for session storage
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// Scopes of a personal access token
+const (
+	TokenRead  = "read"  // may only make GET requests
+	TokenWrite = "write" // may also create, change and delete
+)
+
+// APIToken is a personal access token a user creates for scripts, it is sent as
+// "Authorization: Bearer <token>". Only the SHA-256 hash of the token is stored.
+// gorm.Model definition
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Scope      string     `json:"scope" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"` // Start of the token to tell tokens apart
+	Hash       string     `json:"-" gorm:"not null;uniqueIndex"`
+	Token      string     `json:"token,omitempty" gorm:"-"` // The token itself, only returned when it is created
+	ExpiresAt  *time.Time `json:"expires_at"`               // Nil for tokens that do not expire
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// RefreshToken is one refresh token of the jwt auth mode. Every refresh replaces it with a
+// new token of the same family. Presenting a used token again revokes the whole family,
+// since one of the two clients that hold it must have stolen it.
+// gorm.Model definition
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"not null;index"`
+	Family    string     `gorm:"not null;index"` // Shared by the tokens descending from one login
+	Hash      string     `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // Set once the token was exchanged for a new one
+	RevokedAt *time.Time // Set on logout and when reuse was detected
+	CreatedAt time.Time
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode lets a user log in once without their authenticator app. Only the SHA-256
+// hash of the code is stored, the codes are shown when two-factor authentication is enabled.
+// gorm.Model definition
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"not null;index"`
+	Hash      string     `gorm:"not null;uniqueIndex"`
+	UsedAt    *time.Time // Set once the code was used to log in
+	CreatedAt time.Time
+}
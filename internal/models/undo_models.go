@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// UndoEntry is one change on the undo stack of a user. It keeps the affected todos as
+// they were before and after the change, undoing writes back Before and redoing After.
+// gorm.Model definition
+type UndoEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Action    string    `json:"action" gorm:"not null"`               // one of the history actions, e.g. "completed", or "bulk"
+	Before    string    `json:"-" gorm:"type:text"`                   // JSON array of the todos before the change, created todos are missing
+	After     string    `json:"-" gorm:"type:text"`                   // JSON array of the todos after the change
+	Undone    bool      `json:"undone" gorm:"not null;default:false"` // Undone entries form the redo stack
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// DefaultWorkflow are the statuses every user starts with, the last one is terminal
+var DefaultWorkflow = []string{"Backlog", "In Progress", "Review", "Done"}
+
+// WorkflowStatus is a column of the board. A user has one default workflow and
+// lists can have their own. The first status is where new todos start and todos
+// in a terminal status count as completed.
+// gorm.Model definition
+type WorkflowStatus struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"not null"`
+	SortOrder   int       `json:"sort_order" gorm:"not null;default:0"`
+	IsTerminal  bool      `json:"is_terminal"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	ListID      *uint     `json:"list_id" gorm:"index"` // nil for the default workflow of the user
+	Transitions []string  `json:"transitions" gorm:"-"` // names of the statuses a todo may move to from this one
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WorkflowTransition allows todos to move from one status to another
+type WorkflowTransition struct {
+	FromID uint `gorm:"primaryKey"`
+	ToID   uint `gorm:"primaryKey"`
+}
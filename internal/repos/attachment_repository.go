@@ -0,0 +1,47 @@
+package repos
+
+import (
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for AttachmentRepository
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{db}
+}
+
+// Fetch the attachments of a todo, oldest first
+func (r *AttachmentRepository) GetAttachments(todoId uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.Where("todo_id = ?", todoId).Order("created_at, id").Find(&attachments).Error
+	return attachments, err
+}
+
+// get an attachment of a todo
+func (r *AttachmentRepository) GetAttachment(todoId uint, id string) (models.Attachment, error) {
+	var attachment models.Attachment
+	return attachment, r.db.Where("todo_id = ? AND id = ?", todoId, id).First(&attachment).Error
+}
+
+// Save a new attachment
+func (r *AttachmentRepository) CreateAttachment(attachment *models.Attachment) error {
+	return r.db.Create(attachment).Error
+}
+
+// delete an attachment
+func (r *AttachmentRepository) DeleteAttachment(attachment *models.Attachment) error {
+	return r.db.Delete(attachment).Error
+}
+
+// OrphanedAttachments returns up to limit attachments whose todo was purged
+func (r *AttachmentRepository) OrphanedAttachments(limit int) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.Where("NOT EXISTS (SELECT 1 FROM todos WHERE todos.id = attachments.todo_id)").
+		Order("id").Limit(limit).Find(&attachments).Error
+	return attachments, err
+}
@@ -0,0 +1,44 @@
+package repos
+
+import (
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CommentRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for CommentRepository
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{db}
+}
+
+// Fetch the comments of a todo, oldest first
+func (r *CommentRepository) GetComments(todoId uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.Where("todo_id = ?", todoId).Order("created_at, id").Find(&comments).Error
+	return comments, err
+}
+
+// get a comment of a todo
+func (r *CommentRepository) GetComment(todoId uint, id string) (models.Comment, error) {
+	var comment models.Comment
+	return comment, r.db.Where("todo_id = ? AND id = ?", todoId, id).First(&comment).Error
+}
+
+// Save a new comment
+func (r *CommentRepository) CreateComment(comment *models.Comment) error {
+	return r.db.Create(comment).Error
+}
+
+// update a comment
+func (r *CommentRepository) UpdateComment(comment *models.Comment) error {
+	return r.db.Save(comment).Error
+}
+
+// delete a comment
+func (r *CommentRepository) DeleteComment(comment *models.Comment) error {
+	return r.db.Delete(comment).Error
+}
@@ -0,0 +1,98 @@
+package repos
+
+import (
+	"encoding/json"
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GetDependencies returns the dependencies between the todos of a scope, trashed todos
+// included so restoring one cannot close a cycle
+func (r *TodoRepository) GetDependencies(scope Scope) ([]models.TodoDependency, error) {
+	var dependencies []models.TodoDependency
+	err := scope.where(r.db.Joins("JOIN todos ON todos.id = todo_dependencies.todo_id"), "todos").
+		Order("todo_dependencies.todo_id, todo_dependencies.blocked_by_id").
+		Find(&dependencies).Error
+	return dependencies, err
+}
+
+// OpenBlockers returns the todos blocking a todo that are neither completed nor trashed
+func (r *TodoRepository) OpenBlockers(todoId uint) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := r.db.Joins("JOIN todo_dependencies ON todo_dependencies.blocked_by_id = todos.id").
+		Where("todo_dependencies.todo_id = ? AND todos.is_completed = ?", todoId, false).
+		Order("todos.id").
+		Find(&todos).Error
+	return todos, err
+}
+
+// OpenTodos returns the todos of a scope that are not completed, most urgent first
+func (r *TodoRepository) OpenTodos(scope Scope) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := scope.where(r.db.Preload("Tags"), "todos").
+		Where("is_completed = ?", false).
+		Order("priority DESC, id").
+		Find(&todos).Error
+	return todos, err
+}
+
+// GetTodos returns the todos with the given ids that are not in the trash
+func (r *TodoRepository) GetTodos(ids []uint) ([]models.Todo, error) {
+	var todos []models.Todo
+	if len(ids) == 0 {
+		return todos, nil
+	}
+	err := r.db.Preload("Tags").Where("id IN ?", ids).Order("id").Find(&todos).Error
+	return todos, err
+}
+
+// AddDependency marks a todo as blocked by another one
+func (r *TodoRepository) AddDependency(todoId, blockedById uint) error {
+	return r.changeDependencies(todoId, func(tx *gorm.DB) error {
+		return tx.Create(&models.TodoDependency{TodoID: todoId, BlockedByID: blockedById}).Error
+	})
+}
+
+// RemoveDependency unblocks a todo, gorm.ErrRecordNotFound if it was not blocked by the other todo
+func (r *TodoRepository) RemoveDependency(todoId, blockedById uint) error {
+	return r.changeDependencies(todoId, func(tx *gorm.DB) error {
+		result := tx.Where("todo_id = ? AND blocked_by_id = ?", todoId, blockedById).Delete(&models.TodoDependency{})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	})
+}
+
+// changeDependencies runs change and records how the blockers of the todo changed
+func (r *TodoRepository) changeDependencies(todoId uint, change func(tx *gorm.DB) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		before, err := blockerIDsJSON(tx, todoId)
+		if err != nil {
+			return err
+		}
+		if err := change(tx); err != nil {
+			return err
+		}
+		after, err := blockerIDsJSON(tx, todoId)
+		if err != nil {
+			return err
+		}
+		return r.withDB(tx).record(models.TodoHistory{
+			TodoID:  todoId,
+			Action:  models.HistoryUpdated,
+			Changes: models.FieldChanges{"blocked_by": {Before: before, After: after}},
+		})
+	})
+}
+
+func blockerIDsJSON(db *gorm.DB, todoId uint) (json.RawMessage, error) {
+	ids := []uint{}
+	err := db.Model(&models.TodoDependency{}).Where("todo_id = ?", todoId).Order("blocked_by_id").Pluck("blocked_by_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(ids)
+	return data, err
+}
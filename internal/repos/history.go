@@ -0,0 +1,94 @@
+package repos
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+	"todo-list/internal/models"
+)
+
+// historyFields are the JSON names of the todo fields tracked in the history
+var historyFields = []string{
+	"title", "description", "is_completed", "priority", "due_date", "due_timezone",
+	"list_id", "parent_id", "recurrence", "position", "status_id", "assignee_id",
+}
+
+// GetHistory returns the change log of a todo, oldest entry first
+func (r *TodoRepository) GetHistory(todoId string) ([]models.TodoHistory, error) {
+	var history []models.TodoHistory
+	err := r.db.Where("todo_id = ?", todoId).Order("created_at, id").Find(&history).Error
+	return history, err
+}
+
+// record appends entries to the history of todos, attributed to the actor of the repository
+func (r *TodoRepository) record(entries ...models.TodoHistory) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	for i := range entries {
+		entries[i].ActorID = r.actor
+	}
+	return r.db.Create(&entries).Error
+}
+
+// diffTodos returns the tracked fields that differ between two versions of a todo,
+// before is nil for new todos
+func diffTodos(before, after *models.Todo) models.FieldChanges {
+	afterFields := todoFields(after)
+	var beforeFields map[string]json.RawMessage
+	if before != nil {
+		beforeFields = todoFields(before)
+	}
+
+	changes := models.FieldChanges{}
+	for _, field := range historyFields {
+		old, new := beforeFields[field], afterFields[field]
+		if before == nil && isEmptyJSON(new) {
+			continue
+		}
+		if before != nil && bytes.Equal(old, new) {
+			continue
+		}
+		changes[field] = models.FieldChange{Before: old, After: new}
+	}
+	return changes
+}
+
+// todoFields encodes the tracked fields of a todo the same way the API does.
+// Due dates are compared in UTC at the precision the databases store.
+func todoFields(todo *models.Todo) map[string]json.RawMessage {
+	snapshot := *todo
+	if snapshot.DueDate != nil {
+		due := snapshot.DueDate.UTC().Truncate(time.Microsecond)
+		snapshot.DueDate = &due
+	}
+	data, _ := json.Marshal(snapshot)
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+func isEmptyJSON(value json.RawMessage) bool {
+	switch string(value) {
+	case "", "null", `""`, "false", "0", `"none"`:
+		return true
+	}
+	return false
+}
+
+func tagIDsJSON(tags []models.Tag) json.RawMessage {
+	ids := make([]uint, len(tags))
+	for i, tag := range tags {
+		ids[i] = tag.ID
+	}
+	data, _ := json.Marshal(ids)
+	return data
+}
+
+func historyEntries(ids []uint, action string) []models.TodoHistory {
+	entries := make([]models.TodoHistory, len(ids))
+	for i, id := range ids {
+		entries[i] = models.TodoHistory{TodoID: id, Action: action}
+	}
+	return entries
+}
@@ -0,0 +1,87 @@
+package repos
+
+import (
+	"errors"
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ListRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for ListRepository
+func NewListRepository(db *gorm.DB) *ListRepository {
+	return &ListRepository{db}
+}
+
+// Fetch all lists of a scope, the inbox first
+func (r *ListRepository) GetAllLists(scope Scope) ([]models.List, error) {
+	var lists []models.List
+	err := scope.where(r.db, "lists").Order("is_inbox DESC, id").Find(&lists).Error
+	return lists, err
+}
+
+// get a list
+func (r *ListRepository) GetList(id string) (models.List, error) {
+	var list models.List
+	return list, r.db.Where("id = ?", id).First(&list).Error
+}
+
+// GetInbox returns the inbox of a user. Users registered before lists existed
+// get their inbox created here, and it adopts all of their todos without a list.
+func (r *ListRepository) GetInbox(userId uint) (models.List, error) {
+	var inbox models.List
+	err := r.db.Where("org_id IS NULL AND user_id = ? AND is_inbox = ?", userId, true).First(&inbox).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return inbox, err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if inbox, err = createInbox(tx, userId, nil); err != nil {
+			return err
+		}
+		return tx.Model(&models.Todo{}).
+			Where("org_id IS NULL AND user_id = ? AND list_id IS NULL", userId).
+			Update("list_id", inbox.ID).Error
+	})
+	return inbox, err
+}
+
+// GetOrgInbox returns the inbox of an organization, it is created together with the organization
+func (r *ListRepository) GetOrgInbox(orgId uint) (models.List, error) {
+	var inbox models.List
+	return inbox, r.db.Where("org_id = ? AND is_inbox = ?", orgId, true).First(&inbox).Error
+}
+
+// Save a new list
+func (r *ListRepository) CreateList(list *models.List) error {
+	return r.db.Create(list).Error
+}
+
+// update a list
+func (r *ListRepository) UpdateList(list *models.List) error {
+	return r.db.Save(list).Error
+}
+
+// delete a list, its todos are moved to the given list first
+func (r *ListRepository) DeleteList(list *models.List, moveTodosTo uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// trashed todos move as well so restoring them does not point at a missing list
+		err := tx.Unscoped().Model(&models.Todo{}).Where("list_id = ?", list.ID).Update("list_id", moveTodosTo).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("list_id = ?", list.ID).Delete(&models.Share{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(list).Error
+	})
+}
+
+func createInbox(tx *gorm.DB, userId uint, orgId *uint) (models.List, error) {
+	inbox := models.List{Name: models.InboxListName, IsInbox: true, UserID: userId, OrgID: orgId}
+	return inbox, tx.Create(&inbox).Error
+}
@@ -0,0 +1,60 @@
+package repos
+
+import (
+	"time"
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for NotificationRepository
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db}
+}
+
+// Fetch the notifications of a user, newest first
+func (r *NotificationRepository) GetNotifications(userId uint, unreadOnly bool) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := r.db.Where("user_id = ?", userId)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Order("created_at DESC, id DESC").Find(&notifications).Error
+	return notifications, err
+}
+
+// Save new notifications
+func (r *NotificationRepository) CreateNotifications(notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.Create(&notifications).Error
+}
+
+// MarkRead marks one notification of a user as read, ErrRecordNotFound if there is none
+func (r *NotificationRepository) MarkRead(userId uint, id string) (models.Notification, error) {
+	var notification models.Notification
+	if err := r.db.Where("user_id = ? AND id = ?", userId, id).First(&notification).Error; err != nil {
+		return notification, err
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := r.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			return notification, err
+		}
+	}
+	return notification, nil
+}
+
+// MarkAllRead marks every unread notification of a user as read and returns how many there were
+func (r *NotificationRepository) MarkAllRead(userId uint) (int64, error) {
+	result := r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userId).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
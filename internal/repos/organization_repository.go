@@ -0,0 +1,146 @@
+package repos
+
+import (
+	"errors"
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type OrganizationRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for OrganizationRepository
+func NewOrganizationRepository(db *gorm.DB) *OrganizationRepository {
+	return &OrganizationRepository{db}
+}
+
+// CreateOrganization saves a new organization with its inbox list, the creator becomes its first owner
+func (r *OrganizationRepository) CreateOrganization(org *models.Organization, ownerId uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		membership := models.Membership{OrgID: org.ID, UserID: ownerId, Role: models.OrgOwner}
+		if err := tx.Create(&membership).Error; err != nil {
+			return err
+		}
+		org.Role = membership.Role
+		_, err := createInbox(tx, ownerId, &org.ID)
+		return err
+	})
+}
+
+// Fetch the organizations a user is a member of with the role of the user
+func (r *OrganizationRepository) GetOrganizations(userId uint) ([]models.Organization, error) {
+	var orgs []models.Organization
+	err := r.db.Select("organizations.*, memberships.role").
+		Joins("JOIN memberships ON memberships.org_id = organizations.id").
+		Where("memberships.user_id = ?", userId).
+		Order("organizations.id").
+		Find(&orgs).Error
+	return orgs, err
+}
+
+// get an organization
+func (r *OrganizationRepository) GetOrganization(id string) (models.Organization, error) {
+	var org models.Organization
+	return org, r.db.Where("id = ?", id).First(&org).Error
+}
+
+// Fetch the members of an organization with their usernames, owners first
+func (r *OrganizationRepository) GetMembers(orgId uint) ([]models.Membership, error) {
+	var members []models.Membership
+	err := r.db.Select("memberships.*, users.username").
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.org_id = ?", orgId).
+		Order("users.username").
+		Find(&members).Error
+	return members, err
+}
+
+// MemberRole returns the role of a user in an organization, an empty string if they are not a member
+func (r *OrganizationRepository) MemberRole(orgId, userId uint) (string, error) {
+	var roles []string
+	err := r.db.Model(&models.Membership{}).Where("org_id = ? AND user_id = ?", orgId, userId).Pluck("role", &roles).Error
+	if err != nil || len(roles) == 0 {
+		return "", err
+	}
+	return roles[0], nil
+}
+
+// CountOwners returns how many owners an organization has
+func (r *OrganizationRepository) CountOwners(orgId uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Membership{}).Where("org_id = ? AND role = ?", orgId, models.OrgOwner).Count(&count).Error
+	return count, err
+}
+
+// SetMemberRole changes the role of a member, ErrRecordNotFound if the user is not a member
+func (r *OrganizationRepository) SetMemberRole(orgId, userId uint, role string) error {
+	result := r.db.Model(&models.Membership{}).Where("org_id = ? AND user_id = ?", orgId, userId).Update("role", role)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// DeleteMember removes a user from an organization, ErrRecordNotFound if they are not a member
+func (r *OrganizationRepository) DeleteMember(orgId, userId uint) error {
+	result := r.db.Where("org_id = ? AND user_id = ?", orgId, userId).Delete(&models.Membership{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// SaveInvitation invites a user to an organization, inviting them again changes the role
+func (r *OrganizationRepository) SaveInvitation(invitation *models.Invitation) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Invitation
+		err := tx.Where("org_id = ? AND user_id = ?", invitation.OrgID, invitation.UserID).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(invitation).Error
+		}
+		if err != nil {
+			return err
+		}
+		invitation.ID, invitation.CreatedAt = existing.ID, existing.CreatedAt
+		return tx.Model(invitation).Updates(map[string]interface{}{"role": invitation.Role, "inviter_id": invitation.InviterID}).Error
+	})
+}
+
+// Fetch the open invitations of a user with the names of their organizations
+func (r *OrganizationRepository) GetInvitations(userId uint) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.db.Select("invitations.*, organizations.name AS org_name").
+		Joins("JOIN organizations ON organizations.id = invitations.org_id").
+		Where("invitations.user_id = ?", userId).
+		Order("invitations.id").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// GetInvitation gets an invitation addressed to a user, ErrRecordNotFound for invitations of other users
+func (r *OrganizationRepository) GetInvitation(userId uint, id string) (models.Invitation, error) {
+	var invitation models.Invitation
+	return invitation, r.db.Where("user_id = ? AND id = ?", userId, id).First(&invitation).Error
+}
+
+// AcceptInvitation turns an invitation into a membership
+func (r *OrganizationRepository) AcceptInvitation(invitation *models.Invitation) (models.Membership, error) {
+	membership := models.Membership{OrgID: invitation.OrgID, UserID: invitation.UserID, Role: invitation.Role}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&membership).Error; err != nil {
+			return err
+		}
+		return tx.Delete(invitation).Error
+	})
+	return membership, err
+}
+
+// delete an invitation
+func (r *OrganizationRepository) DeleteInvitation(invitation *models.Invitation) error {
+	return r.db.Delete(invitation).Error
+}
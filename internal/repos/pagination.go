@@ -0,0 +1,128 @@
+package repos
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+	"todo-list/internal/models"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued for the requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a sorted list of todos. Clients only ever see it encoded.
+type Cursor struct {
+	SortBy   string  `json:"s,omitempty"`
+	SortDesc bool    `json:"d,omitempty"`
+	Value    *string `json:"v,omitempty"` // sort column value of the todo, nil for NULL and when sorting by id
+	ID       uint    `json:"i"`
+	Backward bool    `json:"b,omitempty"` // page towards the start of the list
+}
+
+// TodoPage is one page of todos together with the cursors of its neighbours
+type TodoPage struct {
+	Todos      []models.Todo
+	NextCursor string // empty when this is the last page
+	PrevCursor string // empty when this is the first page
+}
+
+// Encode turns the cursor into an opaque URL safe string
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// cursorFor builds the cursor pointing at a todo for the sort order of the filter
+func cursorFor(todo models.Todo, filter TodoFilter, backward bool) string {
+	cursor := Cursor{SortBy: filter.SortBy, SortDesc: filter.SortDesc, ID: todo.ID, Backward: backward}
+	var value string
+	switch filter.SortBy {
+	case "priority":
+		value = strconv.Itoa(int(todo.Priority))
+	case "due_date":
+		if todo.DueDate == nil {
+			return cursor.Encode()
+		}
+		value = todo.DueDate.UTC().Format(time.RFC3339Nano)
+	case "created_at":
+		value = todo.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		value = todo.UpdatedAt.Format(time.RFC3339Nano)
+	case "position":
+		value = todo.Position
+	default:
+		return cursor.Encode()
+	}
+	cursor.Value = &value
+	return cursor.Encode()
+}
+
+// cursorValue converts the value stored in a cursor back to the type of its column
+func cursorValue(sortBy string, value string) (interface{}, error) {
+	switch sortBy {
+	case "priority":
+		return strconv.Atoi(value)
+	case "due_date", "created_at", "updated_at":
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return value, nil
+}
+
+// keysetCondition returns the WHERE clause selecting the todos after the cursor,
+// or before it for backward cursors, in the order built by todoOrder
+func keysetCondition(filter TodoFilter, cursor *Cursor) (string, []interface{}, error) {
+	if cursor.SortBy != filter.SortBy || cursor.SortDesc != filter.SortDesc {
+		return "", nil, ErrInvalidCursor
+	}
+
+	op := ">"
+	if filter.SortDesc != cursor.Backward {
+		op = "<"
+	}
+	column, ok := TodoSortFields[filter.SortBy]
+	if !ok {
+		return "id " + op + " ?", []interface{}{cursor.ID}, nil
+	}
+	// NULL due dates come last in both directions
+	nullable := column == "due_date"
+
+	if cursor.Value == nil {
+		if !nullable {
+			return "", nil, ErrInvalidCursor
+		}
+		if cursor.Backward {
+			return "(" + column + " IS NOT NULL OR (" + column + " IS NULL AND id " + op + " ?))", []interface{}{cursor.ID}, nil
+		}
+		return "(" + column + " IS NULL AND id " + op + " ?)", []interface{}{cursor.ID}, nil
+	}
+
+	value, err := cursorValue(filter.SortBy, *cursor.Value)
+	if err != nil {
+		return "", nil, ErrInvalidCursor
+	}
+	condition := column + " " + op + " ? OR (" + column + " = ? AND id " + op + " ?)"
+	if nullable && !cursor.Backward {
+		condition += " OR " + column + " IS NULL"
+	}
+	return "(" + condition + ")", []interface{}{value, value, cursor.ID}, nil
+}
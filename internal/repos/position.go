@@ -0,0 +1,102 @@
+package repos
+
+import (
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// MigratePositions makes Postgres compare positions byte by byte like SQLite does,
+// the default collation of the database would sort "a" before "B"
+func MigratePositions(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	var collation *string
+	err := db.Raw(`SELECT collation_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'todos' AND column_name = 'position'`).Scan(&collation).Error
+	if err != nil || (collation != nil && *collation == "C") {
+		return err
+	}
+	return db.Exec(`ALTER TABLE todos ALTER COLUMN position TYPE text COLLATE "C"`).Error
+}
+
+// PositionScope is the group of todos ordered by their positions: the direct
+// subtasks of a parent, or the top level todos, of one list. Zero ids mean NULL, except
+// for UserID which is zero for the todos of an organization as its members share its lists.
+type PositionScope struct {
+	UserID   uint
+	ListID   uint
+	ParentID uint
+}
+
+// ScopePositions returns the ids and positions of the todos of a scope in position order
+func (r *TodoRepository) ScopePositions(scope PositionScope) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := scopeQuery(r.db, scope).Select("id", "position").Order("position, id").Find(&todos).Error
+	return todos, err
+}
+
+// LastPosition returns the highest position in a scope, empty when it has no todos
+func (r *TodoRepository) LastPosition(scope PositionScope) (string, error) {
+	var positions []string
+	err := scopeQuery(r.db, scope).Order("position DESC").Limit(1).Pluck("position", &positions).Error
+	if err != nil || len(positions) == 0 {
+		return "", err
+	}
+	return positions[0], nil
+}
+
+// SetPositions changes positions without touching updated_at or the history,
+// rebalancing keeps the order so it is not a change of the todos
+func (r *TodoRepository) SetPositions(positions map[uint]string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for id, position := range positions {
+			if err := tx.Unscoped().Model(&models.Todo{}).Where("id = ?", id).UpdateColumn("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LongPositionScopes returns the scopes with positions longer than maxLength
+func (r *TodoRepository) LongPositionScopes(maxLength int) ([]PositionScope, error) {
+	var rows []struct {
+		UserID   uint
+		ListID   *uint
+		ParentID *uint
+	}
+	err := r.db.Model(&models.Todo{}).
+		Select("DISTINCT CASE WHEN org_id IS NULL THEN user_id ELSE 0 END AS user_id, list_id, parent_id").
+		Where("LENGTH(position) > ?", maxLength).
+		Scan(&rows).Error
+
+	scopes := make([]PositionScope, len(rows))
+	for i, row := range rows {
+		scopes[i].UserID = row.UserID
+		if row.ListID != nil {
+			scopes[i].ListID = *row.ListID
+		}
+		if row.ParentID != nil {
+			scopes[i].ParentID = *row.ParentID
+		}
+	}
+	return scopes, err
+}
+
+func scopeQuery(db *gorm.DB, scope PositionScope) *gorm.DB {
+	query := db.Model(&models.Todo{})
+	if scope.UserID != 0 {
+		query = query.Where("user_id = ?", scope.UserID)
+	}
+	if scope.ListID == 0 {
+		query = query.Where("list_id IS NULL")
+	} else {
+		query = query.Where("list_id = ?", scope.ListID)
+	}
+	if scope.ParentID == 0 {
+		return query.Where("parent_id IS NULL")
+	}
+	return query.Where("parent_id = ?", scope.ParentID)
+}
@@ -0,0 +1,63 @@
+package repos
+
+import (
+	"time"
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for RefreshTokenRepository
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db}
+}
+
+func (r *RefreshTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetRefreshToken returns the token with the given hash, ErrRecordNotFound if there is none
+func (r *RefreshTokenRepository) GetRefreshToken(hash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("hash = ?", hash).First(&token).Error
+	return token, err
+}
+
+// MarkUsed marks a token as used, false when it had been used already. The check and
+// the update are one statement so two concurrent refreshes cannot both succeed.
+func (r *RefreshTokenRepository) MarkUsed(token *models.RefreshToken, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", usedAt)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	token.UsedAt = &usedAt
+	return true, nil
+}
+
+// RevokeFamily revokes every token descending from the same login
+func (r *RefreshTokenRepository) RevokeFamily(family string, revokedAt time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family = ? AND revoked_at IS NULL", family).
+		Update("revoked_at", revokedAt).Error
+}
+
+// RevokeUser revokes the refresh tokens of every login of a user and returns how many
+// logins were still active
+func (r *RefreshTokenRepository) RevokeUser(userId uint, revokedAt time.Time) (int64, error) {
+	var families int64
+	active := r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND used_at IS NULL AND expires_at > ?", userId, revokedAt)
+	if err := active.Distinct("family").Count(&families).Error; err != nil {
+		return 0, err
+	}
+	err := r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", revokedAt).Error
+	return families, err
+}
@@ -0,0 +1,43 @@
+package repos
+
+import (
+	"gorm.io/gorm"
+)
+
+// Scope is the data a request works on: the personal todos and lists of a user, or the
+// ones of the organization the user switched to. Handlers only build an organization
+// scope after checking the membership of the user.
+type Scope struct {
+	UserID uint
+	OrgID  *uint // nil for the personal data of the user
+}
+
+// PersonalScope is the scope of the personal data of a user
+func PersonalScope(userId uint) Scope {
+	return Scope{UserID: userId}
+}
+
+// condition returns the SQL condition selecting the rows of the scope from a table with
+// org_id and user_id columns. The rows of an organization are never personal data of
+// their creator, and the rows of one organization never show up in another.
+func (s Scope) condition(table string) (string, []interface{}) {
+	if s.OrgID == nil {
+		return table + ".org_id IS NULL AND " + table + ".user_id = ?", []interface{}{s.UserID}
+	}
+	return table + ".org_id = ?", []interface{}{*s.OrgID}
+}
+
+// where restricts a query on table to the rows of the scope
+func (s Scope) where(query *gorm.DB, table string) *gorm.DB {
+	condition, args := s.condition(table)
+	return query.Where(condition, args...)
+}
+
+// todoCondition is the condition of the scope for the todos table. The personal scope also includes the
+// todos shared with the user, directly or through their list or a parent todo.
+func (s Scope) todoCondition(db *gorm.DB) (string, []interface{}) {
+	if s.OrgID == nil {
+		return "todos.org_id IS NULL AND (todos.user_id = ? OR todos.id IN (?))", []interface{}{s.UserID, sharedTodoIDs(db, s.UserID)}
+	}
+	return s.condition("todos")
+}
@@ -0,0 +1,328 @@
+package repos
+
+import (
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SearchResult is a todo matching a search query. Rank is higher for better matches
+// and Snippet is HTML escaped text with the matched words wrapped in <mark> tags.
+type SearchResult struct {
+	Todo    models.Todo `json:"todo"`
+	Rank    float64     `json:"rank"`
+	Snippet string      `json:"snippet"`
+}
+
+// TodoSearcher runs full-text searches over the titles and descriptions of todos.
+// Every database driver has its own implementation, all returning the same shape.
+type TodoSearcher interface {
+	Search(scope Scope, query string, limit int) ([]SearchResult, error)
+}
+
+// the database highlights matches with these markers, they are turned into
+// <mark> tags once the rest of the snippet has been escaped
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// NewTodoSearcher picks the search implementation for the driver of db
+func NewTodoSearcher(db *gorm.DB) TodoSearcher {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return &postgresSearcher{db}
+	case "sqlite":
+		var count int64
+		db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'todos_fts'").Scan(&count)
+		if count > 0 {
+			return &sqliteSearcher{db}
+		}
+	}
+	return &likeSearcher{db}
+}
+
+// MigrateSearch creates the full-text indexes of the driver. On Postgres this is a
+// generated tsvector column with a GIN index, on SQLite an FTS5 table kept in sync by
+// triggers. SQLite builds without FTS5 (the sqlite_fts5 build tag of go-sqlite3) fall
+// back to LIKE queries.
+func MigrateSearch(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, statement := range postgresSearchSchema {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case "sqlite":
+		var count int64
+		if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'todos_fts'").Scan(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		if err := db.Exec(sqliteSearchSchema[0]).Error; err != nil {
+			if strings.Contains(err.Error(), "no such module") {
+				log.Println("SQLite was built without FTS5, search falls back to LIKE queries, which are only meant for development")
+				return nil
+			}
+			return err
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, statement := range sqliteSearchSchema[1:] {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+var postgresSearchSchema = []string{
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(description, '')), 'B')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_todos_search_vector ON todos USING GIN (search_vector)`,
+}
+
+var sqliteSearchSchema = []string{
+	`CREATE VIRTUAL TABLE todos_fts USING fts5(title, description, content='todos', content_rowid='id')`,
+	`CREATE TRIGGER todos_fts_insert AFTER INSERT ON todos BEGIN
+		INSERT INTO todos_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER todos_fts_delete AFTER DELETE ON todos BEGIN
+		INSERT INTO todos_fts(todos_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+	END`,
+	`CREATE TRIGGER todos_fts_update AFTER UPDATE OF title, description ON todos BEGIN
+		INSERT INTO todos_fts(todos_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		INSERT INTO todos_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`INSERT INTO todos_fts(todos_fts) VALUES ('rebuild')`,
+}
+
+type searchHit struct {
+	ID      uint
+	Rank    float64
+	Snippet string
+}
+
+type postgresSearcher struct {
+	db *gorm.DB
+}
+
+func (s *postgresSearcher) Search(scope Scope, query string, limit int) ([]SearchResult, error) {
+	condition, args := scope.todoCondition(s.db)
+	var hits []searchHit
+	err := s.db.Raw(`
+		SELECT todos.id, ts_rank(todos.search_vector, q) AS rank,
+			ts_headline('english', todos.title || ' ' || coalesce(todos.description, ''), q,
+				'StartSel=`+markStart+`, StopSel=`+markEnd+`, MaxWords=24, MinWords=8, MaxFragments=2') AS snippet
+		FROM todos, websearch_to_tsquery('english', ?) q
+		WHERE `+condition+` AND todos.deleted_at IS NULL AND todos.search_vector @@ q
+		ORDER BY rank DESC, todos.id DESC
+		LIMIT ?`, append(append([]interface{}{query}, args...), limit)...).Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return loadSearchResults(s.db, hits)
+}
+
+type sqliteSearcher struct {
+	db *gorm.DB
+}
+
+func (s *sqliteSearcher) Search(scope Scope, query string, limit int) ([]SearchResult, error) {
+	match := fts5Query(query)
+	if match == "" {
+		return []SearchResult{}, nil
+	}
+
+	// bm25 is lower for better matches, title matches weigh twice as much
+	condition, args := scope.todoCondition(s.db)
+	var hits []searchHit
+	err := s.db.Raw(`
+		SELECT todos.id, -bm25(todos_fts, 2.0, 1.0) AS rank,
+			snippet(todos_fts, -1, '`+markStart+`', '`+markEnd+`', '…', 16) AS snippet
+		FROM todos_fts JOIN todos ON todos.id = todos_fts.rowid
+		WHERE todos_fts MATCH ? AND `+condition+` AND todos.deleted_at IS NULL
+		ORDER BY rank DESC, todos.id DESC
+		LIMIT ?`, append(append([]interface{}{match}, args...), limit)...).Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return loadSearchResults(s.db, hits)
+}
+
+// fts5Query quotes every word of the user input so FTS5 operators in it are taken
+// literally, the last word is matched as a prefix to support search as you type
+func fts5Query(query string) string {
+	words := strings.Fields(query)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	if len(words) > 0 {
+		words[len(words)-1] += "*"
+	}
+	return strings.Join(words, " ")
+}
+
+// likeCandidates bounds how many matching todos the LIKE fallback ranks per search,
+// the newest ones are kept
+const likeCandidates = 500
+
+// likeSearcher is used when the database has no full-text support, every word
+// has to appear in the title or description and title matches rank higher. It scans
+// the todos of the scope and is meant for development, not for large databases.
+type likeSearcher struct {
+	db *gorm.DB
+}
+
+func (s *likeSearcher) Search(scope Scope, query string, limit int) ([]SearchResult, error) {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	condition, args := scope.todoCondition(s.db)
+	q := s.db.Preload("Tags").Where(condition, args...)
+	for _, word := range words {
+		pattern := "%" + escapeLike(word) + "%"
+		q = q.Where("(LOWER(title) LIKE ? ESCAPE '\\' OR LOWER(description) LIKE ? ESCAPE '\\')", pattern, pattern)
+	}
+	var todos []models.Todo
+	if err := q.Order("todos.id DESC").Limit(likeCandidates).Find(&todos).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(todos))
+	for _, todo := range todos {
+		var rank float64
+		title, description := strings.ToLower(todo.Title), strings.ToLower(todo.Description)
+		for _, word := range words {
+			rank += 2*float64(strings.Count(title, word)) + float64(strings.Count(description, word))
+		}
+		text := todo.Title
+		if todo.Description != "" {
+			text += " " + todo.Description
+		}
+		results = append(results, SearchResult{Todo: todo, Rank: rank, Snippet: highlight(text, words)})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Todo.ID > results[j].Todo.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// loadSearchResults loads the todos of the hits, keeping the order of the hits
+func loadSearchResults(db *gorm.DB, hits []searchHit) ([]SearchResult, error) {
+	results := make([]SearchResult, 0, len(hits))
+	if len(hits) == 0 {
+		return results, nil
+	}
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var todos []models.Todo
+	if err := db.Preload("Tags").Where("id IN ?", ids).Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Todo, len(todos))
+	for _, todo := range todos {
+		byID[todo.ID] = todo
+	}
+	for _, hit := range hits {
+		if todo, ok := byID[hit.ID]; ok {
+			results = append(results, SearchResult{Todo: todo, Rank: hit.Rank, Snippet: markSnippet(hit.Snippet)})
+		}
+	}
+	return results, nil
+}
+
+// markSnippet escapes a snippet highlighted with markStart/markEnd and turns the markers into <mark> tags
+func markSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>").Replace(snippet)
+}
+
+// highlight marks every case insensitive occurrence of the words in text,
+// long texts are cut to a window around the first match
+func highlight(text string, words []string) string {
+	const window = 120
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// lower casing changed the byte offsets, do not risk cutting inside a rune
+		return html.EscapeString(text)
+	}
+	first := -1
+	for _, word := range words {
+		if i := strings.Index(lower, word); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+
+	start, end := 0, len(text)
+	if len(text) > window && first > window/3 {
+		start = first - window/3
+	}
+	if end-start > window {
+		end = start + window
+	}
+	// only cut on byte boundaries of whole runes
+	for start > 0 && !utf8Start(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8Start(text[end]) {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	segment, lowerSegment := text[start:end], lower[start:end]
+	for i := 0; i < len(segment); {
+		matched := 0
+		for _, word := range words {
+			if strings.HasPrefix(lowerSegment[i:], word) && len(word) > matched {
+				matched = len(word)
+			}
+		}
+		if matched > 0 {
+			b.WriteString(markStart + segment[i:i+matched] + markEnd)
+			i += matched
+		} else {
+			b.WriteByte(segment[i])
+			i++
+		}
+	}
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return markSnippet(b.String())
+}
+
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,115 @@
+package repos
+
+import (
+	"errors"
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ShareRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for ShareRepository
+func NewShareRepository(db *gorm.DB) *ShareRepository {
+	return &ShareRepository{db}
+}
+
+// Fetch the shares of a todo with the usernames of their recipients
+func (r *ShareRepository) GetTodoShares(todoId uint) ([]models.Share, error) {
+	return r.getShares("shares.todo_id = ?", todoId)
+}
+
+// Fetch the shares of a list with the usernames of their recipients
+func (r *ShareRepository) GetListShares(listId uint) ([]models.Share, error) {
+	return r.getShares("shares.list_id = ?", listId)
+}
+
+func (r *ShareRepository) getShares(condition string, id uint) ([]models.Share, error) {
+	var shares []models.Share
+	err := r.db.Select("shares.*, users.username").
+		Joins("JOIN users ON users.id = shares.user_id").
+		Where(condition, id).
+		Order("shares.id").
+		Find(&shares).Error
+	return shares, err
+}
+
+// SaveShare creates the share of a todo or list, or changes the role of the
+// recipient when it was already shared with them
+func (r *ShareRepository) SaveShare(share *models.Share) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Share
+		query := tx.Where("user_id = ?", share.UserID)
+		if share.TodoID != nil {
+			query = query.Where("todo_id = ?", *share.TodoID)
+		} else {
+			query = query.Where("list_id = ?", *share.ListID)
+		}
+		err := query.First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(share).Error
+		}
+		if err != nil {
+			return err
+		}
+		share.ID, share.CreatedAt = existing.ID, existing.CreatedAt
+		return tx.Model(share).Update("role", share.Role).Error
+	})
+}
+
+// DeleteTodoShare removes the share of a todo with a user, ErrRecordNotFound if there is none
+func (r *ShareRepository) DeleteTodoShare(todoId, userId uint) error {
+	return r.deleteShare(r.db.Where("todo_id = ? AND user_id = ?", todoId, userId))
+}
+
+// DeleteListShare removes the share of a list with a user, ErrRecordNotFound if there is none
+func (r *ShareRepository) DeleteListShare(listId, userId uint) error {
+	return r.deleteShare(r.db.Where("list_id = ? AND user_id = ?", listId, userId))
+}
+
+func (r *ShareRepository) deleteShare(query *gorm.DB) error {
+	result := query.Delete(&models.Share{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// TodoRoles returns the roles a user was given on a todo, through a share of the todo,
+// of one of its parents or of the list of one of them. Trashed todos are included.
+func (r *ShareRepository) TodoRoles(userId, todoId uint) ([]string, error) {
+	var roles []string
+	err := r.db.Raw(`WITH RECURSIVE ancestors(id, parent_id, list_id) AS (
+			SELECT id, parent_id, list_id FROM todos WHERE id = ?
+			UNION SELECT todos.id, todos.parent_id, todos.list_id FROM todos JOIN ancestors ON todos.id = ancestors.parent_id
+		)
+		SELECT role FROM shares WHERE user_id = ? AND (
+			todo_id IN (SELECT id FROM ancestors) OR list_id IN (SELECT list_id FROM ancestors)
+		)`, todoId, userId).Scan(&roles).Error
+	return roles, err
+}
+
+// ListRole returns the role a user was given on a list, an empty string if it was not shared with them
+func (r *ShareRepository) ListRole(userId, listId uint) (string, error) {
+	var roles []string
+	err := r.db.Model(&models.Share{}).Where("user_id = ? AND list_id = ?", userId, listId).Pluck("role", &roles).Error
+	if err != nil || len(roles) == 0 {
+		return "", err
+	}
+	return roles[0], nil
+}
+
+// sharedTodoIDs is a subquery of the todos shared with a user, directly, as subtasks
+// of a shared todo or through a shared list
+func sharedTodoIDs(db *gorm.DB, userId uint) *gorm.DB {
+	return db.Raw(`WITH RECURSIVE shared(id) AS (
+			SELECT id FROM todos WHERE deleted_at IS NULL AND (
+				id IN (SELECT todo_id FROM shares WHERE user_id = ? AND todo_id IS NOT NULL)
+				OR list_id IN (SELECT list_id FROM shares WHERE user_id = ? AND list_id IS NOT NULL)
+			)
+			UNION SELECT todos.id FROM todos JOIN shared ON todos.parent_id = shared.id WHERE todos.deleted_at IS NULL
+		)
+		SELECT id FROM shared`, userId, userId)
+}
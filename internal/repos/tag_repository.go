@@ -0,0 +1,55 @@
+package repos
+
+import (
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type TagRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for TagRepository
+func NewTagRepository(db *gorm.DB) *TagRepository {
+	return &TagRepository{db}
+}
+
+// Fetch all tags of a user
+func (r *TagRepository) GetAllTags(userId uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.Where("user_id = ?", userId).Order("name").Find(&tags).Error
+	return tags, err
+}
+
+// get a tag
+func (r *TagRepository) GetTag(id string) (models.Tag, error) {
+	var tag models.Tag
+	return tag, r.db.Where("id = ?", id).First(&tag).Error
+}
+
+// find a tag of a user by its name
+func (r *TagRepository) GetTagByName(userId uint, name string) (models.Tag, error) {
+	var tag models.Tag
+	return tag, r.db.Where("user_id = ? AND name = ?", userId, name).First(&tag).Error
+}
+
+// Save a new tag
+func (r *TagRepository) CreateTag(tag *models.Tag) error {
+	return r.db.Create(tag).Error
+}
+
+// update a tag
+func (r *TagRepository) UpdateTag(tag *models.Tag) error {
+	return r.db.Save(tag).Error
+}
+
+// delete a tag, detaching it from all todos first
+func (r *TagRepository) DeleteTag(tag *models.Tag) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM todo_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+}
@@ -1,44 +1,455 @@
 package repos
 
 import (
+	"bytes"
+	"errors"
+	"time"
 	"todo-list/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrUnknownTag is returned when a todo refers to a tag that does not exist
+// or belongs to another user
+var ErrUnknownTag = errors.New("unknown tag")
+
 type TodoRepository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	search  TodoSearcher
+	actor   *uint      // user the history entries of mutations are attributed to
+	changes *changeSet // todos touched while tracking changes, see TrackChanges
 }
 
-// Constructor for TodoRepository
+// Constructor for TodoRepository, the database has to be migrated already
+// so the search implementation can be picked
 func NewTodoRepository(db *gorm.DB) *TodoRepository {
-	return &TodoRepository{db}
+	return &TodoRepository{db: db, search: NewTodoSearcher(db)}
+}
+
+// TodoFilter narrows down the todos returned by GetAllTodos.
+// Zero values mean "no restriction".
+type TodoFilter struct {
+	DueBefore     *time.Time
+	DueAfter      *time.Time
+	Overdue       bool // only incomplete todos whose due date has already passed
+	Completed     *bool
+	CreatedBefore *time.Time
+	CreatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	ListID        *uint
+	ParentID      *uint // only direct subtasks of this todo
+	AssigneeID    *uint // only todos assigned to this user
+
+	Tags        []string // tag names, see TagMatchAll
+	TagMatchAll bool     // require every tag instead of any of them
+
+	SortBy   string // one of the TodoSortFields, defaults to id
+	SortDesc bool
+
+	Limit  int     // page size, DefaultPageLimit when zero and at most MaxPageLimit
+	Cursor *Cursor // continue from a cursor of a previous page
+}
+
+// TodoSortFields maps the sort keys accepted by GetAllTodos to their columns
+var TodoSortFields = map[string]string{
+	"priority":   "priority",
+	"due_date":   "due_date",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"position":   "position",
+}
+
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *TodoRepository) Transaction(fn func(repo *TodoRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(r.withDB(tx))
+	})
+}
+
+// WithActor returns a repository recording its changes in the history as made by the given user
+func (r *TodoRepository) WithActor(userId uint) *TodoRepository {
+	repo := *r
+	repo.actor = &userId
+	return &repo
+}
+
+// Lists returns a list repository using the same database connection or transaction
+func (r *TodoRepository) Lists() *ListRepository {
+	return NewListRepository(r.db)
+}
+
+// withDB returns a copy of the repository running its queries on db
+func (r *TodoRepository) withDB(db *gorm.DB) *TodoRepository {
+	repo := *r
+	repo.db = db
+	return &repo
 }
 
-// Fetch all todos
-func (r *TodoRepository) GetAllTodos(userId uint) ([]models.Todo, error) {
+// Fetch one page of the todos of a scope matching the filter. The personal scope
+// includes the todos other users shared with the user.
+func (r *TodoRepository) GetAllTodos(scope Scope, filter TodoFilter) (TodoPage, error) {
+	var page TodoPage
+	condition, args := scope.todoCondition(r.db)
+	query := r.db.Preload("Tags").Where(condition, args...)
+	if filter.DueBefore != nil {
+		query = query.Where("due_date < ?", filter.DueBefore.UTC())
+	}
+	if filter.DueAfter != nil {
+		query = query.Where("due_date > ?", filter.DueAfter.UTC())
+	}
+	if filter.Overdue {
+		query = query.Where("due_date < ? AND is_completed = ?", time.Now().UTC(), false)
+	}
+	if filter.Completed != nil {
+		query = query.Where("is_completed = ?", *filter.Completed)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at > ?", filter.CreatedAfter.UTC())
+	}
+	if filter.UpdatedBefore != nil {
+		query = query.Where("updated_at < ?", filter.UpdatedBefore.UTC())
+	}
+	if filter.UpdatedAfter != nil {
+		query = query.Where("updated_at > ?", filter.UpdatedAfter.UTC())
+	}
+	if filter.ListID != nil {
+		query = query.Where("list_id = ?", *filter.ListID)
+	}
+	if filter.ParentID != nil {
+		query = query.Where("parent_id = ?", *filter.ParentID)
+	}
+	if filter.AssigneeID != nil {
+		query = query.Where("assignee_id = ?", *filter.AssigneeID)
+	}
+	if len(filter.Tags) > 0 {
+		// todos carry the tags of their owner, which for shared and organization
+		// todos is not necessarily the user asking
+		tagged := r.db.Table("todo_tags").
+			Select("todo_tags.todo_id").
+			Joins("JOIN tags ON tags.id = todo_tags.tag_id").
+			Joins("JOIN todos AS tagged_todos ON tagged_todos.id = todo_tags.todo_id").
+			Where("tags.user_id = tagged_todos.user_id AND tags.name IN ?", filter.Tags)
+		if filter.TagMatchAll {
+			tagged = tagged.Group("todo_tags.todo_id").Having("COUNT(DISTINCT tags.id) = ?", len(uniqueStrings(filter.Tags)))
+		}
+		query = query.Where("id IN (?)", tagged)
+	}
+
+	backward := false
+	if filter.Cursor != nil {
+		condition, args, err := keysetCondition(filter, filter.Cursor)
+		if err != nil {
+			return page, err
+		}
+		query = query.Where(condition, args...)
+		backward = filter.Cursor.Backward
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	// fetch one extra row to know whether there is another page
 	var todos []models.Todo
-	err := r.db.Where("user_id = ?", userId).Find(&todos).Error
-	return todos, err
+	if err := query.Order(todoOrder(filter, backward)).Limit(limit + 1).Find(&todos).Error; err != nil {
+		return page, err
+	}
+	hasMore := len(todos) > limit
+	if hasMore {
+		todos = todos[:limit]
+	}
+	if backward {
+		for i, j := 0, len(todos)-1; i < j; i, j = i+1, j-1 {
+			todos[i], todos[j] = todos[j], todos[i]
+		}
+	}
+	page.Todos = todos
+	if len(todos) == 0 {
+		return page, nil
+	}
+
+	// a page reached by paging backwards always has todos after it,
+	// a page reached by paging forwards always has todos before it
+	first, last := todos[0], todos[len(todos)-1]
+	if hasMore || backward {
+		page.NextCursor = cursorFor(last, filter, false)
+	}
+	if (hasMore && backward) || (filter.Cursor != nil && !backward) {
+		page.PrevCursor = cursorFor(first, filter, true)
+	}
+	return page, nil
+}
+
+// SearchTodos runs a full-text search over the todos of a scope, including the ones
+// shared with the user like GetAllTodos, best matches first
+func (r *TodoRepository) SearchTodos(scope Scope, query string, limit int) ([]SearchResult, error) {
+	return r.search.Search(scope, query, limit)
 }
 
-// Save a new todo
+// todoOrder builds the ORDER BY clause for a filter, reversed when paging backwards.
+// Todos without a due date are always listed last and the id breaks ties so the order is stable.
+func todoOrder(filter TodoFilter, reverse bool) string {
+	direction := "ASC"
+	if filter.SortDesc != reverse {
+		direction = "DESC"
+	}
+	column, ok := TodoSortFields[filter.SortBy]
+	if !ok {
+		return "id " + direction
+	}
+	if column == "due_date" {
+		nulls := "due_date IS NULL"
+		if reverse {
+			nulls += " DESC"
+		}
+		return nulls + ", due_date " + direction + ", id " + direction
+	}
+	return column + " " + direction + ", id " + direction
+}
+
+// Save a new todo, tags are attached separately with SetTodoTags
 func (r *TodoRepository) CreateTodo(todo *models.Todo) error {
-	return r.db.Create(todo).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(todo).Error; err != nil {
+			return err
+		}
+		r.trackCreated(todo.ID)
+		return r.withDB(tx).record(models.TodoHistory{TodoID: todo.ID, Action: models.HistoryCreated, Changes: diffTodos(nil, todo)})
+	})
 }
 
-// update a todo
+// update a todo, completing it is recorded as its own action in the history
 func (r *TodoRepository) UpdateTodo(todo *models.Todo) error {
-	return r.db.Save(todo).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.withDB(tx).track([]uint{todo.ID}); err != nil {
+			return err
+		}
+		var before models.Todo
+		if err := tx.Unscoped().First(&before, todo.ID).Error; err != nil {
+			return err
+		}
+		// unscoped so undo can change todos in the trash, the trash state itself is
+		// only changed by TrashTodos and RestoreTodos
+		if err := tx.Unscoped().Omit(clause.Associations).Save(todo).Error; err != nil {
+			return err
+		}
+
+		changes := diffTodos(&before, todo)
+		if len(changes) == 0 {
+			return nil
+		}
+		action := models.HistoryUpdated
+		if todo.IsCompleted && !before.IsCompleted {
+			action = models.HistoryCompleted
+		}
+		return r.withDB(tx).record(models.TodoHistory{TodoID: todo.ID, Action: action, Changes: changes})
+	})
+}
+
+// TrashTodos moves todos to the trash, their tag links are kept so they can be restored
+func (r *TodoRepository) TrashTodos(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.withDB(tx).track(ids); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Todo{}, ids).Error; err != nil {
+			return err
+		}
+		return r.withDB(tx).record(historyEntries(ids, models.HistoryDeleted)...)
+	})
+}
+
+// RestoreTodos takes todos out of the trash
+func (r *TodoRepository) RestoreTodos(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.withDB(tx).track(ids); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Todo{}).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return r.withDB(tx).record(historyEntries(ids, models.HistoryRestored)...)
+	})
 }
 
-// delete a todo
-func (r *TodoRepository) DeleteTodo(id string) error {
-	return r.db.Delete(&models.Todo{}, id).Error
+// PurgeTodos permanently deletes todos together with their tag links, dependencies and comments
+func (r *TodoRepository) PurgeTodos(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM todo_tags WHERE todo_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id IN ? OR blocked_by_id IN ?", ids, ids).Delete(&models.TodoDependency{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id IN ?", ids).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id IN ?", ids).Delete(&models.Share{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id IN ?", ids).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&models.Todo{}, ids).Error; err != nil {
+			return err
+		}
+		return r.withDB(tx).record(historyEntries(ids, models.HistoryPurged)...)
+	})
+}
+
+// GetTrash returns the trashed todos of a scope, most recently deleted first
+func (r *TodoRepository) GetTrash(scope Scope) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := scope.where(r.db.Unscoped().Preload("Tags"), "todos").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC, id DESC").
+		Find(&todos).Error
+	return todos, err
+}
+
+// GetTrashedTodo gets a todo that is in the trash
+func (r *TodoRepository) GetTrashedTodo(id string) (models.Todo, error) {
+	var todo models.Todo
+	return todo, r.db.Unscoped().Preload("Tags").Where("id = ? AND deleted_at IS NOT NULL", id).First(&todo).Error
+}
+
+// GetTrashedChildren returns the direct subtasks of the given todos that are in the trash
+func (r *TodoRepository) GetTrashedChildren(parentIds []uint) ([]models.Todo, error) {
+	var todos []models.Todo
+	if len(parentIds) == 0 {
+		return todos, nil
+	}
+	err := r.db.Unscoped().Where("parent_id IN ? AND deleted_at IS NOT NULL", parentIds).Order("id").Find(&todos).Error
+	return todos, err
+}
+
+// ExpiredTrash returns the ids of the todos that were trashed before the given time
+func (r *TodoRepository) ExpiredTrash(before time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Unscoped().Model(&models.Todo{}).Where("deleted_at < ?", before).Order("id").Pluck("id", &ids).Error
+	return ids, err
 }
 
 // get a todo
 func (r *TodoRepository) GetTodo(id string) (models.Todo, error) {
 	var todo models.Todo
-	return todo, r.db.First(&todo, id).Error
+	return todo, r.db.Preload("Tags").First(&todo, id).Error
+}
+
+// GetChildren returns the direct subtasks of the given todos
+func (r *TodoRepository) GetChildren(parentIds []uint) ([]models.Todo, error) {
+	var todos []models.Todo
+	if len(parentIds) == 0 {
+		return todos, nil
+	}
+	err := r.db.Preload("Tags").Where("parent_id IN ?", parentIds).Order("id").Find(&todos).Error
+	return todos, err
+}
+
+// GetProgress counts the completed and total direct subtasks per parent todo
+func (r *TodoRepository) GetProgress(parentIds []uint) (map[uint]models.Progress, error) {
+	progress := make(map[uint]models.Progress)
+	if len(parentIds) == 0 {
+		return progress, nil
+	}
+
+	var rows []struct {
+		ParentID  uint
+		Completed int64
+		Total     int64
+	}
+	err := r.db.Model(&models.Todo{}).
+		Select("parent_id, SUM(CASE WHEN is_completed THEN 1 ELSE 0 END) AS completed, COUNT(*) AS total").
+		Where("parent_id IN ?", parentIds).
+		Group("parent_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		progress[row.ParentID] = models.Progress{Completed: row.Completed, Total: row.Total}
+	}
+	return progress, err
+}
+
+// SetTodoTags replaces the tags of a todo. Every tag must belong to the owner of the todo.
+func (r *TodoRepository) SetTodoTags(todo *models.Todo, tagIds []uint) error {
+	var tags []models.Tag
+	if len(tagIds) > 0 {
+		if err := r.db.Where("user_id = ? AND id IN ?", todo.UserID, tagIds).Order("id").Find(&tags).Error; err != nil {
+			return err
+		}
+		if len(tags) != len(uniqueUints(tagIds)) {
+			return ErrUnknownTag
+		}
+	}
+
+	if err := r.track([]uint{todo.ID}); err != nil {
+		return err
+	}
+	var before []models.Tag
+	if err := r.db.Model(todo).Order("id").Association("Tags").Find(&before); err != nil {
+		return err
+	}
+
+	association := r.db.Model(todo).Omit("Tags.*").Association("Tags")
+	var err error
+	if len(tags) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(tags)
+	}
+	if err != nil {
+		return err
+	}
+	todo.Tags = tags
+
+	old, new := tagIDsJSON(before), tagIDsJSON(tags)
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	return r.record(models.TodoHistory{
+		TodoID:  todo.ID,
+		Action:  models.HistoryUpdated,
+		Changes: models.FieldChanges{"tags": {Before: old, After: new}},
+	})
+}
+
+func uniqueUints(values []uint) []uint {
+	seen := make(map[uint]bool, len(values))
+	var result []uint
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
 }
@@ -0,0 +1,50 @@
+package repos
+
+import (
+	"time"
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for TokenRepository
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db}
+}
+
+// Fetch the tokens of a user, newest first
+func (r *TokenRepository) GetTokens(userId uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.Where("user_id = ?", userId).Order("created_at DESC, id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *TokenRepository) CreateToken(token *models.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetTokenByHash returns the token with the given hash, ErrRecordNotFound if there is none
+func (r *TokenRepository) GetTokenByHash(hash string) (models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.Where("hash = ?", hash).First(&token).Error
+	return token, err
+}
+
+// DeleteToken deletes a token of a user, ErrRecordNotFound if there is none
+func (r *TokenRepository) DeleteToken(userId uint, id string) error {
+	result := r.db.Where("user_id = ?", userId).Delete(&models.APIToken{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// TouchToken records when a token was last used
+func (r *TokenRepository) TouchToken(token *models.APIToken, usedAt time.Time) error {
+	token.LastUsedAt = &usedAt
+	return r.db.Model(token).Update("last_used_at", usedAt).Error
+}
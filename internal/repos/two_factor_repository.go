@@ -0,0 +1,125 @@
+package repos
+
+import (
+	"time"
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for TwoFactorRepository
+func NewTwoFactorRepository(db *gorm.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db}
+}
+
+// SetSecret stores the TOTP secret of a user who starts enrolling, two-factor authentication stays disabled
+func (r *TwoFactorRepository) SetSecret(user *models.User, secret string) error {
+	user.TOTPSecret, user.TOTPEnabled, user.TOTPLastStep = secret, false, 0
+	return r.db.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": false, "totp_last_step": 0}).Error
+}
+
+// Enable turns on two-factor authentication and replaces the recovery codes of the user
+func (r *TwoFactorRepository) Enable(user *models.User, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		user.TOTPEnabled = true
+		return replaceRecoveryCodes(tx, user.ID, hashes)
+	})
+}
+
+// Disable turns off two-factor authentication and deletes the secret and recovery codes
+func (r *TwoFactorRepository) Disable(user *models.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{"totp_secret": "", "totp_enabled": false, "totp_last_step": 0}).Error
+		if err != nil {
+			return err
+		}
+		user.TOTPSecret, user.TOTPEnabled, user.TOTPLastStep = "", false, 0
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// ReplaceRecoveryCodes deletes the recovery codes of a user and saves new ones
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userId uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userId, hashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userId uint, hashes []string) error {
+	if err := tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]models.RecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = models.RecoveryCode{UserID: userId, Hash: hash}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseStep records the time step of an accepted code, false when a code of that step
+// or a later one was accepted before
+func (r *TwoFactorRepository) UseStep(user *models.User, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+// RecordFailure counts a wrong code of a user. The maxFailures-th wrong code in a row
+// locks the codes of the user until lockedUntil and starts the count again.
+func (r *TwoFactorRepository) RecordFailure(user *models.User, maxFailures int, lockedUntil time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			UpdateColumn("totp_failures", gorm.Expr("totp_failures + 1")).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Pluck("totp_failures", &user.TOTPFailures).Error; err != nil {
+			return err
+		}
+		if user.TOTPFailures < maxFailures {
+			return nil
+		}
+		user.TOTPFailures, user.TOTPLockedUntil = 0, &lockedUntil
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).
+			UpdateColumns(map[string]interface{}{"totp_failures": 0, "totp_locked_until": lockedUntil}).Error
+	})
+}
+
+// ResetFailures starts counting the wrong codes of a user from zero again
+func (r *TwoFactorRepository) ResetFailures(user *models.User) error {
+	if user.TOTPFailures == 0 {
+		return nil
+	}
+	user.TOTPFailures = 0
+	return r.db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("totp_failures", 0).Error
+}
+
+// UseRecoveryCode marks an unused recovery code of a user as used, false when there is none with the hash
+func (r *TwoFactorRepository) UseRecoveryCode(userId uint, hash string, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", userId, hash).
+		Update("used_at", usedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (r *TwoFactorRepository) CountRecoveryCodes(userId uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userId).Count(&count).Error
+	return count, err
+}
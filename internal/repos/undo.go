@@ -0,0 +1,124 @@
+package repos
+
+import (
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// changeSet remembers the todos touched by a repository, see TrackChanges
+type changeSet struct {
+	before map[uint]*models.Todo // nil for todos created while tracking
+	ids    []uint                // in the order they were first touched
+}
+
+// TrackChanges returns a repository that keeps the state of every todo before its
+// first change, so a whole series of changes can be put on the undo stack at once
+func (r *TodoRepository) TrackChanges() *TodoRepository {
+	repo := *r
+	repo.changes = &changeSet{before: make(map[uint]*models.Todo)}
+	return &repo
+}
+
+// Changes returns the todos touched since TrackChanges as they were before, and the
+// ids of all touched todos including the created ones
+func (r *TodoRepository) Changes() ([]models.Todo, []uint) {
+	if r.changes == nil {
+		return nil, nil
+	}
+	var before []models.Todo
+	for _, id := range r.changes.ids {
+		if todo := r.changes.before[id]; todo != nil {
+			before = append(before, *todo)
+		}
+	}
+	return before, r.changes.ids
+}
+
+// track keeps the current state of todos that have not been touched yet
+func (r *TodoRepository) track(ids []uint) error {
+	if r.changes == nil {
+		return nil
+	}
+	var missing []uint
+	for _, id := range uniqueUints(ids) {
+		if _, ok := r.changes.before[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	todos, err := r.Snapshots(missing)
+	if err != nil {
+		return err
+	}
+	for i := range todos {
+		r.changes.before[todos[i].ID] = &todos[i]
+		r.changes.ids = append(r.changes.ids, todos[i].ID)
+	}
+	return nil
+}
+
+func (r *TodoRepository) trackCreated(id uint) {
+	if r.changes == nil {
+		return
+	}
+	r.changes.before[id] = nil
+	r.changes.ids = append(r.changes.ids, id)
+}
+
+// Snapshots loads todos with their tags, including the ones in the trash
+func (r *TodoRepository) Snapshots(ids []uint) ([]models.Todo, error) {
+	var todos []models.Todo
+	if len(ids) == 0 {
+		return todos, nil
+	}
+	err := r.db.Unscoped().Preload("Tags").Where("id IN ?", ids).Order("id").Find(&todos).Error
+	return todos, err
+}
+
+// OwnedTagIDs returns the ids among the given ones of tags that still exist and belong to the user
+func (r *TodoRepository) OwnedTagIDs(userId uint, ids []uint) ([]uint, error) {
+	owned := []uint{}
+	if len(ids) == 0 {
+		return owned, nil
+	}
+	err := r.db.Model(&models.Tag{}).Where("user_id = ? AND id IN ?", userId, ids).Order("id").Pluck("id", &owned).Error
+	return owned, err
+}
+
+// PushUndo puts a change on the undo stack of its user. The redo stack is cleared
+// and only the newest limit entries are kept.
+func (r *TodoRepository) PushUndo(entry *models.UndoEntry, limit int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND undone = ?", entry.UserID, true).Delete(&models.UndoEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		newest := tx.Model(&models.UndoEntry{}).Select("id").Where("user_id = ?", entry.UserID).Order("id DESC").Limit(limit)
+		return tx.Where("user_id = ? AND id NOT IN (?)", entry.UserID, newest).Delete(&models.UndoEntry{}).Error
+	})
+}
+
+// LastUndo returns the newest change of a user that can be undone
+func (r *TodoRepository) LastUndo(userId uint) (models.UndoEntry, error) {
+	var entry models.UndoEntry
+	return entry, r.db.Where("user_id = ? AND undone = ?", userId, false).Order("id DESC").First(&entry).Error
+}
+
+// LastRedo returns the change of a user that was undone last
+func (r *TodoRepository) LastRedo(userId uint) (models.UndoEntry, error) {
+	var entry models.UndoEntry
+	return entry, r.db.Where("user_id = ? AND undone = ?", userId, true).Order("id").First(&entry).Error
+}
+
+// SetUndone moves an entry between the undo and the redo stack
+func (r *TodoRepository) SetUndone(entry *models.UndoEntry, undone bool) error {
+	entry.Undone = undone
+	return r.db.Model(entry).Update("undone", undone).Error
+}
+
+// DeleteUndo drops an entry that can no longer be applied
+func (r *TodoRepository) DeleteUndo(entry *models.UndoEntry) error {
+	return r.db.Delete(entry).Error
+}
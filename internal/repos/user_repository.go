@@ -1,6 +1,8 @@
 package repos
 
 import (
+	"strings"
+	"time"
 	"todo-list/internal/models"
 
 	"gorm.io/gorm"
@@ -14,11 +16,76 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db}
 }
 
-// Save a new user
+// Save a new user together with its inbox list
 func (r *UserRepository) CreateUser(user *models.User) error {
-	return r.db.Create(user).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		_, err := createInbox(tx, user.ID, nil)
+		return err
+	})
 }
 
 func (r *UserRepository) GetUser(username string, user *models.User) error {
 	return r.db.First(&user, "username = ?", username).Error
 }
+
+func (r *UserRepository) GetUserByID(id uint, user *models.User) error {
+	return r.db.First(&user, id).Error
+}
+
+// GetUserByEmail finds the user with the given lower case email, ErrRecordNotFound if there is none
+func (r *UserRepository) GetUserByEmail(email string, user *models.User) error {
+	return r.db.First(&user, "email = ?", email).Error
+}
+
+// SearchUsers returns the users whose username contains query in id order, starting after the id after
+func (r *UserRepository) SearchUsers(query string, after uint, limit int) ([]models.User, error) {
+	var users []models.User
+	q := r.db.Where("id > ?", after)
+	if query != "" {
+		q = q.Where("LOWER(username) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(query))+"%")
+	}
+	err := q.Order("id").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// SetRole changes the role of the user of the given name, ErrRecordNotFound if there is none
+func (r *UserRepository) SetRole(username, role string) error {
+	result := r.db.Model(&models.User{}).Where("username = ?", username).Update("role", role)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// SetDisabledAt disables a user at the given time, nil enables them again
+func (r *UserRepository) SetDisabledAt(user *models.User, disabledAt *time.Time) error {
+	user.DisabledAt = disabledAt
+	return r.db.Model(user).Update("disabled_at", disabledAt).Error
+}
+
+// UpdatePassword replaces the password hash of a user and revokes their personal access
+// tokens, which must not outlive the password they were created with
+func (r *UserRepository) UpdatePassword(user *models.User, hash string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password", hash).Error; err != nil {
+			return err
+		}
+		user.Password = hash
+		return tx.Where("user_id = ?", user.ID).Delete(&models.APIToken{}).Error
+	})
+}
+
+// SetEmail changes the email of a user, the new address is not verified yet
+func (r *UserRepository) SetEmail(user *models.User, email *string) error {
+	user.Email, user.EmailVerifiedAt = email, nil
+	return r.db.Model(user).Updates(map[string]interface{}{"email": email, "email_verified_at": nil}).Error
+}
+
+// SetEmailVerifiedAt marks the email of a user as verified
+func (r *UserRepository) SetEmailVerifiedAt(user *models.User, verifiedAt time.Time) error {
+	user.EmailVerifiedAt = &verifiedAt
+	return r.db.Model(user).Update("email_verified_at", verifiedAt).Error
+}
@@ -0,0 +1,195 @@
+package repos
+
+import (
+	"todo-list/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type WorkflowRepository struct {
+	db *gorm.DB
+}
+
+// Constructor for WorkflowRepository
+func NewWorkflowRepository(db *gorm.DB) *WorkflowRepository {
+	return &WorkflowRepository{db}
+}
+
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *WorkflowRepository) Transaction(fn func(repo *WorkflowRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&WorkflowRepository{tx})
+	})
+}
+
+// Workflows returns a workflow repository using the same database connection or transaction
+func (r *TodoRepository) Workflows() *WorkflowRepository {
+	return NewWorkflowRepository(r.db)
+}
+
+// GetStatuses returns the statuses of the default workflow of a user, or of a list
+// when listId is given, in board order with their transitions
+func (r *WorkflowRepository) GetStatuses(userId uint, listId *uint) ([]models.WorkflowStatus, error) {
+	var statuses []models.WorkflowStatus
+	query := r.db.Where("user_id = ?", userId)
+	if listId == nil {
+		query = query.Where("list_id IS NULL")
+	} else {
+		query = query.Where("list_id = ?", *listId)
+	}
+	if err := query.Order("sort_order, id").Find(&statuses).Error; err != nil || len(statuses) == 0 {
+		return statuses, err
+	}
+
+	ids := make([]uint, len(statuses))
+	names := make(map[uint]string, len(statuses))
+	for i, status := range statuses {
+		ids[i] = status.ID
+		names[status.ID] = status.Name
+	}
+	var transitions []models.WorkflowTransition
+	if err := r.db.Where("from_id IN ?", ids).Order("from_id, to_id").Find(&transitions).Error; err != nil {
+		return nil, err
+	}
+	next := make(map[uint][]string)
+	for _, transition := range transitions {
+		next[transition.FromID] = append(next[transition.FromID], names[transition.ToID])
+	}
+	for i := range statuses {
+		statuses[i].Transitions = next[statuses[i].ID]
+		if statuses[i].Transitions == nil {
+			statuses[i].Transitions = []string{}
+		}
+	}
+	return statuses, nil
+}
+
+// get a status
+func (r *WorkflowRepository) GetStatus(id uint) (models.WorkflowStatus, error) {
+	var status models.WorkflowStatus
+	return status, r.db.First(&status, id).Error
+}
+
+// SaveStatuses replaces a workflow. Statuses with an id are updated, the others created,
+// and statuses of the workflow missing from the list are deleted. Transitions are
+// resolved by name once every status has an id.
+func (r *WorkflowRepository) SaveStatuses(userId uint, listId *uint, statuses []models.WorkflowStatus) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		repo := &WorkflowRepository{tx}
+		existing, err := repo.GetStatuses(userId, listId)
+		if err != nil {
+			return err
+		}
+
+		keep := make(map[uint]bool)
+		ids := make(map[string]uint)
+		for i := range statuses {
+			statuses[i].UserID, statuses[i].ListID, statuses[i].SortOrder = userId, listId, i
+			var err error
+			if statuses[i].ID == 0 {
+				err = tx.Create(&statuses[i]).Error
+			} else {
+				err = tx.Model(&statuses[i]).Select("name", "sort_order", "is_terminal").Updates(&statuses[i]).Error
+			}
+			if err != nil {
+				return err
+			}
+			keep[statuses[i].ID] = true
+			ids[statuses[i].Name] = statuses[i].ID
+		}
+
+		var removed []uint
+		for _, status := range existing {
+			if !keep[status.ID] {
+				removed = append(removed, status.ID)
+			}
+		}
+		all := append(removed, mapValues(ids)...)
+		if err := tx.Where("from_id IN ? OR to_id IN ?", all, all).Delete(&models.WorkflowTransition{}).Error; err != nil {
+			return err
+		}
+		if len(removed) > 0 {
+			if err := tx.Delete(&models.WorkflowStatus{}, removed).Error; err != nil {
+				return err
+			}
+		}
+
+		var transitions []models.WorkflowTransition
+		for _, status := range statuses {
+			for _, name := range uniqueStrings(status.Transitions) {
+				transitions = append(transitions, models.WorkflowTransition{FromID: status.ID, ToID: ids[name]})
+			}
+		}
+		if len(transitions) == 0 {
+			return nil
+		}
+		return tx.Create(&transitions).Error
+	})
+}
+
+// CountTodosWithStatus counts the todos not in the trash that use one of the statuses
+func (r *WorkflowRepository) CountTodosWithStatus(statusIds []uint) (int64, error) {
+	var count int64
+	if len(statusIds) == 0 {
+		return 0, nil
+	}
+	err := r.db.Model(&models.Todo{}).Where("status_id IN ?", statusIds).Count(&count).Error
+	return count, err
+}
+
+// ClearTrashedStatuses unsets the statuses of trashed todos, their status is
+// derived again from is_completed
+func (r *WorkflowRepository) ClearTrashedStatuses(statusIds []uint) error {
+	if len(statusIds) == 0 {
+		return nil
+	}
+	return r.db.Unscoped().Model(&models.Todo{}).Where("status_id IN ?", statusIds).UpdateColumn("status_id", nil).Error
+}
+
+// SetStatuses moves todos to other statuses without recording history, used when
+// a workflow changes under them
+func (r *WorkflowRepository) SetStatuses(statuses map[uint]uint) error {
+	for todoId, statusId := range statuses {
+		if err := r.db.Model(&models.Todo{}).Where("id = ?", todoId).UpdateColumn("status_id", statusId).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListsWithWorkflow returns the ids of the lists of a user that have their own workflow
+func (r *WorkflowRepository) ListsWithWorkflow(userId uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.WorkflowStatus{}).Where("user_id = ? AND list_id IS NOT NULL", userId).Distinct().Pluck("list_id", &ids).Error
+	return ids, err
+}
+
+// BoardTodos returns the todos of a board in position order, the todos of one list or,
+// without listId, of every personal list of the user that uses the default workflow
+func (r *WorkflowRepository) BoardTodos(userId uint, listId *uint) ([]models.Todo, error) {
+	query := r.db.Preload("Tags")
+	if listId != nil {
+		// the todos of a list of an organization are created by all of its members
+		query = query.Where("list_id = ?", *listId)
+	} else {
+		query = PersonalScope(userId).where(query, "todos")
+		own, err := r.ListsWithWorkflow(userId)
+		if err != nil {
+			return nil, err
+		}
+		if len(own) > 0 {
+			query = query.Where("list_id IS NULL OR list_id NOT IN ?", own)
+		}
+	}
+	var todos []models.Todo
+	err := query.Order("position, id").Find(&todos).Error
+	return todos, err
+}
+
+func mapValues(m map[string]uint) []uint {
+	values := make([]uint, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
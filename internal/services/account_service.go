@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	mailer "todo-list/pkg/mail"
+	"todo-list/pkg/signedtoken"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Purposes and lifetimes of the tokens mailed to users
+const (
+	emailVerificationPurpose = "email-verification"
+	emailVerificationTTL     = 48 * time.Hour
+	passwordResetPurpose     = "password-reset"
+	passwordResetTTL         = time.Hour
+)
+
+var (
+	// ErrInvalidEmail is returned for addresses that cannot receive mail
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrEmailTaken is returned when another user has the address already
+	ErrEmailTaken = errors.New("email address is taken")
+	// ErrNoEmail is returned when mailing a user without an address
+	ErrNoEmail = errors.New("user has no email address")
+	// ErrEmailVerified is returned when asking to verify an address that is verified already
+	ErrEmailVerified = errors.New("email address is verified already")
+	// ErrInvalidAccountToken is returned for mailed tokens that are wrong, expired or used
+	ErrInvalidAccountToken = errors.New("invalid or expired token")
+)
+
+// AccountService verifies email addresses and resets forgotten passwords by mailing
+// signed tokens. The tokens are bound to the address and the password hash of the
+// user, so they stop working once they were used.
+type AccountService struct {
+	userRepo *repos.UserRepository
+	mailer   mailer.Mailer
+	tokens   *signedtoken.Signer
+}
+
+// the constructor for AccountService
+
+func NewAccountService(userRepo *repos.UserRepository, mailer mailer.Mailer, tokens *signedtoken.Signer) *AccountService {
+	return &AccountService{userRepo, mailer, tokens}
+}
+
+// NormalizeEmail checks an address and returns it in lower case
+func NormalizeEmail(email string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Name != "" || address.Address != strings.TrimSpace(email) {
+		return "", ErrInvalidEmail
+	}
+	return strings.ToLower(address.Address), nil
+}
+
+// CheckEmailAvailable returns ErrEmailTaken when a user other than userId has the address
+func (s *AccountService) CheckEmailAvailable(email string, userId uint) error {
+	var existing models.User
+	err := s.userRepo.GetUserByEmail(email, &existing)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != userId {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+// SetEmail changes the address of a user and mails a verification token to it.
+// Setting the current address again keeps it verified.
+func (s *AccountService) SetEmail(ctx context.Context, user *models.User, email string) error {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	if user.Email != nil && *user.Email == email {
+		return nil
+	}
+	if err := s.CheckEmailAvailable(email, user.ID); err != nil {
+		return err
+	}
+	if err := s.userRepo.SetEmail(user, &email); err != nil {
+		return err
+	}
+	return s.SendVerification(ctx, user)
+}
+
+// SendVerification mails a token to confirm the address of a user
+func (s *AccountService) SendVerification(ctx context.Context, user *models.User) error {
+	if user.Email == nil {
+		return ErrNoEmail
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailVerified
+	}
+	token := s.tokens.Sign(emailVerificationPurpose, user.ID, time.Now().Add(emailVerificationTTL), *user.Email)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      *user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nconfirm this email address by sending the token below with POST /email/verify "+
+			"within %d hours:\n\n%s\n\nIf you did not add this address, ignore this email.\n",
+			user.Username, int(emailVerificationTTL.Hours()), token),
+	})
+}
+
+// VerifyEmail marks the address a verification token was mailed to as verified
+func (s *AccountService) VerifyEmail(token string) (models.User, error) {
+	user, err := s.tokenUser(token, emailVerificationPurpose)
+	if err != nil {
+		return user, err
+	}
+	if user.Email == nil || user.EmailVerifiedAt != nil {
+		return user, ErrInvalidAccountToken
+	}
+	if err := s.tokens.Verify(token, emailVerificationPurpose, *user.Email, time.Now()); err != nil {
+		return user, ErrInvalidAccountToken
+	}
+	return user, s.userRepo.SetEmailVerifiedAt(&user, time.Now())
+}
+
+// ForgotPassword mails a password reset token to a verified address. Nothing tells the
+// caller whether there is such a user, so the endpoint cannot be used to find addresses.
+func (s *AccountService) ForgotPassword(ctx context.Context, email string) error {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil
+	}
+	var user models.User
+	err = s.userRepo.GetUserByEmail(email, &user)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil || user.DisabledAt != nil {
+		return nil
+	}
+
+	token := s.tokens.Sign(passwordResetPurpose, user.ID, time.Now().Add(passwordResetTTL), user.Password)
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nreset your password by sending the token below with POST /password/reset "+
+			"within %d minutes:\n\n%s\n\nIf you did not ask for this, ignore this email. Your password stays the same.\n",
+			user.Username, int(passwordResetTTL.Minutes()), token),
+	})
+	if err != nil {
+		log.Printf("failed to mail the password reset of user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with a mailed reset token and revokes the personal
+// access tokens of the user, the caller ends their sessions
+func (s *AccountService) ResetPassword(token, password string) (models.User, error) {
+	if password == "" {
+		return models.User{}, ErrEmptyPassword
+	}
+	user, err := s.tokenUser(token, passwordResetPurpose)
+	if err != nil {
+		return user, err
+	}
+	if user.DisabledAt != nil {
+		return user, ErrInvalidAccountToken
+	}
+	if err := s.tokens.Verify(token, passwordResetPurpose, user.Password, time.Now()); err != nil {
+		return user, ErrInvalidAccountToken
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return user, err
+	}
+	return user, s.userRepo.UpdatePassword(&user, string(hash))
+}
+
+// tokenUser loads the user a token was issued for, its signature is checked by the caller
+func (s *AccountService) tokenUser(token, purpose string) (models.User, error) {
+	var user models.User
+	userId, err := s.tokens.Subject(token, purpose)
+	if err != nil {
+		return user, ErrInvalidAccountToken
+	}
+	err = s.userRepo.GetUserByID(userId, &user)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, ErrInvalidAccountToken
+	}
+	return user, err
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrDisableSelf is returned when admins try to disable their own account
+	ErrDisableSelf = errors.New("admins cannot disable themselves")
+	// ErrEmptyPassword is returned when resetting a password to an empty one
+	ErrEmptyPassword = errors.New("password is required")
+)
+
+type AdminService struct {
+	userRepo *repos.UserRepository
+}
+
+// the constructor for AdminService
+
+func NewAdminService(userRepo *repos.UserRepository) *AdminService {
+	return &AdminService{userRepo}
+}
+
+// GetUsers returns one page of the users whose username contains query, in id order after the id after
+func (s *AdminService) GetUsers(query string, after uint, limit int) ([]models.User, error) {
+	return s.userRepo.SearchUsers(strings.TrimSpace(query), after, limit)
+}
+
+func (s *AdminService) GetUser(id uint) (models.User, error) {
+	var user models.User
+	return user, s.userRepo.GetUserByID(id, &user)
+}
+
+// DisableUser keeps a user from logging in, the caller ends their sessions
+func (s *AdminService) DisableUser(actorID uint, user *models.User) error {
+	if user.ID == actorID {
+		return ErrDisableSelf
+	}
+	if user.DisabledAt != nil {
+		return nil
+	}
+	now := time.Now()
+	return s.userRepo.SetDisabledAt(user, &now)
+}
+
+// EnableUser lets a disabled user log in again
+func (s *AdminService) EnableUser(user *models.User) error {
+	return s.userRepo.SetDisabledAt(user, nil)
+}
+
+// ResetPassword sets a new password for a user and revokes their personal access tokens, the caller ends their sessions
+func (s *AdminService) ResetPassword(user *models.User, password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.UpdatePassword(user, string(hash))
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+)
+
+// HistoryAssigned is the undo action of AssignTodo
+const HistoryAssigned = "assigned"
+
+var (
+	// ErrAssigneeNoAccess is returned when assigning a todo to a user who cannot see it
+	ErrAssigneeNoAccess = errors.New("assignee has no access to the todo")
+	// ErrReassign is returned when the acting user may not change the assignee of a todo
+	ErrReassign = errors.New("not allowed to reassign the todo")
+)
+
+// AssignTodo sets the user responsible for a todo, nil unassigns it. The caller checks who may do so.
+func (s *TodoService) AssignTodo(actorID uint, todo *models.Todo, assigneeID *uint) error {
+	return s.undoable(actorID, func(tx *TodoService) (string, error) {
+		_, err := tx.editTodo(todo, EditOptions{assign: true, assignee: assigneeID})
+		return HistoryAssigned, err
+	})
+}
+
+type AssignmentService struct {
+	todoService   *TodoService
+	shares        *ShareService
+	userRepo      *repos.UserRepository
+	notifications *NotificationService
+}
+
+// the constructor for AssignmentService
+
+func NewAssignmentService(todoService *TodoService, shares *ShareService, userRepo *repos.UserRepository, notifications *NotificationService) *AssignmentService {
+	return &AssignmentService{todoService, shares, userRepo, notifications}
+}
+
+// Assign gives a todo to the user of the given name, an empty name unassigns it.
+//   - the owner may assign the todo to anyone who can see it
+//   - the current assignee may hand it on or give it back
+//   - editors may assign todos nobody is assigned to, e.g. to take them
+//
+// The new and the previous assignee are notified.
+func (s *AssignmentService) Assign(actorID uint, todo *models.Todo, username string) error {
+	var assignee *models.User
+	if username != "" {
+		user, err := s.shares.FindUser(username)
+		if err != nil {
+			return err
+		}
+		permission, err := s.shares.TodoPermission(user.ID, todo)
+		if err != nil {
+			return err
+		}
+		if permission < PermissionView {
+			return ErrAssigneeNoAccess
+		}
+		assignee = &user
+	}
+
+	permission, err := s.shares.TodoPermission(actorID, todo)
+	if err != nil {
+		return err
+	}
+	previous := todo.AssigneeID
+	switch {
+	case permission == PermissionOwner:
+	case previous != nil && *previous == actorID:
+	case previous == nil && permission >= PermissionEdit:
+	default:
+		return ErrReassign
+	}
+
+	var assigneeID *uint
+	if assignee != nil {
+		assigneeID = &assignee.ID
+	}
+	if sameID(previous, assigneeID) {
+		return nil
+	}
+	if err := s.todoService.AssignTodo(actorID, todo, assigneeID); err != nil {
+		return err
+	}
+
+	// the todo is assigned either way, a failed notification is only logged
+	if err := s.notify(actorID, todo, previous, assignee); err != nil {
+		log.Printf("failed to notify about the assignment of todo %d: %v", todo.ID, err)
+	}
+	return nil
+}
+
+// GetAssigned returns the todos of a scope assigned to its user, accepting the same filters as GetTodoList
+func (s *AssignmentService) GetAssigned(scope repos.Scope, filter repos.TodoFilter) (repos.TodoPage, error) {
+	filter.AssigneeID = &scope.UserID
+	return s.todoService.GetTodoList(scope, filter)
+}
+
+func (s *AssignmentService) notify(actorID uint, todo *models.Todo, previous *uint, assignee *models.User) error {
+	var actor models.User
+	if err := s.userRepo.GetUserByID(actorID, &actor); err != nil {
+		return err
+	}
+	var notifications []models.Notification
+	if assignee != nil {
+		notifications = append(notifications, models.Notification{
+			UserID: assignee.ID, ActorID: actorID, TodoID: todo.ID, Type: models.NotificationAssigned,
+			Message: fmt.Sprintf("%s assigned %q to you", actor.Username, todo.Title),
+		})
+	}
+	if previous != nil {
+		message := fmt.Sprintf("%s unassigned you from %q", actor.Username, todo.Title)
+		if assignee != nil {
+			message = fmt.Sprintf("%s reassigned %q to %s", actor.Username, todo.Title, assignee.Username)
+		}
+		notifications = append(notifications, models.Notification{
+			UserID: *previous, ActorID: actorID, TodoID: todo.ID, Type: models.NotificationUnassigned, Message: message,
+		})
+	}
+	return s.notifications.Notify(notifications...)
+}
+
+// sameID reports whether two optional ids are both unset or equal
+func sameID(a, b *uint) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
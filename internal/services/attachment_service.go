@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/pkg/storage"
+)
+
+// DefaultMaxAttachmentSize is used when MAX_ATTACHMENT_SIZE is not configured
+const DefaultMaxAttachmentSize = 10 << 20
+
+// AttachmentTypes are the content types accepted for attachments, detected from the file content
+var AttachmentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf", "text/plain"}
+
+var (
+	// ErrAttachmentTooLarge is returned for uploads above the configured maximum size
+	ErrAttachmentTooLarge = errors.New("attachment too large")
+	// ErrAttachmentType is returned for uploads whose content is not one of AttachmentTypes
+	ErrAttachmentType = errors.New("attachment type not allowed")
+)
+
+type AttachmentService struct {
+	repo    *repos.AttachmentRepository
+	store   storage.BlobStore
+	maxSize int64
+}
+
+// the constructor for AttachmentService
+
+func NewAttachmentService(repo *repos.AttachmentRepository, store storage.BlobStore, maxSize int64) *AttachmentService {
+	return &AttachmentService{repo, store, maxSize}
+}
+
+// MaxSize is the largest attachment accepted in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *AttachmentService) GetAttachments(todoId uint) ([]models.Attachment, error) {
+	return s.repo.GetAttachments(todoId)
+}
+
+func (s *AttachmentService) GetAttachment(todoId uint, id string) (models.Attachment, error) {
+	return s.repo.GetAttachment(todoId, id)
+}
+
+// AddAttachment stores the content of an upload and saves the attachment. The content
+// type is detected from the content, the one sent by the client is not trusted.
+func (s *AttachmentService) AddAttachment(ctx context.Context, attachment *models.Attachment, content io.Reader) error {
+	var data bytes.Buffer
+	if _, err := io.Copy(&data, io.LimitReader(content, s.maxSize+1)); err != nil {
+		return err
+	}
+	if int64(data.Len()) > s.maxSize {
+		return ErrAttachmentTooLarge
+	}
+	contentType, err := attachmentType(data.Bytes())
+	if err != nil {
+		return err
+	}
+
+	key, err := storageKey(attachment.TodoID)
+	if err != nil {
+		return err
+	}
+	attachment.ContentType = contentType
+	attachment.Size = int64(data.Len())
+	attachment.StorageKey = key
+	if err := s.store.Put(ctx, key, &data, attachment.Size, contentType); err != nil {
+		return err
+	}
+	if err := s.repo.CreateAttachment(attachment); err != nil {
+		// the row was never written, so nothing refers to the blob
+		s.store.Delete(ctx, key)
+		return err
+	}
+	return nil
+}
+
+// OpenAttachment returns the content of an attachment, the caller closes it
+func (s *AttachmentService) OpenAttachment(ctx context.Context, attachment *models.Attachment) (io.ReadCloser, error) {
+	return s.store.Get(ctx, attachment.StorageKey)
+}
+
+// RemoveAttachment deletes the content first, so a failure leaves the attachment in place
+func (s *AttachmentService) RemoveAttachment(ctx context.Context, attachment *models.Attachment) error {
+	if err := s.store.Delete(ctx, attachment.StorageKey); err != nil {
+		return err
+	}
+	return s.repo.DeleteAttachment(attachment)
+}
+
+// CleanupOrphans deletes the attachments of purged todos and returns how many were deleted
+func (s *AttachmentService) CleanupOrphans(ctx context.Context) (int, error) {
+	attachments, err := s.repo.OrphanedAttachments(100)
+	if err != nil {
+		return 0, err
+	}
+	for i := range attachments {
+		if err := s.RemoveAttachment(ctx, &attachments[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(attachments), nil
+}
+
+// RunAttachmentCleanup removes the attachments of purged todos every interval until ctx is done
+func (s *AttachmentService) RunAttachmentCleanup(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		removed, err := s.CleanupOrphans(ctx)
+		if err != nil {
+			log.Printf("failed to clean up attachments: %v", err)
+		} else if removed > 0 {
+			log.Printf("removed %d attachments of purged todos", removed)
+		}
+	})
+}
+
+// attachmentType detects the content type of an upload and checks that it is allowed
+func attachmentType(data []byte) (string, error) {
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "", ErrAttachmentType
+	}
+	for _, allowed := range AttachmentTypes {
+		if detected == allowed {
+			return detected, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrAttachmentType, detected)
+}
+
+// storageKey returns a new random blob key below the todo
+func storageKey(todoId uint) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("todos/%d/%s", todoId, hex.EncodeToString(random)), nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+// MaxBulkItems limits the number of todos a single bulk request may touch
+const MaxBulkItems = 1000
+
+// HistoryBulk is the undo action of a bulk request
+const HistoryBulk = "bulk"
+
+// Operations of a bulk request
+const (
+	BulkComplete   = "complete"
+	BulkUncomplete = "uncomplete"
+	BulkDelete     = "delete"
+	BulkMove       = "move"
+	BulkTag        = "tag"
+	BulkUpdate     = "update"
+)
+
+// errBulkAborted rolls back an all-or-nothing bulk request after a failed item
+var errBulkAborted = errors.New("bulk request aborted")
+
+// BulkOperation applies one operation to a list of todos
+type BulkOperation struct {
+	Op           string          `json:"op"`
+	IDs          []uint          `json:"ids"`
+	ListID       *uint           `json:"list_id,omitempty"`        // move
+	AddTagIDs    []uint          `json:"add_tag_ids,omitempty"`    // tag
+	RemoveTagIDs []uint          `json:"remove_tag_ids,omitempty"` // tag
+	Fields       json.RawMessage `json:"fields,omitempty"`         // update, the same fields as PUT /todos/{id}
+}
+
+// BulkResult is the outcome of an operation on a single todo
+type BulkResult struct {
+	Op  int // index of the operation in the request
+	ID  uint
+	Err error
+}
+
+// Bulk applies the operations in order in a single transaction and one undo entry.
+// When atomic is set the first failing todo rolls everything back, otherwise every
+// todo is applied in its own savepoint and failures only skip that todo. The
+// returned bool reports whether the changes were committed.
+func (s *TodoService) Bulk(actorID uint, ops []BulkOperation, atomic bool) ([]BulkResult, bool, error) {
+	var results []BulkResult
+	err := s.undoable(actorID, func(tx *TodoService) (string, error) {
+		results = nil
+		for i, op := range ops {
+			for _, id := range op.IDs {
+				var err error
+				if atomic {
+					err = tx.applyBulk(op, id)
+				} else {
+					err = tx.repo.Transaction(func(repo *repos.TodoRepository) error {
+						return tx.withRepo(repo).applyBulk(op, id)
+					})
+				}
+				results = append(results, BulkResult{Op: i, ID: id, Err: err})
+				if err != nil && atomic {
+					return "", errBulkAborted
+				}
+			}
+		}
+		return HistoryBulk, nil
+	})
+	if errors.Is(err, errBulkAborted) {
+		return results, false, nil
+	}
+	return results, err == nil, err
+}
+
+func (s *TodoService) applyBulk(op BulkOperation, id uint) error {
+	todo, err := s.repo.GetTodo(idString(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) && op.Op == BulkDelete {
+		// already trashed together with a parent earlier in the request
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case BulkDelete:
+		return s.removeTodo(&todo)
+	case BulkComplete, BulkUncomplete:
+		todo.IsCompleted = op.Op == BulkComplete
+	case BulkMove:
+		todo.ListID = op.ListID
+	case BulkTag:
+		remove := make(map[uint]bool, len(op.RemoveTagIDs))
+		for _, tagId := range op.RemoveTagIDs {
+			remove[tagId] = true
+		}
+		todo.TagIDs = []uint{}
+		for _, tag := range todo.Tags {
+			if !remove[tag.ID] {
+				todo.TagIDs = append(todo.TagIDs, tag.ID)
+			}
+		}
+		for _, tagId := range op.AddTagIDs {
+			if !remove[tagId] {
+				todo.TagIDs = append(todo.TagIDs, tagId)
+			}
+		}
+		todo.TagIDs = uniqueIDs(todo.TagIDs)
+	case BulkUpdate:
+		userId := todo.UserID
+		if err := json.Unmarshal(op.Fields, &todo); err != nil {
+			return err
+		}
+		todo.ID, todo.UserID = id, userId
+	}
+	_, err = s.editTodo(&todo, EditOptions{})
+	return err
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/pkg/markdown"
+)
+
+type CommentService struct {
+	repo *repos.CommentRepository
+}
+
+// the constructor for CommentService
+
+func NewCommentService(repo *repos.CommentRepository) *CommentService {
+	return &CommentService{repo}
+}
+
+// GetComments returns the comments of a todo with their rendered bodies, oldest first
+func (s *CommentService) GetComments(todoId uint) ([]models.Comment, error) {
+	comments, err := s.repo.GetComments(todoId)
+	for i := range comments {
+		render(&comments[i])
+	}
+	return comments, err
+}
+
+func (s *CommentService) GetComment(todoId uint, id string) (models.Comment, error) {
+	comment, err := s.repo.GetComment(todoId, id)
+	render(&comment)
+	return comment, err
+}
+
+func (s *CommentService) AddComment(comment *models.Comment) error {
+	comment.EditedAt = nil
+	if err := s.repo.CreateComment(comment); err != nil {
+		return err
+	}
+	render(comment)
+	return nil
+}
+
+// EditComment replaces the body of a comment and marks it as edited
+func (s *CommentService) EditComment(comment *models.Comment, body string) error {
+	if body == comment.Body {
+		return nil
+	}
+	now := time.Now()
+	comment.Body = body
+	comment.EditedAt = &now
+	if err := s.repo.UpdateComment(comment); err != nil {
+		return err
+	}
+	render(comment)
+	return nil
+}
+
+func (s *CommentService) RemoveComment(comment *models.Comment) error {
+	return s.repo.DeleteComment(comment)
+}
+
+func render(comment *models.Comment) {
+	comment.BodyHTML = markdown.ToHTML(comment.Body)
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"container/heap"
+	"errors"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidDependency is returned when a todo should be blocked by itself or by a todo of another user
+	ErrInvalidDependency = errors.New("invalid dependency")
+	// ErrDependencyCycle is returned when a dependency would make a todo wait on itself
+	ErrDependencyCycle = errors.New("dependency cycle")
+	// ErrBlocked is returned when completing a todo whose blockers are still open
+	ErrBlocked = errors.New("todo is blocked")
+)
+
+// DependencyGraph is a todo with every todo it waits on or that waits on it, at any distance
+type DependencyGraph struct {
+	TodoID uint                    `json:"todo_id"`
+	Todos  []models.Todo           `json:"todos"`
+	Edges  []models.TodoDependency `json:"edges"`
+}
+
+// NextTodo is an open todo in the order it can be done, BlockedBy lists its open blockers
+type NextTodo struct {
+	Todo      models.Todo `json:"todo"`
+	BlockedBy []uint      `json:"blocked_by"`
+}
+
+// AddDependency marks todo as blocked by the todo blockedByID of the same owner,
+// rejecting dependencies that would form a cycle
+func (s *TodoService) AddDependency(actorID uint, todo *models.Todo, blockedByID uint) error {
+	blocker, err := s.repo.GetTodo(idString(blockedByID))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && (!sameOwner(&blocker, todo) || blocker.ID == todo.ID)) {
+		return ErrInvalidDependency
+	}
+	if err != nil {
+		return err
+	}
+
+	dependencies, err := s.repo.GetDependencies(todoScope(todo))
+	if err != nil {
+		return err
+	}
+	blockers := make(map[uint][]uint)
+	for _, dependency := range dependencies {
+		if dependency.TodoID == todo.ID && dependency.BlockedByID == blockedByID {
+			return nil
+		}
+		blockers[dependency.TodoID] = append(blockers[dependency.TodoID], dependency.BlockedByID)
+	}
+	// the new edge closes a cycle when the blocker already waits on the todo
+	if reachable(blockers, blockedByID)[todo.ID] {
+		return ErrDependencyCycle
+	}
+	return s.repo.WithActor(actorID).AddDependency(todo.ID, blockedByID)
+}
+
+// RemoveDependency unblocks a todo, gorm.ErrRecordNotFound when it was not blocked by blockedByID
+func (s *TodoService) RemoveDependency(actorID uint, todo *models.Todo, blockedByID uint) error {
+	return s.repo.WithActor(actorID).RemoveDependency(todo.ID, blockedByID)
+}
+
+// GetDependencyGraph returns the todos connected to a todo through dependencies.
+// Todos in the trash are left out.
+func (s *TodoService) GetDependencyGraph(todo *models.Todo) (DependencyGraph, error) {
+	graph := DependencyGraph{TodoID: todo.ID}
+	dependencies, err := s.repo.GetDependencies(todoScope(todo))
+	if err != nil {
+		return graph, err
+	}
+	neighbours := make(map[uint][]uint)
+	for _, dependency := range dependencies {
+		neighbours[dependency.TodoID] = append(neighbours[dependency.TodoID], dependency.BlockedByID)
+		neighbours[dependency.BlockedByID] = append(neighbours[dependency.BlockedByID], dependency.TodoID)
+	}
+	connected := reachable(neighbours, todo.ID)
+	ids := []uint{todo.ID}
+	for id := range connected {
+		ids = append(ids, id)
+	}
+	if graph.Todos, err = s.repo.GetTodos(ids); err != nil {
+		return graph, err
+	}
+
+	live := make(map[uint]bool, len(graph.Todos))
+	for _, t := range graph.Todos {
+		live[t.ID] = true
+	}
+	graph.Edges = []models.TodoDependency{}
+	for _, dependency := range dependencies {
+		if live[dependency.TodoID] && live[dependency.BlockedByID] {
+			graph.Edges = append(graph.Edges, dependency)
+		}
+	}
+	return graph, nil
+}
+
+// NextTodos returns the open todos of a scope in an order that respects their
+// dependencies, the most urgent todo that can be done first. Todos without open
+// blockers can be started right away.
+func (s *TodoService) NextTodos(scope repos.Scope) ([]NextTodo, error) {
+	todos, err := s.repo.OpenTodos(scope)
+	if err != nil {
+		return nil, err
+	}
+	dependencies, err := s.repo.GetDependencies(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[uint]int, len(todos))
+	for i, todo := range todos {
+		index[todo.ID] = i
+	}
+	blockedBy := make([][]uint, len(todos))
+	dependents := make([][]int, len(todos))
+	waiting := make([]int, len(todos))
+	for _, dependency := range dependencies {
+		todo, open := index[dependency.TodoID]
+		blocker, blocking := index[dependency.BlockedByID]
+		if !open || !blocking {
+			continue
+		}
+		blockedBy[todo] = append(blockedBy[todo], dependency.BlockedByID)
+		dependents[blocker] = append(dependents[blocker], todo)
+		waiting[todo]++
+	}
+
+	// Kahn's algorithm, todos keep the order of OpenTodos among those that are ready
+	ready := &indexHeap{}
+	for i := range todos {
+		if waiting[i] == 0 {
+			heap.Push(ready, i)
+		}
+	}
+	next := make([]NextTodo, 0, len(todos))
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(int)
+		if blockedBy[i] == nil {
+			blockedBy[i] = []uint{}
+		}
+		next = append(next, NextTodo{Todo: todos[i], BlockedBy: blockedBy[i]})
+		for _, dependent := range dependents[i] {
+			if waiting[dependent]--; waiting[dependent] == 0 {
+				heap.Push(ready, dependent)
+			}
+		}
+	}
+	return next, nil
+}
+
+// checkBlockers makes sure a todo about to be completed does not wait on open todos
+func (s *TodoService) checkBlockers(todo *models.Todo) error {
+	blockers, err := s.repo.OpenBlockers(todo.ID)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// reachable returns the ids reachable from start by following edges, start excluded
+func reachable(edges map[uint][]uint, start uint) map[uint]bool {
+	seen := map[uint]bool{}
+	stack := []uint{start}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, next := range edges[id] {
+			if !seen[next] {
+				seen[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+	delete(seen, start)
+	return seen
+}
+
+// indexHeap is a min-heap of indexes into a slice
+type indexHeap []int
+
+func (h indexHeap) Len() int            { return len(h) }
+func (h indexHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h indexHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *indexHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *indexHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/pkg/jwt"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired and revoked refresh tokens
+	// and the refresh tokens of disabled users
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReuse is returned when a refresh token is used a second time,
+	// every token of its login is revoked then
+	ErrRefreshTokenReuse = errors.New("refresh token was used before")
+)
+
+// TokenPair is what the jwt auth mode returns on login and refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // Lifetime of the access token in seconds
+	RefreshToken string `json:"refresh_token"`
+}
+
+// JWTService issues the short-lived access tokens and rotating refresh tokens of the jwt
+// auth mode. Access tokens are verified by their signature alone, refresh tokens are
+// stored hashed like personal access tokens.
+type JWTService struct {
+	repo       *repos.RefreshTokenRepository
+	userRepo   *repos.UserRepository
+	keys       *jwt.KeySet
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// the constructor for JWTService
+
+func NewJWTService(repo *repos.RefreshTokenRepository, userRepo *repos.UserRepository, keys *jwt.KeySet, accessTTL, refreshTTL time.Duration) *JWTService {
+	return &JWTService{repo, userRepo, keys, accessTTL, refreshTTL}
+}
+
+// Login starts a new refresh token family for a user whose password was checked
+func (s *JWTService) Login(user *models.User) (TokenPair, error) {
+	family, err := randomHex(16)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return s.issue(user.ID, family, time.Now())
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens. A refresh token works once,
+// presenting it again revokes its family and returns ErrRefreshTokenReuse.
+func (s *JWTService) Refresh(raw string) (TokenPair, error) {
+	now := time.Now()
+	token, err := s.repo.GetRefreshToken(hashToken(raw))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if token.RevokedAt != nil || !token.ExpiresAt.After(now) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	claimed, err := s.repo.MarkUsed(&token, now)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !claimed {
+		if err := s.repo.RevokeFamily(token.Family, now); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrRefreshTokenReuse
+	}
+
+	var user models.User
+	if err := s.userRepo.GetUserByID(token.UserID, &user); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return TokenPair{}, ErrInvalidRefreshToken
+		}
+		return TokenPair{}, err
+	}
+	if user.DisabledAt != nil {
+		if err := s.repo.RevokeFamily(token.Family, now); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	return s.issue(user.ID, token.Family, now)
+}
+
+// Logout revokes the family of a refresh token, unknown tokens are ignored
+func (s *JWTService) Logout(raw string) error {
+	token, err := s.repo.GetRefreshToken(hashToken(raw))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.RevokeFamily(token.Family, time.Now())
+}
+
+// RevokeUser ends every login of a user and returns how many were active. Access tokens
+// that were already issued stay valid until they expire.
+func (s *JWTService) RevokeUser(userId uint) (int, error) {
+	revoked, err := s.repo.RevokeUser(userId, time.Now())
+	return int(revoked), err
+}
+
+// Verify checks an access token and returns the id of its user
+func (s *JWTService) Verify(access string) (uint, error) {
+	claims, err := s.keys.Verify(access, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, jwt.ErrMalformed
+	}
+	return uint(userID), nil
+}
+
+func (s *JWTService) issue(userId uint, family string, now time.Time) (TokenPair, error) {
+	access, err := s.keys.Sign(jwt.Claims{
+		Subject:   strconv.FormatUint(uint64(userId), 10),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.accessTTL).Unix(),
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+	random, err := randomHex(32)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	raw := "rt_" + random
+	refresh := models.RefreshToken{UserID: userId, Family: family, Hash: hashToken(raw), ExpiresAt: now.Add(s.refreshTTL)}
+	if err := s.repo.CreateRefreshToken(&refresh); err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTTL.Seconds()),
+		RefreshToken: raw,
+	}, nil
+}
+
+func randomHex(size int) (string, error) {
+	random := make([]byte, size)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+)
+
+// ErrInboxList is returned when trying to delete the inbox of a user
+var ErrInboxList = errors.New("the inbox list cannot be deleted")
+
+type ListService struct {
+	repo *repos.ListRepository
+}
+
+// the constructor for ListService
+
+func NewListService(repo *repos.ListRepository) *ListService {
+	return &ListService{repo}
+}
+
+// GetLists returns every list of a scope, making sure the inbox of a user exists
+func (s *ListService) GetLists(scope repos.Scope) ([]models.List, error) {
+	if scope.OrgID == nil {
+		if _, err := s.repo.GetInbox(scope.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetAllLists(scope)
+}
+
+func (s *ListService) GetList(id string) (models.List, error) {
+	return s.repo.GetList(id)
+}
+
+func (s *ListService) AddList(list *models.List) error {
+	list.IsInbox = false
+	return s.repo.CreateList(list)
+}
+
+func (s *ListService) EditList(list *models.List) error {
+	return s.repo.UpdateList(list)
+}
+
+// RemoveList deletes a list and moves its todos to the inbox of its owner or organization
+func (s *ListService) RemoveList(list *models.List) error {
+	if list.IsInbox {
+		return ErrInboxList
+	}
+	var inbox models.List
+	var err error
+	if list.OrgID != nil {
+		inbox, err = s.repo.GetOrgInbox(*list.OrgID)
+	} else {
+		inbox, err = s.repo.GetInbox(list.UserID)
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteList(list, inbox.ID)
+}
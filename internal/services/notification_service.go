@@ -0,0 +1,39 @@
+package services
+
+import (
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+)
+
+type NotificationService struct {
+	repo *repos.NotificationRepository
+}
+
+// the constructor for NotificationService
+
+func NewNotificationService(repo *repos.NotificationRepository) *NotificationService {
+	return &NotificationService{repo}
+}
+
+func (s *NotificationService) GetNotifications(userId uint, unreadOnly bool) ([]models.Notification, error) {
+	return s.repo.GetNotifications(userId, unreadOnly)
+}
+
+// Notify saves notifications, the ones a user would get about their own actions are dropped
+func (s *NotificationService) Notify(notifications ...models.Notification) error {
+	var kept []models.Notification
+	for _, notification := range notifications {
+		if notification.UserID != notification.ActorID {
+			kept = append(kept, notification)
+		}
+	}
+	return s.repo.CreateNotifications(kept)
+}
+
+func (s *NotificationService) MarkRead(userId uint, id string) (models.Notification, error) {
+	return s.repo.MarkRead(userId, id)
+}
+
+func (s *NotificationService) MarkAllRead(userId uint) (int64, error) {
+	return s.repo.MarkAllRead(userId)
+}
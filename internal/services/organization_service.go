@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidOrgRole is returned for organization roles other than owner, admin and member
+	ErrInvalidOrgRole = errors.New("invalid organization role")
+	// ErrOrgRole is returned when the role of the acting member does not allow a change of the members
+	ErrOrgRole = errors.New("not allowed to manage these members of the organization")
+	// ErrAlreadyMember is returned when inviting a user who is a member already
+	ErrAlreadyMember = errors.New("user is already a member")
+	// ErrLastOwner is returned when the last owner of an organization leaves or is demoted
+	ErrLastOwner = errors.New("an organization needs at least one owner")
+)
+
+// orgRoles are the roles a member of an organization can have
+var orgRoles = map[string]bool{
+	models.OrgOwner:  true,
+	models.OrgAdmin:  true,
+	models.OrgMember: true,
+}
+
+type OrganizationService struct {
+	repo     *repos.OrganizationRepository
+	userRepo *repos.UserRepository
+}
+
+// the constructor for OrganizationService
+
+func NewOrganizationService(repo *repos.OrganizationRepository, userRepo *repos.UserRepository) *OrganizationService {
+	return &OrganizationService{repo, userRepo}
+}
+
+// CreateOrganization saves a new organization with an inbox list, its creator becomes the owner
+func (s *OrganizationService) CreateOrganization(userId uint, org *models.Organization) error {
+	return s.repo.CreateOrganization(org, userId)
+}
+
+// GetOrganizations returns the organizations a user is a member of
+func (s *OrganizationService) GetOrganizations(userId uint) ([]models.Organization, error) {
+	return s.repo.GetOrganizations(userId)
+}
+
+// GetOrganization returns an organization with the role of the user, which is empty for non-members
+func (s *OrganizationService) GetOrganization(id string, userId uint) (models.Organization, error) {
+	org, err := s.repo.GetOrganization(id)
+	if err != nil {
+		return org, err
+	}
+	org.Role, err = s.repo.MemberRole(org.ID, userId)
+	return org, err
+}
+
+func (s *OrganizationService) GetMembers(orgId uint) ([]models.Membership, error) {
+	return s.repo.GetMembers(orgId)
+}
+
+// Invite asks the user of the given name to join an organization. Admins may invite
+// members, only owners may invite admins and owners. Inviting again changes the role.
+func (s *OrganizationService) Invite(actorID uint, org *models.Organization, username, role string) (models.Invitation, error) {
+	invitation := models.Invitation{OrgID: org.ID, OrgName: org.Name, InviterID: actorID, Role: role}
+	if err := s.checkGrant(actorID, org.ID, role); err != nil {
+		return invitation, err
+	}
+	user, err := s.findUser(username)
+	if err != nil {
+		return invitation, err
+	}
+	existing, err := s.repo.MemberRole(org.ID, user.ID)
+	if err != nil {
+		return invitation, err
+	}
+	if existing != "" {
+		return invitation, ErrAlreadyMember
+	}
+
+	invitation.UserID = user.ID
+	return invitation, s.repo.SaveInvitation(&invitation)
+}
+
+func (s *OrganizationService) GetInvitations(userId uint) ([]models.Invitation, error) {
+	return s.repo.GetInvitations(userId)
+}
+
+// AcceptInvitation makes a user a member of the organization that invited them
+func (s *OrganizationService) AcceptInvitation(userId uint, id string) (models.Membership, error) {
+	invitation, err := s.repo.GetInvitation(userId, id)
+	if err != nil {
+		return models.Membership{}, err
+	}
+	return s.repo.AcceptInvitation(&invitation)
+}
+
+// DeclineInvitation deletes an invitation of a user
+func (s *OrganizationService) DeclineInvitation(userId uint, id string) error {
+	invitation, err := s.repo.GetInvitation(userId, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteInvitation(&invitation)
+}
+
+// SetRole changes the role of a member, which only owners may do.
+// gorm.ErrRecordNotFound when the user is not a member.
+func (s *OrganizationService) SetRole(actorID uint, org *models.Organization, username, role string) (models.Membership, error) {
+	membership := models.Membership{OrgID: org.ID, Username: username, Role: role}
+	if !orgRoles[role] {
+		return membership, ErrInvalidOrgRole
+	}
+	actorRole, err := s.repo.MemberRole(org.ID, actorID)
+	if err != nil {
+		return membership, err
+	}
+	if actorRole != models.OrgOwner {
+		return membership, ErrOrgRole
+	}
+	user, err := s.findUser(username)
+	if err != nil {
+		return membership, err
+	}
+	membership.UserID = user.ID
+	current, err := s.repo.MemberRole(org.ID, user.ID)
+	if err == nil && current == "" {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return membership, err
+	}
+	if current == models.OrgOwner && role != models.OrgOwner {
+		if err := s.checkOtherOwner(org.ID); err != nil {
+			return membership, err
+		}
+	}
+	return membership, s.repo.SetMemberRole(org.ID, user.ID, role)
+}
+
+// RemoveMember takes a user out of an organization. Every member may leave, admins may
+// remove members and owners anyone. The last owner cannot leave.
+// gorm.ErrRecordNotFound when the user is not a member.
+func (s *OrganizationService) RemoveMember(actorID uint, org *models.Organization, username string) error {
+	user, err := s.findUser(username)
+	if err != nil {
+		return err
+	}
+	role, err := s.repo.MemberRole(org.ID, user.ID)
+	if err == nil && role == "" {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if user.ID != actorID {
+		if err := s.checkGrant(actorID, org.ID, role); err != nil {
+			return err
+		}
+	}
+	if role == models.OrgOwner {
+		if err := s.checkOtherOwner(org.ID); err != nil {
+			return err
+		}
+	}
+	return s.repo.DeleteMember(org.ID, user.ID)
+}
+
+// checkGrant makes sure the acting user may give a role to someone, or take it away:
+// admins may manage members, owners every role
+func (s *OrganizationService) checkGrant(actorID, orgId uint, role string) error {
+	if !orgRoles[role] {
+		return ErrInvalidOrgRole
+	}
+	actorRole, err := s.repo.MemberRole(orgId, actorID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case actorRole == models.OrgOwner:
+	case actorRole == models.OrgAdmin && role == models.OrgMember:
+	default:
+		return ErrOrgRole
+	}
+	return nil
+}
+
+func (s *OrganizationService) checkOtherOwner(orgId uint) error {
+	owners, err := s.repo.CountOwners(orgId)
+	if err != nil {
+		return err
+	}
+	if owners < 2 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func (s *OrganizationService) findUser(username string) (models.User, error) {
+	var user models.User
+	err := s.userRepo.GetUser(username, &user)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, ErrUnknownUser
+	}
+	return user, err
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+)
+
+// positions are base62 fractions compared as plain strings, "V" sorts between "A" and "z".
+// A key never ends with the zero digit so there is always room to insert before it.
+const positionDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// MaxPositionLength is the key length above which a list is rebalanced
+const MaxPositionLength = 12
+
+// HistoryMoved is the undo action of MoveTodo
+const HistoryMoved = "moved"
+
+// ErrInvalidAnchor is returned when the todos to move next to are not in the same
+// list of the user, or are the moved todo itself
+var ErrInvalidAnchor = errors.New("invalid move anchor")
+
+// positionBetween returns a key sorting strictly between a and b. An empty a means the
+// start of the list and an empty b the end of it. a must sort before b.
+func positionBetween(a, b string) string {
+	if b != "" {
+		// keep the common prefix, a is padded with zero digits
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + positionBetween(suffix(a, n), b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(positionDigits, a[0])
+	}
+	digitB := len(positionDigits)
+	if b != "" {
+		digitB = strings.IndexByte(positionDigits, b[0])
+	}
+	if digitB-digitA > 1 {
+		return string(positionDigits[(digitA+digitB+1)/2])
+	}
+	// the first digits are next to each other
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(positionDigits[digitA]) + positionBetween(suffix(a, 1), "")
+}
+
+// evenPositions returns n increasing keys spread evenly over the key space
+// with enough room between them for many inserts
+func evenPositions(n int) []string {
+	base := uint64(len(positionDigits))
+	length, space := 1, base
+	for space < uint64(n+1)*base {
+		length++
+		space *= base
+	}
+
+	positions := make([]string, n)
+	step := space / uint64(n+1)
+	for i := range positions {
+		value := step * uint64(i+1)
+		key := make([]byte, length)
+		for j := length - 1; j >= 0; j-- {
+			key[j] = positionDigits[value%base]
+			value /= base
+		}
+		positions[i] = strings.TrimRight(string(key), positionDigits[:1])
+	}
+	return positions
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return positionDigits[0]
+}
+
+func suffix(s string, n int) string {
+	if n >= len(s) {
+		return ""
+	}
+	return s[n:]
+}
+
+// MoveTodo places a todo right after the todo afterID and/or right before beforeID.
+// The todo joins the list and parent of its anchors.
+func (s *TodoService) MoveTodo(actorID uint, todo *models.Todo, beforeID, afterID *uint) error {
+	return s.undoable(actorID, func(tx *TodoService) (string, error) {
+		position, anchor, err := tx.positionBetweenAnchors(todo, beforeID, afterID)
+		if err != nil {
+			return "", err
+		}
+		todo.ListID, todo.ParentID = anchor.ListID, anchor.ParentID
+		_, err = tx.editTodo(todo, EditOptions{position: position})
+		return HistoryMoved, err
+	})
+}
+
+// positionBetweenAnchors computes the new position of a moved todo and returns one of the anchors
+func (s *TodoService) positionBetweenAnchors(todo *models.Todo, beforeID, afterID *uint) (string, models.Todo, error) {
+	var before, after *models.Todo
+	for _, id := range []*uint{beforeID, afterID} {
+		if id == nil {
+			continue
+		}
+		anchor, err := s.repo.GetTodo(idString(*id))
+		if err != nil || !sameOwner(&anchor, todo) || anchor.ID == todo.ID {
+			return "", anchor, ErrInvalidAnchor
+		}
+		if id == beforeID {
+			before = &anchor
+		} else {
+			after = &anchor
+		}
+	}
+	anchor := before
+	if anchor == nil {
+		anchor = after
+	}
+	if anchor == nil {
+		return "", models.Todo{}, ErrInvalidAnchor
+	}
+	scope := positionScope(anchor)
+	if before != nil && after != nil && scope != positionScope(after) {
+		return "", *anchor, ErrInvalidAnchor
+	}
+
+	siblings, err := s.scopePositions(scope)
+	if err != nil {
+		return "", *anchor, err
+	}
+	// the new neighbours are looked up without the moved todo itself
+	var ordered []models.Todo
+	for _, sibling := range siblings {
+		if sibling.ID != todo.ID {
+			ordered = append(ordered, sibling)
+		}
+	}
+	index := func(id uint) int {
+		for i, sibling := range ordered {
+			if sibling.ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	lower, upper := "", ""
+	switch {
+	case before != nil && after != nil:
+		i, j := index(after.ID), index(before.ID)
+		if i < 0 || j < 0 || i >= j {
+			return "", *anchor, ErrInvalidAnchor
+		}
+		lower, upper = ordered[i].Position, ordered[j].Position
+	case after != nil:
+		i := index(after.ID)
+		lower = ordered[i].Position
+		if i+1 < len(ordered) {
+			upper = ordered[i+1].Position
+		}
+	default:
+		j := index(before.ID)
+		upper = ordered[j].Position
+		if j > 0 {
+			lower = ordered[j-1].Position
+		}
+	}
+	return positionBetween(lower, upper), *anchor, nil
+}
+
+// scopePositions returns the todos of a list in position order, rebalancing it first
+// when positions are missing or not unique, e.g. for todos created before ordering existed
+func (s *TodoService) scopePositions(scope repos.PositionScope) ([]models.Todo, error) {
+	todos, err := s.repo.ScopePositions(scope)
+	if err != nil {
+		return nil, err
+	}
+	for i, todo := range todos {
+		if todo.Position == "" || (i > 0 && todos[i-1].Position == todo.Position) {
+			if err := s.rebalance(scope); err != nil {
+				return nil, err
+			}
+			return s.repo.ScopePositions(scope)
+		}
+	}
+	return todos, nil
+}
+
+// nextPosition returns a position after every todo of the scope
+func (s *TodoService) nextPosition(scope repos.PositionScope) (string, error) {
+	last, err := s.repo.LastPosition(scope)
+	if err != nil {
+		return "", err
+	}
+	return positionBetween(last, ""), nil
+}
+
+// rebalance spreads the positions of a list evenly again, keeping the order
+func (s *TodoService) rebalance(scope repos.PositionScope) error {
+	todos, err := s.repo.ScopePositions(scope)
+	if err != nil || len(todos) == 0 {
+		return err
+	}
+	keys := evenPositions(len(todos))
+	positions := make(map[uint]string, len(todos))
+	for i, todo := range todos {
+		positions[todo.ID] = keys[i]
+	}
+	return s.repo.SetPositions(positions)
+}
+
+// RebalancePositions rebalances every list with a position longer than MaxPositionLength
+// and returns how many lists were rebalanced
+func (s *TodoService) RebalancePositions() (int, error) {
+	scopes, err := s.repo.LongPositionScopes(MaxPositionLength)
+	if err != nil {
+		return 0, err
+	}
+	for i, scope := range scopes {
+		err := s.repo.Transaction(func(repo *repos.TodoRepository) error {
+			return s.withRepo(repo).rebalance(scope)
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(scopes), nil
+}
+
+// RunPositionRebalance calls RebalancePositions every interval until ctx is done
+func (s *TodoService) RunPositionRebalance(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		if rebalanced, err := s.RebalancePositions(); err != nil {
+			log.Printf("failed to rebalance todo positions: %v", err)
+		} else if rebalanced > 0 {
+			log.Printf("rebalanced the positions of %d lists", rebalanced)
+		}
+	})
+}
+
+func positionScope(todo *models.Todo) repos.PositionScope {
+	var scope repos.PositionScope
+	if todo.OrgID == nil {
+		scope.UserID = todo.UserID
+	}
+	if todo.ListID != nil {
+		scope.ListID = *todo.ListID
+	}
+	if todo.ParentID != nil {
+		scope.ParentID = *todo.ParentID
+	}
+	return scope
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPositionBetween(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"", "", "V"},
+		{"V", "", "l"},
+		{"", "V", "G"},
+		{"A", "B", "AV"},
+		{"A", "A1", "A0V"},
+		{"z", "", "zV"},
+		{"", "1", "0V"},
+		{"AV", "B", "Al"},
+		{"Azz", "B", "AzzV"},
+	}
+	for _, tt := range tests {
+		got := positionBetween(tt.a, tt.b)
+		assert.Equal(t, tt.want, got, "between %q and %q", tt.a, tt.b)
+		assert.True(t, tt.a < got && (tt.b == "" || got < tt.b), "%q not between %q and %q", got, tt.a, tt.b)
+	}
+}
+
+func TestPositionBetweenKeepsOrder(t *testing.T) {
+	positions := []string{positionBetween("", "")}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		at := r.Intn(len(positions) + 1)
+		lower, upper := "", ""
+		if at > 0 {
+			lower = positions[at-1]
+		}
+		if at < len(positions) {
+			upper = positions[at]
+		}
+		key := positionBetween(lower, upper)
+		assert.False(t, strings.HasSuffix(key, "0"), key)
+		positions = append(positions[:at], append([]string{key}, positions[at:]...)...)
+	}
+	assert.True(t, sort.StringsAreSorted(positions))
+	for i := 1; i < len(positions); i++ {
+		assert.NotEqual(t, positions[i-1], positions[i])
+	}
+}
+
+func TestEvenPositions(t *testing.T) {
+	for _, n := range []int{1, 2, 61, 62, 500, 5000} {
+		positions := evenPositions(n)
+		assert.Len(t, positions, n)
+		assert.True(t, sort.StringsAreSorted(positions))
+		for i, key := range positions {
+			assert.NotEmpty(t, key)
+			assert.False(t, strings.HasSuffix(key, "0"), key)
+			assert.LessOrEqual(t, len(key), 4)
+			if i > 0 {
+				assert.NotEqual(t, positions[i-1], key)
+			}
+		}
+	}
+}
@@ -0,0 +1,490 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"todo-list/internal/models"
+)
+
+// ErrInvalidRecurrence is returned for recurrence rules that cannot be parsed
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// Frequency of a recurrence rule
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// WeekdayNum is a BYDAY entry such as "MO" or "-1FR" (the last friday of the month or year)
+type WeekdayNum struct {
+	Ordinal int // 0 means every matching weekday of the period
+	Weekday time.Weekday
+}
+
+// RecurrenceRule is the subset of an RFC 5545 RRULE used by recurring todos:
+// FREQ, INTERVAL, BYDAY, BYMONTHDAY, BYMONTH, BYHOUR, BYMINUTE, BYSECOND, UNTIL and COUNT.
+// Occurrences are computed in wall clock time of the location of the previous
+// occurrence, so a todo due at 09:00 Europe/Berlin stays at 09:00 across DST changes.
+type RecurrenceRule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	ByHour     *int
+	ByMinute   *int
+	BySecond   *int
+	Until      *time.Time
+	Count      int // occurrences left including the current one, 0 means unlimited
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// ParseRecurrence parses an RRULE string, with or without the "RRULE:" prefix,
+// e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10"
+func ParseRecurrence(value string) (RecurrenceRule, error) {
+	rule := RecurrenceRule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, found := strings.Cut(part, "=")
+		if !found || val == "" {
+			return rule, fmt.Errorf("%w: malformed part %q", ErrInvalidRecurrence, part)
+		}
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(val))
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				err = fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("INTERVAL must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+			if err == nil && rule.Count < 1 {
+				err = errors.New("COUNT must be positive")
+			}
+		case "UNTIL":
+			var until time.Time
+			until, err = parseUntil(val)
+			rule.Until = &until
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(val, -31, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseIntList(val, 1, 12)
+			for _, m := range months {
+				rule.ByMonth = append(rule.ByMonth, time.Month(m))
+			}
+		case "BYHOUR":
+			rule.ByHour, err = parseSingleInt(val, 0, 23)
+		case "BYMINUTE":
+			rule.ByMinute, err = parseSingleInt(val, 0, 59)
+		case "BYSECOND":
+			rule.BySecond, err = parseSingleInt(val, 0, 59)
+		case "WKST":
+			if strings.ToUpper(val) != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported part %q", key)
+		}
+		if err != nil {
+			return rule, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return rule, fmt.Errorf("%w: COUNT and UNTIL cannot be combined", ErrInvalidRecurrence)
+	}
+	for _, day := range rule.ByDay {
+		if day.Ordinal != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return rule, fmt.Errorf("%w: numbered BYDAY needs FREQ=MONTHLY or YEARLY", ErrInvalidRecurrence)
+		}
+	}
+	return rule, nil
+}
+
+// String formats the rule as an RRULE value without the "RRULE:" prefix
+func (r RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		var months []string
+		for _, m := range r.ByMonth {
+			months = append(months, strconv.Itoa(int(m)))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		var days []string
+		for _, d := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(d))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, d := range r.ByDay {
+			code := weekdayCode(d.Weekday)
+			if d.Ordinal != 0 {
+				code = strconv.Itoa(d.Ordinal) + code
+			}
+			days = append(days, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.ByHour != nil {
+		parts = append(parts, "BYHOUR="+strconv.Itoa(*r.ByHour))
+	}
+	if r.ByMinute != nil {
+		parts = append(parts, "BYMINUTE="+strconv.Itoa(*r.ByMinute))
+	}
+	if r.BySecond != nil {
+		parts = append(parts, "BYSECOND="+strconv.Itoa(*r.BySecond))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after the given one.
+// It returns false when the rule is exhausted by COUNT or UNTIL.
+func (r RecurrenceRule) Next(current time.Time) (time.Time, bool) {
+	if r.Count == 1 {
+		return time.Time{}, false
+	}
+
+	loc := current.Location()
+	hour, minute, second := current.Clock()
+	if r.ByHour != nil {
+		hour = *r.ByHour
+	}
+	if r.ByMinute != nil {
+		minute = *r.ByMinute
+	}
+	if r.BySecond != nil {
+		second = *r.BySecond
+	}
+
+	// walk the periods (day, week, month or year) the rule may fire in, starting with
+	// the one of the current occurrence, until one has a candidate after it.
+	// Invalid dates such as February 30 are skipped as RFC 5545 requires.
+	year, month, day := current.Date()
+	for period := 0; period < 1000; period++ {
+		var dates []time.Time
+		switch r.Freq {
+		case Daily:
+			d := time.Date(year, month, day+period*r.Interval, 0, 0, 0, 0, time.UTC)
+			if r.matchesDay(d) {
+				dates = append(dates, d)
+			}
+		case Weekly:
+			monday := time.Date(year, month, day-(int(current.Weekday())+6)%7+period*7*r.Interval, 0, 0, 0, 0, time.UTC)
+			for i := 0; i < 7; i++ {
+				d := monday.AddDate(0, 0, i)
+				if (len(r.ByDay) == 0 && d.Weekday() == current.Weekday()) || r.hasWeekday(d.Weekday()) {
+					dates = append(dates, d)
+				}
+			}
+		case Monthly:
+			first := time.Date(year, month+time.Month(period*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+			if len(r.ByMonth) == 0 || r.hasMonth(first.Month()) {
+				dates = r.daysOfMonth(first, day)
+			}
+		case Yearly:
+			months := r.ByMonth
+			if len(months) == 0 {
+				months = []time.Month{month}
+			}
+			y := year + period*r.Interval
+			if len(r.ByDay) > 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+				dates = r.weekdaysOfYear(y)
+			} else {
+				for _, m := range months {
+					dates = append(dates, r.daysOfMonth(time.Date(y, m, 1, 0, 0, 0, 0, time.UTC), day)...)
+				}
+			}
+		}
+
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+		for _, d := range dates {
+			candidate := time.Date(d.Year(), d.Month(), d.Day(), hour, minute, second, 0, loc)
+			if !candidate.After(current) {
+				continue
+			}
+			if r.Until != nil && candidate.After(*r.Until) {
+				return time.Time{}, false
+			}
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextOccurrence builds the todo that follows a completed recurring todo, or nil when
+// the series has ended. Without a due date the series continues from now.
+func nextOccurrence(todo *models.Todo, now time.Time) (*models.Todo, error) {
+	rule, err := ParseRecurrence(todo.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	if todo.DueTimezone != "" {
+		if loc, err = time.LoadLocation(todo.DueTimezone); err != nil {
+			return nil, err
+		}
+	}
+
+	current := now.In(loc)
+	if todo.DueDate != nil {
+		current = todo.DueDate.In(loc)
+	}
+	next, ok := rule.Next(current)
+	if !ok {
+		return nil, nil
+	}
+
+	// pin the time of day, otherwise an occurrence moved by a DST gap
+	// (02:30 does not exist on the day clocks go forward) would shift all later ones
+	if rule.ByHour == nil && rule.ByMinute == nil && rule.BySecond == nil {
+		hour, minute, second := current.Clock()
+		rule.ByHour, rule.ByMinute, rule.BySecond = &hour, &minute, &second
+	}
+	if rule.Count > 1 {
+		rule.Count--
+	}
+
+	due := next.UTC()
+	tagIDs := make([]uint, 0, len(todo.Tags))
+	for _, tag := range todo.Tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	return &models.Todo{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Priority:    todo.Priority,
+		DueDate:     &due,
+		DueTimezone: todo.DueTimezone,
+		UserID:      todo.UserID,
+		AssigneeID:  todo.AssigneeID,
+		ListID:      todo.ListID,
+		OrgID:       todo.OrgID,
+		ParentID:    todo.ParentID,
+		Recurrence:  rule.String(),
+		TagIDs:      tagIDs,
+	}, nil
+}
+
+// daysOfMonth lists the days of the month starting at first that match the rule,
+// anchorDay is used when the rule has neither BYMONTHDAY nor BYDAY
+func (r RecurrenceRule) daysOfMonth(first time.Time, anchorDay int) []time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+	var dates []time.Time
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			if md < 0 {
+				md = last + md + 1
+			}
+			if md < 1 || md > last {
+				continue
+			}
+			d := time.Date(first.Year(), first.Month(), md, 0, 0, 0, 0, time.UTC)
+			if len(r.ByDay) == 0 || r.hasWeekday(d.Weekday()) {
+				dates = append(dates, d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			dates = append(dates, nthWeekdays(first, last, wd)...)
+		}
+	case anchorDay <= last:
+		dates = append(dates, time.Date(first.Year(), first.Month(), anchorDay, 0, 0, 0, 0, time.UTC))
+	}
+	return dates
+}
+
+// weekdaysOfYear handles FREQ=YEARLY;BYDAY=... without BYMONTH, e.g. BYDAY=20MO
+func (r RecurrenceRule) weekdaysOfYear(year int) []time.Time {
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	days := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	var dates []time.Time
+	for _, wd := range r.ByDay {
+		var matches []time.Time
+		for i := 0; i < days; i++ {
+			if d := first.AddDate(0, 0, i); d.Weekday() == wd.Weekday {
+				matches = append(matches, d)
+			}
+		}
+		dates = append(dates, pickOrdinal(matches, wd.Ordinal)...)
+	}
+	return dates
+}
+
+// nthWeekdays returns the days between first and the last day of the month that match
+// the weekday, restricted to the n-th (or n-th from the end) one when an ordinal is given
+func nthWeekdays(first time.Time, last int, wd WeekdayNum) []time.Time {
+	var matches []time.Time
+	for day := 1; day <= last; day++ {
+		d := time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+		if d.Weekday() == wd.Weekday {
+			matches = append(matches, d)
+		}
+	}
+	return pickOrdinal(matches, wd.Ordinal)
+}
+
+func pickOrdinal(matches []time.Time, ordinal int) []time.Time {
+	switch {
+	case ordinal == 0:
+		return matches
+	case ordinal > 0 && ordinal <= len(matches):
+		return matches[ordinal-1 : ordinal]
+	case ordinal < 0 && -ordinal <= len(matches):
+		i := len(matches) + ordinal
+		return matches[i : i+1]
+	}
+	return nil
+}
+
+func (r RecurrenceRule) matchesDay(d time.Time) bool {
+	if len(r.ByDay) > 0 && !r.hasWeekday(d.Weekday()) {
+		return false
+	}
+	if len(r.ByMonth) > 0 && !r.hasMonth(d.Month()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		for _, md := range r.ByMonthDay {
+			if md == d.Day() || md < 0 && last+md+1 == d.Day() {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (r RecurrenceRule) hasWeekday(weekday time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RecurrenceRule) hasMonth(month time.Month) bool {
+	for _, m := range r.ByMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+func weekdayCode(weekday time.Weekday) string {
+	for code, wd := range weekdayCodes {
+		if wd == weekday {
+			return code
+		}
+	}
+	return ""
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// a date only UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL %q is not a valid date", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(strings.ToUpper(value), ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		weekday, ok := weekdayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		day := WeekdayNum{Weekday: weekday}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			ordinal, err := strconv.Atoi(prefix)
+			if err != nil || ordinal == 0 || ordinal < -53 || ordinal > 53 {
+				return nil, fmt.Errorf("invalid BYDAY %q", item)
+			}
+			day.Ordinal = ordinal
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseIntList parses a comma separated list of non zero numbers within [min, max]
+func parseIntList(value string, min, max int) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n < min || n > max || n == 0 {
+			return nil, fmt.Errorf("invalid value %q", item)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+func parseSingleInt(value string, min, max int) (*int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return nil, fmt.Errorf("invalid value %q", value)
+	}
+	return &n, nil
+}
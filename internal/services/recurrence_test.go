@@ -0,0 +1,232 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	utc := time.UTC
+	tests := []struct {
+		name    string
+		rule    string
+		current time.Time
+		want    []time.Time // successive occurrences
+		ends    bool        // the series has no occurrence after the last wanted one
+	}{
+		{
+			name:    "daily with interval",
+			rule:    "FREQ=DAILY;INTERVAL=2",
+			current: time.Date(2024, 12, 30, 8, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2025, 1, 1, 8, 0, 0, 0, utc),
+				time.Date(2025, 1, 3, 8, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "weekdays only",
+			rule:    "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			current: time.Date(2025, 1, 10, 8, 0, 0, 0, utc), // friday
+			want: []time.Time{
+				time.Date(2025, 1, 13, 8, 0, 0, 0, utc),
+				time.Date(2025, 1, 14, 8, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "weekly on the weekday of the current occurrence",
+			rule:    "FREQ=WEEKLY",
+			current: time.Date(2025, 2, 26, 18, 30, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2025, 3, 5, 18, 30, 0, 0, utc),
+			},
+		},
+		{
+			name:    "every other week on monday and thursday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+			current: time.Date(2025, 1, 6, 9, 0, 0, 0, utc), // monday
+			want: []time.Time{
+				time.Date(2025, 1, 9, 9, 0, 0, 0, utc),
+				time.Date(2025, 1, 20, 9, 0, 0, 0, utc),
+				time.Date(2025, 1, 23, 9, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "monthly on the 31st skips shorter months",
+			rule:    "FREQ=MONTHLY",
+			current: time.Date(2025, 1, 31, 12, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2025, 3, 31, 12, 0, 0, 0, utc),
+				time.Date(2025, 5, 31, 12, 0, 0, 0, utc),
+				time.Date(2025, 7, 31, 12, 0, 0, 0, utc),
+				time.Date(2025, 8, 31, 12, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "last day of the month",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			current: time.Date(2024, 1, 31, 12, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2024, 2, 29, 12, 0, 0, 0, utc),
+				time.Date(2024, 3, 31, 12, 0, 0, 0, utc),
+				time.Date(2024, 4, 30, 12, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "monthly on the 30th in february",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=30",
+			current: time.Date(2025, 1, 30, 12, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2025, 3, 30, 12, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "last friday of the month",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR",
+			current: time.Date(2025, 1, 31, 17, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2025, 2, 28, 17, 0, 0, 0, utc),
+				time.Date(2025, 3, 28, 17, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "quarterly with interval",
+			rule:    "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=15",
+			current: time.Date(2025, 11, 15, 9, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2026, 2, 15, 9, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "yearly on leap day",
+			rule:    "FREQ=YEARLY",
+			current: time.Date(2024, 2, 29, 10, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2028, 2, 29, 10, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "yearly on the fourth thursday of november",
+			rule:    "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			current: time.Date(2024, 11, 28, 15, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2025, 11, 27, 15, 0, 0, 0, utc),
+			},
+		},
+		{
+			name:    "until stops the series",
+			rule:    "FREQ=DAILY;UNTIL=20250102",
+			current: time.Date(2025, 1, 1, 8, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2025, 1, 2, 8, 0, 0, 0, utc),
+			},
+			ends: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrence(tt.rule)
+			if !assert.NoError(t, err) {
+				return
+			}
+			current := tt.current
+			for _, want := range tt.want {
+				next, ok := rule.Next(current)
+				assert.True(t, ok)
+				assert.Equal(t, want, next)
+				current = next
+			}
+			if tt.ends {
+				_, ok := rule.Next(current)
+				assert.False(t, ok)
+			}
+		})
+	}
+}
+
+func TestRecurrenceKeepsWallClockAcrossDST(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	rule, _ := ParseRecurrence("FREQ=WEEKLY")
+
+	// clocks go forward on 2025-03-30, the todo stays at 09:00 local time
+	next, ok := rule.Next(time.Date(2025, 3, 24, 9, 0, 0, 0, berlin))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2025, 3, 31, 9, 0, 0, 0, berlin), next)
+	assert.Equal(t, 7, next.UTC().Hour())
+
+	// and back on 2025-10-26
+	next, _ = rule.Next(time.Date(2025, 10, 20, 9, 0, 0, 0, berlin))
+	assert.Equal(t, 8, next.UTC().Hour())
+
+	newYork := mustLocation(t, "America/New_York")
+	monthly, _ := ParseRecurrence("FREQ=MONTHLY")
+	next, _ = monthly.Next(time.Date(2025, 2, 28, 23, 30, 0, 0, newYork))
+	assert.Equal(t, time.Date(2025, 3, 28, 23, 30, 0, 0, newYork), next)
+}
+
+func TestNextOccurrenceAcrossDSTGap(t *testing.T) {
+	// 02:30 does not exist in Berlin on 2025-03-30, the day after must be back at 02:30
+	due := time.Date(2025, 3, 29, 2, 30, 0, 0, mustLocation(t, "Europe/Berlin")).UTC()
+	todo := &models.Todo{Title: "Night job", DueDate: &due, DueTimezone: "Europe/Berlin", Recurrence: "FREQ=DAILY"}
+
+	gap, err := nextOccurrence(todo, time.Now())
+	if !assert.NoError(t, err) || !assert.NotNil(t, gap) {
+		return
+	}
+	assert.Equal(t, "2025-03-30", gap.DueDate.In(mustLocation(t, "Europe/Berlin")).Format("2006-01-02"))
+
+	after, err := nextOccurrence(gap, time.Now())
+	if !assert.NoError(t, err) || !assert.NotNil(t, after) {
+		return
+	}
+	assert.Equal(t, "2025-03-31 02:30", after.DueDate.In(mustLocation(t, "Europe/Berlin")).Format("2006-01-02 15:04"))
+}
+
+func TestNextOccurrenceCount(t *testing.T) {
+	due := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	todo := &models.Todo{Title: "Standup", DueDate: &due, Recurrence: "FREQ=DAILY;COUNT=2", Tags: []models.Tag{{ID: 3}}}
+
+	next, err := nextOccurrence(todo, time.Now())
+	if !assert.NoError(t, err) || !assert.NotNil(t, next) {
+		return
+	}
+	assert.Equal(t, time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC), *next.DueDate)
+	assert.Equal(t, "FREQ=DAILY;BYHOUR=9;BYMINUTE=0;BYSECOND=0;COUNT=1", next.Recurrence)
+	assert.Equal(t, []uint{3}, next.TagIDs)
+
+	last, err := nextOccurrence(next, time.Now())
+	assert.NoError(t, err)
+	assert.Nil(t, last)
+}
+
+func TestParseRecurrence(t *testing.T) {
+	rule, err := ParseRecurrence("RRULE:FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR;COUNT=4")
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR;COUNT=4", rule.String())
+
+	for _, invalid := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250101",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		_, err := ParseRecurrence(invalid)
+		assert.ErrorIs(t, err, ErrInvalidRecurrence, invalid)
+	}
+}
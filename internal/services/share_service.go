@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+// Permission is what a user may do with a todo, list or tag. Every level includes the ones below it.
+type Permission int
+
+const (
+	PermissionNone  Permission = iota
+	PermissionView             // read, comment and download attachments
+	PermissionEdit             // change todos, add todos to a list and manage attachments and dependencies
+	PermissionOwner            // delete, restore from the trash and share
+)
+
+var (
+	// ErrInvalidRole is returned for share roles other than viewer and editor
+	ErrInvalidRole = errors.New("invalid share role")
+	// ErrUnknownUser is returned when sharing with a username that does not exist
+	ErrUnknownUser = errors.New("unknown user")
+	// ErrShareWithOwner is returned when the owner shares a todo or list with themselves
+	ErrShareWithOwner = errors.New("cannot share with the owner")
+	// ErrShareOrgData is returned when sharing a todo or list of an organization, invite the user instead
+	ErrShareOrgData = errors.New("todos and lists of an organization cannot be shared")
+)
+
+// rolePermissions maps the roles of a share to the permission they grant
+var rolePermissions = map[string]Permission{
+	models.ShareViewer: PermissionView,
+	models.ShareEditor: PermissionEdit,
+}
+
+// orgPermissions maps the roles of the members of an organization to the permission
+// they have on its lists and todos
+var orgPermissions = map[string]Permission{
+	models.OrgOwner:  PermissionOwner,
+	models.OrgAdmin:  PermissionOwner,
+	models.OrgMember: PermissionEdit,
+}
+
+type ShareService struct {
+	repo     *repos.ShareRepository
+	userRepo *repos.UserRepository
+	orgRepo  *repos.OrganizationRepository
+}
+
+// the constructor for ShareService
+
+func NewShareService(repo *repos.ShareRepository, userRepo *repos.UserRepository, orgRepo *repos.OrganizationRepository) *ShareService {
+	return &ShareService{repo, userRepo, orgRepo}
+}
+
+// TodoPermission returns what a user may do with a todo: everything as its owner, otherwise
+// the highest role of the shares of the todo, of its parents and of their lists.
+// Todos of an organization are never shared, only its members have access to them.
+func (s *ShareService) TodoPermission(userId uint, todo *models.Todo) (Permission, error) {
+	if todo.OrgID != nil {
+		permission, err := s.OrgPermission(userId, *todo.OrgID)
+		// members may delete the todos they created themselves
+		if permission == PermissionEdit && todo.UserID == userId {
+			permission = PermissionOwner
+		}
+		return permission, err
+	}
+	if todo.UserID == userId {
+		return PermissionOwner, nil
+	}
+	roles, err := s.repo.TodoRoles(userId, todo.ID)
+	if err != nil {
+		return PermissionNone, err
+	}
+	permission := PermissionNone
+	for _, role := range roles {
+		permission = max(permission, rolePermissions[role])
+	}
+	return permission, nil
+}
+
+// ListPermission returns what a user may do with a list and its todos
+func (s *ShareService) ListPermission(userId uint, list *models.List) (Permission, error) {
+	if list.OrgID != nil {
+		return s.OrgPermission(userId, *list.OrgID)
+	}
+	if list.UserID == userId {
+		return PermissionOwner, nil
+	}
+	role, err := s.repo.ListRole(userId, list.ID)
+	return rolePermissions[role], err
+}
+
+// OrgPermission returns what a member may do with the lists and todos of an organization:
+// owners and admins everything, members read and change them
+func (s *ShareService) OrgPermission(userId, orgId uint) (Permission, error) {
+	role, err := s.orgRepo.MemberRole(orgId, userId)
+	return orgPermissions[role], err
+}
+
+// TagPermission returns what a user may do with a tag, tags are never shared
+func (s *ShareService) TagPermission(userId uint, tag *models.Tag) (Permission, error) {
+	if tag.UserID == userId {
+		return PermissionOwner, nil
+	}
+	return PermissionNone, nil
+}
+
+func (s *ShareService) GetTodoShares(todoId uint) ([]models.Share, error) {
+	return s.repo.GetTodoShares(todoId)
+}
+
+func (s *ShareService) GetListShares(listId uint) ([]models.Share, error) {
+	return s.repo.GetListShares(listId)
+}
+
+// ShareTodo shares a todo and its subtasks with the user of the given name,
+// sharing it again changes the role
+func (s *ShareService) ShareTodo(todo *models.Todo, username, role string) (models.Share, error) {
+	if todo.OrgID != nil {
+		return models.Share{}, ErrShareOrgData
+	}
+	return s.share(models.Share{TodoID: &todo.ID}, todo.UserID, username, role)
+}
+
+// ShareList shares a list and every todo in it with the user of the given name,
+// sharing it again changes the role
+func (s *ShareService) ShareList(list *models.List, username, role string) (models.Share, error) {
+	if list.OrgID != nil {
+		return models.Share{}, ErrShareOrgData
+	}
+	return s.share(models.Share{ListID: &list.ID}, list.UserID, username, role)
+}
+
+// FindUser returns the user a todo or list can be shared with, ErrUnknownUser if there is none
+func (s *ShareService) FindUser(username string) (models.User, error) {
+	var user models.User
+	err := s.userRepo.GetUser(username, &user)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, ErrUnknownUser
+	}
+	return user, err
+}
+
+func (s *ShareService) share(share models.Share, ownerId uint, username, role string) (models.Share, error) {
+	if _, ok := rolePermissions[role]; !ok {
+		return share, ErrInvalidRole
+	}
+	user, err := s.FindUser(username)
+	if err != nil {
+		return share, err
+	}
+	if user.ID == ownerId {
+		return share, ErrShareWithOwner
+	}
+
+	share.UserID, share.Username, share.Role = user.ID, user.Username, role
+	return share, s.repo.SaveShare(&share)
+}
+
+// UnshareTodo takes the access to a todo away from a user
+func (s *ShareService) UnshareTodo(todoId, userId uint) error {
+	return s.repo.DeleteTodoShare(todoId, userId)
+}
+
+// UnshareList takes the access to a list away from a user
+func (s *ShareService) UnshareList(listId, userId uint) error {
+	return s.repo.DeleteListShare(listId, userId)
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidParent is returned when parent_id points to a todo of another user,
+// to the todo itself or to one of its own subtasks
+var ErrInvalidParent = errors.New("invalid parent todo")
+
+// GetSubtree returns the todo with all of its subtasks nested in Children,
+// every todo that has subtasks reports its progress
+func (s *TodoService) GetSubtree(id string) (models.Todo, error) {
+	root, err := s.repo.GetTodo(id)
+	if err != nil {
+		return root, err
+	}
+
+	// load the tree level by level
+	children := make(map[uint][]models.Todo)
+	level := []uint{root.ID}
+	seen := map[uint]bool{root.ID: true}
+	for len(level) > 0 {
+		todos, err := s.repo.GetChildren(level)
+		if err != nil {
+			return root, err
+		}
+		level = nil
+		for _, todo := range todos {
+			if seen[todo.ID] {
+				continue
+			}
+			seen[todo.ID] = true
+			children[*todo.ParentID] = append(children[*todo.ParentID], todo)
+			level = append(level, todo.ID)
+		}
+	}
+
+	var build func(todo *models.Todo)
+	build = func(todo *models.Todo) {
+		todo.Children = children[todo.ID]
+		if len(todo.Children) > 0 {
+			progress := models.Progress{Total: int64(len(todo.Children))}
+			for i := range todo.Children {
+				if todo.Children[i].IsCompleted {
+					progress.Completed++
+				}
+				build(&todo.Children[i])
+			}
+			todo.Progress = &progress
+		}
+	}
+	build(&root)
+	return root, nil
+}
+
+// attachProgress sets the progress of every todo that has subtasks
+func (s *TodoService) attachProgress(todos []models.Todo) error {
+	ids := make([]uint, len(todos))
+	for i, todo := range todos {
+		ids[i] = todo.ID
+	}
+	progress, err := s.repo.GetProgress(ids)
+	if err != nil {
+		return err
+	}
+	for i := range todos {
+		if p, ok := progress[todos[i].ID]; ok {
+			todos[i].Progress = &p
+		}
+	}
+	return nil
+}
+
+// checkParent validates parent_id. A subtask without an explicit list joins the list of its parent.
+func (s *TodoService) checkParent(todo *models.Todo) error {
+	if todo.ParentID == nil {
+		return nil
+	}
+
+	parent, err := s.repo.GetTodo(idString(*todo.ParentID))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !sameOwner(&parent, todo)) {
+		return ErrInvalidParent
+	}
+	if err != nil {
+		return err
+	}
+
+	// walk up from the parent, meeting the todo itself would create a cycle
+	if todo.ID != 0 {
+		ancestor := &parent
+		for {
+			if ancestor.ID == todo.ID {
+				return ErrInvalidParent
+			}
+			if ancestor.ParentID == nil {
+				break
+			}
+			next, err := s.repo.GetTodo(idString(*ancestor.ParentID))
+			if err != nil {
+				return err
+			}
+			ancestor = &next
+		}
+	}
+
+	if todo.ListID == nil {
+		todo.ListID = parent.ListID
+	}
+	return nil
+}
+
+// completeDescendants completes the open subtasks below a todo, each moving to the
+// terminal status of its workflow
+func completeDescendants(repo *repos.TodoRepository, id uint) error {
+	descendants, err := descendantIDs(repo, id)
+	if err != nil {
+		return err
+	}
+	for _, id := range descendants {
+		child, err := repo.GetTodo(idString(id))
+		if err != nil {
+			return err
+		}
+		if child.IsCompleted {
+			continue
+		}
+		previous := child
+		child.IsCompleted = true
+		if err := resolveStatus(repo, &child, &previous); err != nil {
+			return err
+		}
+		if err := repo.UpdateTodo(&child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// descendantIDs collects the ids of all subtasks below a todo, at any depth
+func descendantIDs(repo *repos.TodoRepository, id uint) ([]uint, error) {
+	var ids []uint
+	seen := map[uint]bool{id: true}
+	level := []uint{id}
+	for len(level) > 0 {
+		children, err := repo.GetChildren(level)
+		if err != nil {
+			return nil, err
+		}
+		level = nil
+		for _, child := range children {
+			if !seen[child.ID] {
+				seen[child.ID] = true
+				ids = append(ids, child.ID)
+				level = append(level, child.ID)
+			}
+		}
+	}
+	return ids, nil
+}
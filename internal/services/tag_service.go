@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+// ErrDuplicateTag is returned when a user already has a tag with the same name
+var ErrDuplicateTag = errors.New("tag already exists")
+
+type TagService struct {
+	repo *repos.TagRepository
+}
+
+// the constructor for TagService
+
+func NewTagService(repo *repos.TagRepository) *TagService {
+	return &TagService{repo}
+}
+
+func (s *TagService) GetTags(userId uint) ([]models.Tag, error) {
+	return s.repo.GetAllTags(userId)
+}
+
+func (s *TagService) GetTag(id string) (models.Tag, error) {
+	return s.repo.GetTag(id)
+}
+
+func (s *TagService) AddTag(tag *models.Tag) error {
+	if err := s.checkNameFree(tag); err != nil {
+		return err
+	}
+	return s.repo.CreateTag(tag)
+}
+
+func (s *TagService) EditTag(tag *models.Tag) error {
+	if err := s.checkNameFree(tag); err != nil {
+		return err
+	}
+	return s.repo.UpdateTag(tag)
+}
+
+func (s *TagService) RemoveTag(tag *models.Tag) error {
+	return s.repo.DeleteTag(tag)
+}
+
+// tag names are unique per user
+func (s *TagService) checkNameFree(tag *models.Tag) error {
+	existing, err := s.repo.GetTagByName(tag.UserID, tag.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != tag.ID {
+		return ErrDuplicateTag
+	}
+	return nil
+}
@@ -1,36 +1,279 @@
 package services
 
 import (
+	"errors"
+	"strconv"
+	"time"
 	"todo-list/internal/models"
 	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
 )
 
+// ErrUnknownList is returned when a todo is put in a list the user does not own
+var ErrUnknownList = errors.New("unknown list")
+
 type TodoService struct {
-	repo *repos.TodoRepository
+	repo     *repos.TodoRepository
+	listRepo *repos.ListRepository
 }
 
 // the constructor for TodoService
 
-func NewTodoService(repo *repos.TodoRepository) *TodoService {
-	return &TodoService{repo}
+func NewTodoService(repo *repos.TodoRepository, listRepo *repos.ListRepository) *TodoService {
+	return &TodoService{repo, listRepo}
+}
+
+// EditOptions changes how EditTodo applies an update
+type EditOptions struct {
+	CompleteChildren bool // completing a todo also completes all of its subtasks
+	Force            bool // complete a todo even though todos blocking it are still open
+
+	position string // new position set by MoveTodo, otherwise positions only change with the list
+	assign   bool   // set by AssignTodo, otherwise the assignee is kept
+	assignee *uint
+}
+
+func (s *TodoService) GetTodoList(scope repos.Scope, filter repos.TodoFilter) (repos.TodoPage, error) {
+	page, err := s.repo.GetAllTodos(scope, filter)
+	if err != nil {
+		return page, err
+	}
+	return page, s.attachProgress(page.Todos)
+}
+
+// AddTodo creates a todo, actorID is the user recorded in its history
+func (s *TodoService) AddTodo(actorID uint, todo *models.Todo) error {
+	return s.undoable(actorID, func(tx *TodoService) (string, error) {
+		return models.HistoryCreated, tx.addTodo(todo)
+	})
 }
 
-func (s *TodoService) GetTodoList(userId uint) ([]models.Todo, error) {
-	return s.repo.GetAllTodos(userId)
+func (s *TodoService) EditTodo(actorID uint, todo *models.Todo, opts EditOptions) error {
+	return s.undoable(actorID, func(tx *TodoService) (string, error) {
+		return tx.editTodo(todo, opts)
+	})
+}
+
+// RemoveTodo moves a todo together with all of its subtasks to the trash
+func (s *TodoService) RemoveTodo(actorID uint, id string) error {
+	todo, err := s.repo.GetTodo(id)
+	if err != nil {
+		return err
+	}
+	return s.undoable(actorID, func(tx *TodoService) (string, error) {
+		return models.HistoryDeleted, tx.removeTodo(&todo)
+	})
+}
+
+// withRepo returns a copy of the service working on repo, usually bound to a transaction
+func (s *TodoService) withRepo(repo *repos.TodoRepository) *TodoService {
+	return &TodoService{repo, repo.Lists()}
+}
+
+func (s *TodoService) addTodo(todo *models.Todo) error {
+	todo.DeletedAt = gorm.DeletedAt{}
+	todo.AssigneeID = nil // new todos are assigned with AssignTodo
+	normalizeDueDate(todo)
+	if err := s.checkParent(todo); err != nil {
+		return err
+	}
+	if err := s.resolveList(todo); err != nil {
+		return err
+	}
+	if err := resolveStatus(s.repo, todo, nil); err != nil {
+		return err
+	}
+	position, err := s.nextPosition(positionScope(todo))
+	if err != nil {
+		return err
+	}
+	todo.Position = position
+	if err := s.repo.CreateTodo(todo); err != nil {
+		return err
+	}
+	return setTags(s.repo, todo)
+}
+
+// editTodo saves a changed todo and returns whether it was completed or updated
+func (s *TodoService) editTodo(todo *models.Todo, opts EditOptions) (string, error) {
+	// only RemoveTodo and RestoreTodo move todos in and out of the trash
+	todo.DeletedAt = gorm.DeletedAt{}
+	normalizeDueDate(todo)
+	previous, err := s.repo.GetTodo(idString(todo.ID))
+	if err != nil {
+		return "", err
+	}
+	// todos never move between organizations or out of one
+	todo.OrgID = previous.OrgID
+	if err := s.checkParent(todo); err != nil {
+		return "", err
+	}
+	if err := s.resolveList(todo); err != nil {
+		return "", err
+	}
+	if err := resolveStatus(s.repo, todo, &previous); err != nil {
+		return "", err
+	}
+	completing := todo.IsCompleted && !previous.IsCompleted
+	if completing && !opts.Force {
+		if err := s.checkBlockers(todo); err != nil {
+			return "", err
+		}
+	}
+	switch {
+	case opts.position != "":
+		todo.Position = opts.position
+	case positionScope(todo) != positionScope(&previous):
+		// todos moved to another list or parent go to its end
+		if todo.Position, err = s.nextPosition(positionScope(todo)); err != nil {
+			return "", err
+		}
+	default:
+		todo.Position = previous.Position
+	}
+	if opts.assign {
+		todo.AssigneeID = opts.assignee
+	} else {
+		todo.AssigneeID = previous.AssigneeID
+	}
+	action := models.HistoryUpdated
+	if completing {
+		action = models.HistoryCompleted
+	}
+
+	if err := s.repo.UpdateTodo(todo); err != nil {
+		return "", err
+	}
+	if completing && opts.CompleteChildren {
+		if err := completeDescendants(s.repo, todo.ID); err != nil {
+			return "", err
+		}
+	}
+	if err := setTags(s.repo, todo); err != nil {
+		return "", err
+	}
+	if completing && todo.Recurrence != "" {
+		return action, spawnNextOccurrence(s.repo, todo)
+	}
+	return action, nil
 }
 
-func (s *TodoService) AddTodo(todo *models.Todo) error {
-	return s.repo.CreateTodo(todo)
+func (s *TodoService) removeTodo(todo *models.Todo) error {
+	descendants, err := descendantIDs(s.repo, todo.ID)
+	if err != nil {
+		return err
+	}
+	return s.repo.TrashTodos(append(descendants, todo.ID))
 }
 
-func (s *TodoService) EditTodo(todo *models.Todo) error {
-	return s.repo.UpdateTodo(todo)
+// SearchTodos returns the todos of a scope matching a full-text query
+func (s *TodoService) SearchTodos(scope repos.Scope, query string, limit int) ([]repos.SearchResult, error) {
+	return s.repo.SearchTodos(scope, query, limit)
 }
 
-func (s *TodoService) RemoveTodo(id string) error {
-	return s.repo.DeleteTodo(id)
+// GetHistory returns the change log of a todo, oldest entry first
+func (s *TodoService) GetHistory(id string) ([]models.TodoHistory, error) {
+	return s.repo.GetHistory(id)
 }
 
 func (s *TodoService) GetTodo(id string) (models.Todo, error) {
-	return s.repo.GetTodo(id)
+	todo, err := s.repo.GetTodo(id)
+	if err != nil {
+		return todo, err
+	}
+	todos := []models.Todo{todo}
+	err = s.attachProgress(todos)
+	return todos[0], err
+}
+
+// spawnNextOccurrence creates the next todo of a recurring series. The rule moves on
+// to the new todo so completing the old one again does not create a duplicate.
+func spawnNextOccurrence(repo *repos.TodoRepository, todo *models.Todo) error {
+	next, err := nextOccurrence(todo, time.Now())
+	if err != nil || next == nil {
+		return err
+	}
+	last, err := repo.LastPosition(positionScope(next))
+	if err != nil {
+		return err
+	}
+	next.Position = positionBetween(last, "")
+	if err := resolveStatus(repo, next, nil); err != nil {
+		return err
+	}
+	if err := repo.CreateTodo(next); err != nil {
+		return err
+	}
+	if err := setTags(repo, next); err != nil {
+		return err
+	}
+
+	todo.Recurrence = ""
+	todo.Next = next
+	return repo.UpdateTodo(todo)
+}
+
+// resolveList puts todos without a list into the inbox of their owner, or of their
+// organization, and makes sure an explicit list belongs to the same user or organization
+func (s *TodoService) resolveList(todo *models.Todo) error {
+	if todo.ListID == nil {
+		inbox, err := s.inboxFor(todo)
+		if err != nil {
+			return err
+		}
+		todo.ListID = &inbox.ID
+		return nil
+	}
+
+	list, err := s.listRepo.GetList(idString(*todo.ListID))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !sameID(list.OrgID, todo.OrgID)) ||
+		(err == nil && list.OrgID == nil && list.UserID != todo.UserID) {
+		return ErrUnknownList
+	}
+	return err
+}
+
+func (s *TodoService) inboxFor(todo *models.Todo) (models.List, error) {
+	if todo.OrgID != nil {
+		return s.listRepo.GetOrgInbox(*todo.OrgID)
+	}
+	return s.listRepo.GetInbox(todo.UserID)
+}
+
+// sameOwner reports whether two todos belong to the same organization, or
+// both are personal todos of the same user
+func sameOwner(a, b *models.Todo) bool {
+	if a.OrgID != nil || b.OrgID != nil {
+		return sameID(a.OrgID, b.OrgID)
+	}
+	return a.UserID == b.UserID
+}
+
+// todoScope is the scope a todo belongs to
+func todoScope(todo *models.Todo) repos.Scope {
+	return repos.Scope{UserID: todo.UserID, OrgID: todo.OrgID}
+}
+
+// due dates are always stored in UTC so that range queries compare like with like,
+// the original zone is kept in DueTimezone
+func normalizeDueDate(todo *models.Todo) {
+	if todo.DueDate != nil {
+		due := todo.DueDate.UTC()
+		todo.DueDate = &due
+	}
+}
+
+// tags are only touched when the client sent tag_ids, an empty list detaches all of them
+func setTags(repo *repos.TodoRepository, todo *models.Todo) error {
+	if todo.TagIDs == nil {
+		return nil
+	}
+	err := repo.SetTodoTags(todo, todo.TagIDs)
+	todo.TagIDs = nil
+	return err
+}
+
+func idString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
 }
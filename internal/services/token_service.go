@@ -0,0 +1,116 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+// tokenPrefix starts every personal access token so they are easy to spot in scripts and logs
+const tokenPrefix = "tdl_"
+
+var (
+	// ErrInvalidTokenScope is returned for token scopes other than read and write
+	ErrInvalidTokenScope = errors.New("invalid token scope")
+	// ErrTokenName is returned when creating a token without a name
+	ErrTokenName = errors.New("token name is required")
+	// ErrTokenExpiry is returned when creating a token that expires in the past
+	ErrTokenExpiry = errors.New("token expiry must be in the future")
+	// ErrInvalidToken is returned for unknown and expired tokens and the tokens of disabled users
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+type TokenService struct {
+	repo     *repos.TokenRepository
+	userRepo *repos.UserRepository
+}
+
+// the constructor for TokenService
+
+func NewTokenService(repo *repos.TokenRepository, userRepo *repos.UserRepository) *TokenService {
+	return &TokenService{repo, userRepo}
+}
+
+func (s *TokenService) GetTokens(userId uint) ([]models.APIToken, error) {
+	return s.repo.GetTokens(userId)
+}
+
+// CreateToken generates a new token for a user. The returned token carries the token
+// itself in Token, which is the only time it is available since only its hash is saved.
+func (s *TokenService) CreateToken(userId uint, name, scope string, expiresAt *time.Time) (models.APIToken, error) {
+	token := models.APIToken{UserID: userId, Name: strings.TrimSpace(name), Scope: scope, ExpiresAt: expiresAt}
+	if token.Name == "" {
+		return token, ErrTokenName
+	}
+	if token.Scope == "" {
+		token.Scope = models.TokenRead
+	}
+	if token.Scope != models.TokenRead && token.Scope != models.TokenWrite {
+		return token, ErrInvalidTokenScope
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return token, ErrTokenExpiry
+	}
+
+	random, err := randomHex(32)
+	if err != nil {
+		return token, err
+	}
+	raw := tokenPrefix + random
+	token.Prefix = raw[:len(tokenPrefix)+8]
+	token.Hash = hashToken(raw)
+	if err := s.repo.CreateToken(&token); err != nil {
+		return token, err
+	}
+	token.Token = raw
+	return token, nil
+}
+
+// DeleteToken revokes a token of a user, gorm.ErrRecordNotFound if the user has no such token
+func (s *TokenService) DeleteToken(userId uint, id string) error {
+	return s.repo.DeleteToken(userId, id)
+}
+
+// IsPersonalToken tells personal access tokens apart from the access tokens of the jwt auth mode
+func IsPersonalToken(raw string) bool {
+	return strings.HasPrefix(raw, tokenPrefix)
+}
+
+// Authenticate looks up the token sent by a client and records that it was used.
+// ErrInvalidToken when the token is unknown, expired or its user is disabled.
+func (s *TokenService) Authenticate(raw string) (models.APIToken, error) {
+	token, err := s.repo.GetTokenByHash(hashToken(raw))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return token, ErrInvalidToken
+	}
+	if err != nil {
+		return token, err
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return token, ErrInvalidToken
+	}
+	var user models.User
+	if err := s.userRepo.GetUserByID(token.UserID, &user); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return token, ErrInvalidToken
+		}
+		return token, err
+	}
+	if user.DisabledAt != nil {
+		return token, ErrInvalidToken
+	}
+	return token, s.repo.TouchToken(&token, now)
+}
+
+// hashToken is how tokens are stored, they are random enough that a plain SHA-256 will do
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
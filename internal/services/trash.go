@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+// GetTrash returns the trashed todos of a scope, most recently deleted first
+func (s *TodoService) GetTrash(scope repos.Scope) ([]models.Todo, error) {
+	return s.repo.GetTrash(scope)
+}
+
+func (s *TodoService) GetTrashedTodo(id string) (models.Todo, error) {
+	return s.repo.GetTrashedTodo(id)
+}
+
+// RestoreTodo takes a todo out of the trash together with the subtasks that were
+// deleted with it. A todo whose parent is still trashed or gone becomes a top level
+// todo again.
+func (s *TodoService) RestoreTodo(actorID uint, todo *models.Todo) error {
+	if todo.ParentID != nil {
+		if _, err := s.repo.GetTodo(idString(*todo.ParentID)); errors.Is(err, gorm.ErrRecordNotFound) {
+			todo.ParentID = nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return s.undoable(actorID, func(tx *TodoService) (string, error) {
+		ids, err := trashedWith(tx.repo, todo)
+		if err != nil {
+			return "", err
+		}
+		if err := tx.repo.RestoreTodos(ids); err != nil {
+			return "", err
+		}
+		todo.DeletedAt = gorm.DeletedAt{}
+		return models.HistoryRestored, tx.repo.UpdateTodo(todo)
+	})
+}
+
+// PurgeTodo permanently deletes a trashed todo together with the subtasks deleted with it
+func (s *TodoService) PurgeTodo(actorID uint, todo *models.Todo) error {
+	return s.repo.WithActor(actorID).Transaction(func(repo *repos.TodoRepository) error {
+		ids, err := trashedWith(repo, todo)
+		if err != nil {
+			return err
+		}
+		return repo.PurgeTodos(ids)
+	})
+}
+
+// trashedWith returns the ids of a trashed todo and of the subtasks that were deleted
+// together with it. Subtasks deleted on their own before it stay in the trash.
+func trashedWith(repo *repos.TodoRepository, todo *models.Todo) ([]uint, error) {
+	ids := []uint{todo.ID}
+	level := []uint{todo.ID}
+	for len(level) > 0 {
+		children, err := repo.GetTrashedChildren(level)
+		if err != nil {
+			return nil, err
+		}
+		level = nil
+		for _, child := range children {
+			if !child.DeletedAt.Time.Before(todo.DeletedAt.Time) {
+				ids = append(ids, child.ID)
+				level = append(level, child.ID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// PurgeTrash permanently deletes the todos trashed before the given time
+// and returns how many were deleted
+func (s *TodoService) PurgeTrash(before time.Time) (int, error) {
+	ids, err := s.repo.ExpiredTrash(before)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return len(ids), s.repo.PurgeTodos(ids)
+}
+
+// RunTrashPurge purges todos that have been in the trash for longer than retention
+// every interval until ctx is done
+func (s *TodoService) RunTrashPurge(ctx context.Context, retention, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		purged, err := s.PurgeTrash(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("failed to purge the trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d todos from the trash", purged)
+		}
+	})
+}
+
+// runEvery calls fn right away and then every interval until ctx is done
+func runEvery(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fn()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/pkg/totp"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at a time
+const recoveryCodeCount = 10
+
+const (
+	// maxLoginFailures is how many wrong codes in a row lock the two-factor logins of a user
+	maxLoginFailures = 5
+	// loginLockout is how long codes are refused after too many wrong ones
+	loginLockout = 15 * time.Minute
+)
+
+var (
+	// ErrTwoFactorEnabled is returned when enrolling while two-factor authentication is on already
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is enabled already")
+	// ErrTwoFactorNotEnrolled is returned when enabling before an enrollment was started
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication enrollment was not started")
+	// ErrTwoFactorNotEnabled is returned when changing two-factor authentication that is off
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrInvalidCode is returned for wrong, expired and reused codes
+	ErrInvalidCode = errors.New("invalid code")
+	// ErrTwoFactorLocked is returned while a user is locked out after too many wrong codes
+	ErrTwoFactorLocked = errors.New("too many wrong codes")
+)
+
+// Enrollment is what a user adds to their authenticator app, uri is the payload of the QR code
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorService struct {
+	repo   *repos.TwoFactorRepository
+	issuer string
+}
+
+// the constructor for TwoFactorService
+
+func NewTwoFactorService(repo *repos.TwoFactorRepository, issuer string) *TwoFactorService {
+	return &TwoFactorService{repo, issuer}
+}
+
+// Enroll creates a new TOTP secret for a user. Two-factor authentication is only
+// turned on once Enable confirmed that the authenticator app generates the right codes.
+func (s *TwoFactorService) Enroll(user *models.User) (Enrollment, error) {
+	if user.TOTPEnabled {
+		return Enrollment{}, ErrTwoFactorEnabled
+	}
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return Enrollment{}, err
+	}
+	if err := s.repo.SetSecret(user, secret); err != nil {
+		return Enrollment{}, err
+	}
+	return Enrollment{Secret: secret, URI: totp.URI(s.issuer, user.Username, secret)}, nil
+}
+
+// Enable turns on two-factor authentication with the first code of the authenticator
+// app and returns the recovery codes, which are not shown again
+func (s *TwoFactorService) Enable(user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err := s.checkTOTP(user, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	return codes, s.repo.Enable(user, hashes)
+}
+
+// Disable turns off two-factor authentication, confirmed by a code or recovery code
+func (s *TwoFactorService) Disable(user *models.User, code string) error {
+	if err := s.Verify(user, code); err != nil {
+		return err
+	}
+	return s.repo.Disable(user)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user, confirmed by a code or recovery code
+func (s *TwoFactorService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	if err := s.Verify(user, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	return codes, s.repo.ReplaceRecoveryCodes(user.ID, hashes)
+}
+
+// RemainingRecoveryCodes returns how many unused recovery codes a user has
+func (s *TwoFactorService) RemainingRecoveryCodes(user *models.User) (int64, error) {
+	return s.repo.CountRecoveryCodes(user.ID)
+}
+
+// Verify checks the second factor of a user with two-factor authentication enabled. code
+// is either the current code of the authenticator app or an unused recovery code. Every
+// code works once.
+func (s *TwoFactorService) Verify(user *models.User, code string) error {
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	code = strings.TrimSpace(code)
+	if len(strings.ReplaceAll(code, " ", "")) == totp.Digits {
+		return s.checkTOTP(user, code)
+	}
+	used, err := s.repo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// VerifyLogin is Verify for logins. The wrong codes are counted per user across all
+// logins, after maxLoginFailures wrong codes in a row every code is refused for loginLockout.
+func (s *TwoFactorService) VerifyLogin(user *models.User, code string) error {
+	if user.TOTPLockedUntil != nil && time.Now().Before(*user.TOTPLockedUntil) {
+		return ErrTwoFactorLocked
+	}
+	err := s.Verify(user, code)
+	if errors.Is(err, ErrInvalidCode) {
+		if err := s.repo.RecordFailure(user, maxLoginFailures, time.Now().Add(loginLockout)); err != nil {
+			return err
+		}
+		return ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.ResetFailures(user)
+}
+
+// checkTOTP accepts the code of the current time step and the steps next to it, and
+// refuses codes of a step that was used already
+func (s *TwoFactorService) checkTOTP(user *models.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), 1)
+	if !ok {
+		return ErrInvalidCode
+	}
+	fresh, err := s.repo.UseStep(user, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// newRecoveryCodes returns codes like "3f9a-0c4e-b812-77d1" and their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		random, err := randomHex(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = random[0:4] + "-" + random[4:8] + "-" + random[8:12] + "-" + random[12:16]
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+
+	"gorm.io/gorm"
+)
+
+// UndoStackSize is the number of changes per user that can be undone
+const UndoStackSize = 50
+
+var (
+	// ErrNothingToUndo is returned when the undo or redo stack of a user is empty
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrUndoConflict is returned when a todo of the change has been purged since,
+	// the change is dropped from the stack
+	ErrUndoConflict = errors.New("a todo of the change was permanently deleted")
+	// ErrUndoStale is returned when a todo of the change has been changed since it was
+	// applied, the change is dropped from the stack instead of overwriting the newer state
+	ErrUndoStale = errors.New("a todo of the change was changed since")
+	// ErrUndoForbidden is returned when the user may no longer edit a todo of the change,
+	// the change is dropped from the stack
+	ErrUndoForbidden = errors.New("not allowed to change a todo of the change")
+)
+
+// UndoResult describes an undone or redone change and the todos it affected, in their new state
+type UndoResult struct {
+	Action string        `json:"action"`
+	Todos  []models.Todo `json:"todos"`
+}
+
+// undoable runs a change of the user in a transaction and puts every todo it touched
+// on the undo stack as a single entry. fn gets a service bound to the transaction and
+// returns the action the entry is recorded as.
+func (s *TodoService) undoable(actorID uint, fn func(tx *TodoService) (string, error)) error {
+	return s.repo.WithActor(actorID).TrackChanges().Transaction(func(repo *repos.TodoRepository) error {
+		action, err := fn(s.withRepo(repo))
+		if err != nil {
+			return err
+		}
+		before, ids := repo.Changes()
+		if len(ids) == 0 {
+			return nil
+		}
+		after, err := repo.Snapshots(ids)
+		if err != nil {
+			return err
+		}
+
+		beforeJSON, err := json.Marshal(before)
+		if err != nil {
+			return err
+		}
+		afterJSON, err := json.Marshal(after)
+		if err != nil {
+			return err
+		}
+		entry := models.UndoEntry{UserID: actorID, Action: action, Before: string(beforeJSON), After: string(afterJSON)}
+		return repo.PushUndo(&entry, UndoStackSize)
+	})
+}
+
+// Undo reverts the most recent change of a user that has not been undone yet.
+// The user needs edit permission on every todo of the change.
+func (s *TodoService) Undo(userId uint, shares *ShareService) (UndoResult, error) {
+	return s.replay(userId, shares, true)
+}
+
+// Redo applies the change of a user that was undone last again.
+// The user needs edit permission on every todo of the change.
+func (s *TodoService) Redo(userId uint, shares *ShareService) (UndoResult, error) {
+	return s.replay(userId, shares, false)
+}
+
+func (s *TodoService) replay(userId uint, shares *ShareService, undo bool) (UndoResult, error) {
+	var result UndoResult
+	var entry models.UndoEntry
+	var err error
+	if undo {
+		entry, err = s.repo.LastUndo(userId)
+	} else {
+		entry, err = s.repo.LastRedo(userId)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return result, ErrNothingToUndo
+	}
+	if err != nil {
+		return result, err
+	}
+
+	var before, after []models.Todo
+	if err := json.Unmarshal([]byte(entry.Before), &before); err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal([]byte(entry.After), &after); err != nil {
+		return result, err
+	}
+
+	// the todos as the change left them and as undoing it leaves them,
+	// todos created by the change go back to the trash
+	existed := make(map[uint]models.Todo, len(before))
+	for _, todo := range before {
+		existed[todo.ID] = todo
+	}
+	reverted := make([]models.Todo, len(after))
+	for i, todo := range after {
+		if previous, ok := existed[todo.ID]; ok {
+			reverted[i] = previous
+		} else {
+			reverted[i] = todo
+			reverted[i].DeletedAt = gorm.DeletedAt{Time: todo.UpdatedAt, Valid: true}
+		}
+	}
+	expected, target := reverted, after
+	if undo {
+		expected, target = after, reverted
+	}
+
+	err = s.repo.WithActor(userId).Transaction(func(repo *repos.TodoRepository) error {
+		ids := make([]uint, len(after))
+		for i, todo := range after {
+			ids[i] = todo.ID
+		}
+		current, err := repo.Snapshots(ids)
+		if err != nil {
+			return err
+		}
+		if len(current) != len(ids) {
+			return ErrUndoConflict
+		}
+		for i := range current {
+			permission, err := shares.TodoPermission(userId, &current[i])
+			if err != nil {
+				return err
+			}
+			if permission < PermissionEdit {
+				return ErrUndoForbidden
+			}
+			if !sameState(current[i], expected[i]) {
+				return ErrUndoStale
+			}
+		}
+
+		tx := s.withRepo(repo)
+		for i := range target {
+			if err := tx.applySnapshot(current[i], target[i]); err != nil {
+				return err
+			}
+		}
+		// completed todos must not wait on open ones, checked once the whole change is back
+		// since it may complete blockers and the todos they block together
+		for i := range target {
+			if target[i].IsCompleted && !current[i].IsCompleted && !target[i].DeletedAt.Valid {
+				if err := tx.checkBlockers(&target[i]); err != nil {
+					return err
+				}
+			}
+		}
+		if err := repo.SetUndone(&entry, undo); err != nil {
+			return err
+		}
+
+		result.Action = entry.Action
+		result.Todos, err = repo.Snapshots(ids)
+		return err
+	})
+	if errors.Is(err, ErrUndoConflict) || errors.Is(err, ErrUndoStale) || errors.Is(err, ErrUndoForbidden) {
+		if err := s.repo.DeleteUndo(&entry); err != nil {
+			return result, err
+		}
+	}
+	return result, err
+}
+
+// applySnapshot writes a saved version of a todo back, moving it in or out of the trash as needed.
+// Status changes have to be allowed by the workflow like any other edit.
+func (s *TodoService) applySnapshot(current, snapshot models.Todo) error {
+	repo := s.repo
+	if err := resolveStatus(repo, &snapshot, &current); err != nil {
+		return err
+	}
+
+	trashed := snapshot.DeletedAt.Valid
+	if !trashed && current.DeletedAt.Valid {
+		if err := repo.RestoreTodos([]uint{current.ID}); err != nil {
+			return err
+		}
+	}
+
+	tagIDs := make([]uint, len(snapshot.Tags))
+	for i, tag := range snapshot.Tags {
+		tagIDs[i] = tag.ID
+	}
+	// tags deleted in the meantime cannot come back
+	tagIDs, err := repo.OwnedTagIDs(snapshot.UserID, tagIDs)
+	if err != nil {
+		return err
+	}
+
+	snapshot.Tags = nil
+	snapshot.DeletedAt = gorm.DeletedAt{}
+	if trashed && current.DeletedAt.Valid {
+		snapshot.DeletedAt = current.DeletedAt
+	}
+	if err := repo.UpdateTodo(&snapshot); err != nil {
+		return err
+	}
+	if err := repo.SetTodoTags(&snapshot, tagIDs); err != nil {
+		return err
+	}
+
+	if trashed && !current.DeletedAt.Valid {
+		return repo.TrashTodos([]uint{current.ID})
+	}
+	return nil
+}
+
+// sameState reports whether a todo is still in a saved state. Timestamps and positions,
+// which change without the todo being edited, only count as far as the trash goes.
+func sameState(current, snapshot models.Todo) bool {
+	key := func(todo models.Todo) string {
+		tagIDs := make([]uint, len(todo.Tags))
+		for i, tag := range todo.Tags {
+			tagIDs[i] = tag.ID
+		}
+		sort.Slice(tagIDs, func(i, j int) bool { return tagIDs[i] < tagIDs[j] })
+		todo.Tags = nil
+		todo.TagIDs = tagIDs
+		todo.Position = ""
+		todo.CreatedAt = todo.CreatedAt.UTC()
+		todo.UpdatedAt = time.Time{}
+		todo.DeletedAt = gorm.DeletedAt{Valid: todo.DeletedAt.Valid}
+		if todo.DueDate != nil {
+			due := todo.DueDate.UTC()
+			todo.DueDate = &due
+		}
+		data, _ := json.Marshal(todo)
+		return string(data)
+	}
+	return key(current) == key(snapshot)
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+)
+
+var (
+	// ErrUnknownStatus is returned when a todo is given a status that is not part of its workflow
+	ErrUnknownStatus = errors.New("unknown status")
+	// ErrInvalidTransition is returned when the workflow does not allow moving a todo to a status
+	ErrInvalidTransition = errors.New("transition not allowed")
+	// ErrInvalidWorkflow wraps the reason a workflow was rejected
+	ErrInvalidWorkflow = errors.New("invalid workflow")
+	// ErrStatusInUse is returned when removing a status that todos still use
+	ErrStatusInUse = errors.New("status is still in use")
+)
+
+type WorkflowService struct {
+	repo     *repos.WorkflowRepository
+	todoRepo *repos.TodoRepository
+}
+
+// the constructor for WorkflowService
+
+func NewWorkflowService(repo *repos.WorkflowRepository, todoRepo *repos.TodoRepository) *WorkflowService {
+	return &WorkflowService{repo, todoRepo}
+}
+
+// Board is the todos of a list, or of every list using the default workflow, grouped by status
+type Board struct {
+	ListID  *uint         `json:"list_id"`
+	Columns []BoardColumn `json:"columns"`
+}
+
+type BoardColumn struct {
+	Status models.WorkflowStatus `json:"status"`
+	Todos  []models.Todo         `json:"todos"`
+}
+
+// GetWorkflow returns the workflow used by a list, or the default workflow of the user
+// when listId is nil or the list has none of its own
+func (s *WorkflowService) GetWorkflow(userId uint, listId *uint) ([]models.WorkflowStatus, error) {
+	return workflowFor(s.repo, userId, listId)
+}
+
+// SaveWorkflow replaces the default workflow of a user, or gives a list its own.
+// Statuses are matched to the current ones by id, then by name, so todos keep their
+// status. Removing a status that todos still use fails with ErrStatusInUse.
+func (s *WorkflowService) SaveWorkflow(userId uint, listId *uint, statuses []models.WorkflowStatus) error {
+	if err := validateWorkflow(statuses); err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(repo *repos.WorkflowRepository) error {
+		if listId == nil {
+			// make sure the default exists so renamed statuses keep their todos
+			if _, err := workflowFor(repo, userId, nil); err != nil {
+				return err
+			}
+		}
+		existing, err := repo.GetStatuses(userId, listId)
+		if err != nil {
+			return err
+		}
+		if listId != nil && len(existing) == 0 {
+			return s.createListWorkflow(repo, userId, *listId, statuses)
+		}
+
+		byID := make(map[uint]bool, len(existing))
+		byName := make(map[string]uint, len(existing))
+		for _, status := range existing {
+			byID[status.ID] = true
+			byName[status.Name] = status.ID
+		}
+		kept := make(map[uint]bool, len(statuses))
+		for i := range statuses {
+			if !byID[statuses[i].ID] {
+				statuses[i].ID = byName[statuses[i].Name]
+			}
+			kept[statuses[i].ID] = true
+		}
+		var removed []uint
+		for _, status := range existing {
+			if !kept[status.ID] {
+				removed = append(removed, status.ID)
+			}
+		}
+
+		inUse, err := repo.CountTodosWithStatus(removed)
+		if err != nil {
+			return err
+		}
+		if inUse > 0 {
+			return ErrStatusInUse
+		}
+		if err := repo.ClearTrashedStatuses(removed); err != nil {
+			return err
+		}
+		return repo.SaveStatuses(userId, listId, statuses)
+	})
+}
+
+// createListWorkflow gives a list its own workflow. The todos of the list keep a status
+// with the same name, the others get one derived from is_completed.
+func (s *WorkflowService) createListWorkflow(repo *repos.WorkflowRepository, userId, listId uint, statuses []models.WorkflowStatus) error {
+	previous, err := workflowFor(repo, userId, nil)
+	if err != nil {
+		return err
+	}
+	for i := range statuses {
+		statuses[i].ID = 0
+	}
+	if err := repo.SaveStatuses(userId, &listId, statuses); err != nil {
+		return err
+	}
+
+	todos, err := repo.BoardTodos(userId, &listId)
+	if err != nil {
+		return err
+	}
+	remapped := make(map[uint]uint, len(todos))
+	for _, todo := range todos {
+		status := findStatus(statuses, todo.StatusID)
+		if old := findStatus(previous, todo.StatusID); old != nil {
+			status = statusNamed(statuses, old.Name)
+		}
+		if status == nil {
+			status = derivedStatus(statuses, todo.IsCompleted)
+		}
+		remapped[todo.ID] = status.ID
+	}
+	return repo.SetStatuses(remapped)
+}
+
+// GetBoard groups todos by the statuses of their workflow, each column in manual order.
+// Todos whose status is not part of the workflow are shown where is_completed puts them.
+func (s *WorkflowService) GetBoard(userId uint, listId *uint) (Board, error) {
+	board := Board{ListID: listId}
+	statuses, err := workflowFor(s.repo, userId, listId)
+	if err != nil {
+		return board, err
+	}
+	todos, err := s.repo.BoardTodos(userId, listId)
+	if err != nil {
+		return board, err
+	}
+	if err := (&TodoService{repo: s.todoRepo}).attachProgress(todos); err != nil {
+		return board, err
+	}
+
+	board.Columns = make([]BoardColumn, len(statuses))
+	column := make(map[uint]int, len(statuses))
+	for i, status := range statuses {
+		board.Columns[i] = BoardColumn{Status: status, Todos: []models.Todo{}}
+		column[status.ID] = i
+	}
+	for _, todo := range todos {
+		status := findStatus(statuses, todo.StatusID)
+		if status == nil {
+			status = derivedStatus(statuses, todo.IsCompleted)
+		}
+		i := column[status.ID]
+		board.Columns[i].Todos = append(board.Columns[i].Todos, todo)
+	}
+	return board, nil
+}
+
+// validateWorkflow checks the statuses sent by a client before they are saved
+func validateWorkflow(statuses []models.WorkflowStatus) error {
+	names := make(map[string]bool, len(statuses))
+	terminal, open := false, false
+	for i := range statuses {
+		statuses[i].Name = strings.TrimSpace(statuses[i].Name)
+		name := statuses[i].Name
+		if name == "" {
+			return fmt.Errorf("%w: every status needs a name", ErrInvalidWorkflow)
+		}
+		if names[name] {
+			return fmt.Errorf("%w: duplicate status %q", ErrInvalidWorkflow, name)
+		}
+		names[name] = true
+		if statuses[i].IsTerminal {
+			terminal = true
+		} else {
+			open = true
+		}
+	}
+	if !terminal || !open {
+		return fmt.Errorf("%w: at least one terminal and one non-terminal status are required", ErrInvalidWorkflow)
+	}
+	for _, status := range statuses {
+		for _, to := range status.Transitions {
+			if !names[to] {
+				return fmt.Errorf("%w: transition from %q to unknown status %q", ErrInvalidWorkflow, status.Name, to)
+			}
+		}
+	}
+	return nil
+}
+
+// workflowFor returns the statuses of the workflow of a list, falling back to the
+// default workflow of the user, which is created on first use
+func workflowFor(repo *repos.WorkflowRepository, userId uint, listId *uint) ([]models.WorkflowStatus, error) {
+	if listId != nil {
+		statuses, err := repo.GetStatuses(userId, listId)
+		if err != nil || len(statuses) > 0 {
+			return statuses, err
+		}
+	}
+	statuses, err := repo.GetStatuses(userId, nil)
+	if err != nil || len(statuses) > 0 {
+		return statuses, err
+	}
+
+	// every status of the default workflow can move to every other one
+	statuses = make([]models.WorkflowStatus, len(models.DefaultWorkflow))
+	for i, name := range models.DefaultWorkflow {
+		statuses[i] = models.WorkflowStatus{Name: name, IsTerminal: i == len(models.DefaultWorkflow)-1}
+		for _, other := range models.DefaultWorkflow {
+			if other != name {
+				statuses[i].Transitions = append(statuses[i].Transitions, other)
+			}
+		}
+	}
+	if err := repo.SaveStatuses(userId, nil, statuses); err != nil {
+		return nil, err
+	}
+	return repo.GetStatuses(userId, nil)
+}
+
+// resolveStatus keeps the status and is_completed of a todo in line. An explicit status
+// change must be allowed by the workflow and decides is_completed. Otherwise toggling
+// is_completed picks the first matching status the workflow allows moving to, and moving
+// to a list with another workflow a status of the same name. previous is nil for new todos.
+func resolveStatus(repo *repos.TodoRepository, todo, previous *models.Todo) error {
+	owner := todo.UserID
+	if todo.OrgID != nil && todo.ListID != nil {
+		// the members of an organization share the workflows of its lists, kept by their creator
+		list, err := repo.Lists().GetList(idString(*todo.ListID))
+		if err != nil {
+			return err
+		}
+		owner = list.UserID
+	}
+	statuses, err := workflowFor(repo.Workflows(), owner, todo.ListID)
+	if err != nil {
+		return err
+	}
+	status := findStatus(statuses, todo.StatusID)
+
+	explicit := todo.StatusID != nil && (previous == nil || previous.StatusID == nil || *previous.StatusID != *todo.StatusID)
+	switch {
+	case explicit && status == nil:
+		return ErrUnknownStatus
+	case explicit && previous != nil:
+		if from := findStatus(statuses, previous.StatusID); from != nil && !allowsTransition(from, status.Name) {
+			return ErrInvalidTransition
+		}
+	case status == nil && todo.StatusID != nil:
+		// the todo moved to a list with another workflow, keep a status of the same name
+		old, err := repo.Workflows().GetStatus(*todo.StatusID)
+		if err == nil {
+			status = statusNamed(statuses, old.Name)
+		}
+	}
+	switch {
+	case status == nil || (previous == nil && status.IsTerminal != todo.IsCompleted):
+		status = derivedStatus(statuses, todo.IsCompleted)
+	case !explicit && status.IsTerminal != todo.IsCompleted:
+		// toggling is_completed is a status change the workflow has to allow as well
+		if status = reachableStatus(statuses, status, todo.IsCompleted); status == nil {
+			return ErrInvalidTransition
+		}
+	}
+
+	todo.StatusID = &status.ID
+	todo.IsCompleted = status.IsTerminal
+	return nil
+}
+
+// derivedStatus is the status a todo gets from is_completed alone, the first
+// terminal status or the first status of the workflow
+func derivedStatus(statuses []models.WorkflowStatus, completed bool) *models.WorkflowStatus {
+	for i := range statuses {
+		if statuses[i].IsTerminal == completed {
+			return &statuses[i]
+		}
+	}
+	return &statuses[0]
+}
+
+// reachableStatus is the first status of the workflow with the given completed state that
+// from may move to, nil when there is none
+func reachableStatus(statuses []models.WorkflowStatus, from *models.WorkflowStatus, completed bool) *models.WorkflowStatus {
+	for i := range statuses {
+		if statuses[i].IsTerminal == completed && allowsTransition(from, statuses[i].Name) {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+func findStatus(statuses []models.WorkflowStatus, id *uint) *models.WorkflowStatus {
+	if id == nil {
+		return nil
+	}
+	for i := range statuses {
+		if statuses[i].ID == *id {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+func statusNamed(statuses []models.WorkflowStatus, name string) *models.WorkflowStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+func allowsTransition(from *models.WorkflowStatus, to string) bool {
+	if from.Name == to {
+		return true
+	}
+	for _, name := range from.Transitions {
+		if name == to {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"todo-list/internal/models"
+	"todo-list/internal/repos"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -32,17 +33,45 @@ func InitDB() *gorm.DB {
 	}
 
 	// Run migrations
-	err = db.AutoMigrate(
+	if err := Migrate(db); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	return db
+}
+
+// Migrate creates or updates the tables for all models and the full-text
+// search indexes, it is shared with the e2e tests so both use the same schema
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
 		&models.User{},
+		&models.Tag{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.Invitation{},
+		&models.List{},
+		&models.WorkflowStatus{},
+		&models.WorkflowTransition{},
 		&models.Todo{},
+		&models.TodoDependency{},
+		&models.Comment{},
+		&models.Attachment{},
+		&models.Share{},
+		&models.Notification{},
+		&models.TodoHistory{},
+		&models.UndoEntry{},
+		&models.APIToken{},
+		&models.RefreshToken{},
+		&models.RecoveryCode{},
 		&models.Session{},
 	)
-
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		return err
 	}
-
-	return db
+	if err := repos.MigratePositions(db); err != nil {
+		return err
+	}
+	return repos.MigrateSearch(db)
 }
 
 func CloseDB(db *gorm.DB) {
@@ -78,3 +78,18 @@ func (s *GORMStore) Delete(key string) error {
 	// If the token does not exist, it's a no-op, so return nil
 	return nil
 }
+
+// All returns the data of every session that has not expired, keyed by token.
+// It makes the store an scs.IterableStore so the sessions of a user can be ended.
+func (s *GORMStore) All() (map[string][]byte, error) {
+	var sessions []models.Session
+	if err := s.db.Where("expiry > ?", time.Now()).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	all := make(map[string][]byte, len(sessions))
+	for _, session := range sessions {
+		all[session.Token] = session.Data
+	}
+	return all, nil
+}
@@ -0,0 +1,163 @@
+// Package jwt signs and verifies the compact JSON Web Tokens used as access tokens.
+// It supports HS256 and EdDSA (Ed25519) and picks the verification key by the "kid"
+// header, so new signing keys can be rolled out while tokens of older keys stay valid.
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Algorithms a Key can sign with
+const (
+	HS256 = "HS256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrMalformed  = errors.New("jwt: malformed token")
+	ErrUnknownKey = errors.New("jwt: unknown key")
+	ErrSignature  = errors.New("jwt: invalid signature")
+	ErrExpired    = errors.New("jwt: token expired")
+)
+
+// Key signs and verifies tokens with one algorithm, ID is sent as the "kid" header
+type Key struct {
+	ID        string
+	Algorithm string
+	secret    []byte
+	private   ed25519.PrivateKey
+	public    ed25519.PublicKey
+}
+
+// NewHS256Key returns a key for HMAC-SHA256, the secret needs at least 32 bytes
+func NewHS256Key(id string, secret []byte) (Key, error) {
+	if len(secret) < 32 {
+		return Key{}, fmt.Errorf("jwt: HS256 secret of key %q is shorter than 32 bytes", id)
+	}
+	return Key{ID: id, Algorithm: HS256, secret: secret}, nil
+}
+
+// NewEdDSAKey returns a key for Ed25519 signatures from the 32 byte seed of the private key
+func NewEdDSAKey(id string, seed []byte) (Key, error) {
+	if len(seed) != ed25519.SeedSize {
+		return Key{}, fmt.Errorf("jwt: EdDSA seed of key %q must be %d bytes", id, ed25519.SeedSize)
+	}
+	private := ed25519.NewKeyFromSeed(seed)
+	return Key{ID: id, Algorithm: EdDSA, private: private, public: private.Public().(ed25519.PublicKey)}, nil
+}
+
+func (k Key) sign(data []byte) []byte {
+	if k.Algorithm == EdDSA {
+		return ed25519.Sign(k.private, data)
+	}
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (k Key) verify(data, signature []byte) bool {
+	if k.Algorithm == EdDSA {
+		return ed25519.Verify(k.public, data, signature)
+	}
+	return hmac.Equal(k.sign(data), signature)
+}
+
+// Claims are the registered claims of an access token
+type Claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// KeySet signs with its current key and verifies with any of its keys
+type KeySet struct {
+	current Key
+	keys    map[string]Key
+}
+
+// NewKeySet returns a key set that signs with current. The previous keys are only used
+// to verify tokens that were signed before current was rolled out.
+func NewKeySet(current Key, previous ...Key) *KeySet {
+	keys := map[string]Key{current.ID: current}
+	for _, key := range previous {
+		if _, ok := keys[key.ID]; !ok {
+			keys[key.ID] = key
+		}
+	}
+	return &KeySet{current, keys}
+}
+
+// Sign returns the compact serialization of a token with the given claims
+func (s *KeySet) Sign(claims Claims) (string, error) {
+	head, err := json.Marshal(header{s.current.Algorithm, "JWT", s.current.ID})
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := encode(head) + "." + encode(body)
+	return unsigned + "." + encode(s.current.sign([]byte(unsigned))), nil
+}
+
+// Verify checks the signature and expiry of a token and returns its claims. The
+// algorithm in the header has to match the key of its kid.
+func (s *KeySet) Verify(token string, now time.Time) (Claims, error) {
+	var claims Claims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, ErrMalformed
+	}
+	var head header
+	if err := decodeJSON(parts[0], &head); err != nil {
+		return claims, err
+	}
+	key, ok := s.keys[head.KeyID]
+	if !ok {
+		return claims, ErrUnknownKey
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, ErrMalformed
+	}
+	if head.Algorithm != key.Algorithm || !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return claims, ErrSignature
+	}
+	if err := decodeJSON(parts[1], &claims); err != nil {
+		return claims, err
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return claims, ErrExpired
+	}
+	return claims, nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeJSON(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
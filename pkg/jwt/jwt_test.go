@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustKey(key Key, err error) Key {
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := Claims{Subject: "42", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}
+	keys := []Key{
+		mustKey(NewHS256Key("h1", bytes.Repeat([]byte("s"), 32))),
+		mustKey(NewEdDSAKey("e1", bytes.Repeat([]byte{7}, 32))),
+	}
+	for _, key := range keys {
+		set := NewKeySet(key)
+		token, err := set.Sign(claims)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		got, err := set.Verify(token, now)
+		assert.NoError(t, err, key.Algorithm)
+		assert.Equal(t, claims, got, key.Algorithm)
+
+		_, err = set.Verify(token, now.Add(time.Minute))
+		assert.ErrorIs(t, err, ErrExpired, key.Algorithm)
+		parts := strings.Split(token, ".")
+		tampered := parts[0] + "." + encode([]byte(`{"sub":"1","exp":9999999999}`)) + "." + parts[2]
+		_, err = set.Verify(tampered, now)
+		assert.ErrorIs(t, err, ErrSignature, key.Algorithm)
+		_, err = set.Verify("a.b", now)
+		assert.ErrorIs(t, err, ErrMalformed, key.Algorithm)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := Claims{Subject: "1", ExpiresAt: now.Add(time.Minute).Unix()}
+	old := mustKey(NewHS256Key("2024", bytes.Repeat([]byte("o"), 32)))
+	next := mustKey(NewEdDSAKey("2025", bytes.Repeat([]byte{1}, 32)))
+
+	token, _ := NewKeySet(old).Sign(claims)
+	rotated := NewKeySet(next, old)
+	_, err := rotated.Verify(token, now)
+	assert.NoError(t, err, "tokens of the previous key stay valid")
+	newToken, _ := rotated.Sign(claims)
+	assert.Contains(t, decodeHeader(t, newToken), `"kid":"2025"`)
+
+	_, err = NewKeySet(next).Verify(token, now)
+	assert.ErrorIs(t, err, ErrUnknownKey, "retired keys are rejected")
+}
+
+func TestAlgorithmMustMatchKey(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	secret := bytes.Repeat([]byte{1}, 32)
+	ed := mustKey(NewEdDSAKey("k", secret))
+	// an HS256 token signed with the seed must not pass as the EdDSA key of the same kid
+	hs := mustKey(NewHS256Key("k", secret))
+	token, _ := NewKeySet(hs).Sign(Claims{Subject: "1", ExpiresAt: now.Add(time.Minute).Unix()})
+	_, err := NewKeySet(ed).Verify(token, now)
+	assert.ErrorIs(t, err, ErrSignature)
+
+	_, err = NewHS256Key("short", []byte("too short"))
+	assert.Error(t, err)
+}
+
+func decodeHeader(t *testing.T, token string) string {
+	data, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// received is what the stand-in SMTP server got in one session
+type received struct {
+	from, to, data string
+}
+
+// standInSMTP accepts one SMTP session on a local port and reports what it received
+func standInSMTP(t *testing.T) (string, <-chan received) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	result := make(chan received, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var got received
+		reply("220 stand-in ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 stand-in")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				got.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				got.to = strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>")
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				got.data = data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				result <- got
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), result
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, result := standInSMTP(t)
+	mailer := NewSMTPMailer(SMTPConfig{Addr: addr, From: "todo@example.com"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := mailer.Send(ctx, Message{To: "alice@example.com", Subject: "Reset your password", Body: "Line one\nLine two"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	select {
+	case got := <-result:
+		assert.Equal(t, "todo@example.com", got.from)
+		assert.Equal(t, "alice@example.com", got.to)
+		assert.Contains(t, got.data, "Subject: Reset your password\r\n")
+		assert.Contains(t, got.data, "To: alice@example.com\r\n")
+		assert.True(t, strings.HasSuffix(got.data, "\r\n\r\nLine one\r\nLine two\r\n"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stand-in server received no mail")
+	}
+}
+
+func TestHeaderInjectionIsRejected(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer(dir, "todo@example.com")
+	err := mailer.Send(context.Background(), Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hi"})
+	assert.Error(t, err)
+	files, _ := os.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := NewFileMailer(dir, "todo@example.com")
+	assert.NoError(t, mailer.Send(context.Background(), Message{To: "bob@example.com", Subject: "Grüße", Body: "Hello"}))
+
+	files, err := os.ReadDir(dir)
+	if !assert.NoError(t, err) || !assert.Len(t, files, 1) {
+		return
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.Contains(t, string(data), "To: bob@example.com\r\n")
+	assert.Contains(t, string(data), "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n")
+	assert.True(t, strings.HasSuffix(string(data), "\r\n\r\nHello\r\n"))
+}
@@ -0,0 +1,178 @@
+// Package markdown renders the small subset of Markdown used in comments to HTML.
+// All input is escaped first, so raw HTML in the source is shown as text, and links
+// are only created for http, https and mailto URLs.
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletPattern      = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedPattern     = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	linkPattern        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongPattern      = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	emphasisPattern    = regexp.MustCompile(`\*([^*]+)\*`)
+	underscorePattern  = regexp.MustCompile(`(^|[^\w])_([^_]+)_([^\w]|$)`)
+	placeholderPattern = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// ToHTML renders Markdown to HTML. Supported are paragraphs, headings, bullet and
+// numbered lists, block quotes, fenced code blocks, inline code, bold, italics and links.
+func ToHTML(source string) string {
+	// NUL bytes delimit the placeholders of inline, so they never come from the source
+	source = strings.ReplaceAll(source, "\x00", "")
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	var out strings.Builder
+	var paragraph []string
+	list := ""
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + inline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			out.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			out.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			flushParagraph()
+			closeList()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+
+		if trimmed == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+		if m := headingPattern.FindStringSubmatch(trimmed); m != nil {
+			flushParagraph()
+			closeList()
+			out.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", len(m[1]), inline(m[2]), len(m[1])))
+			continue
+		}
+		if m := bulletPattern.FindStringSubmatch(trimmed); m != nil {
+			flushParagraph()
+			openList("ul")
+			out.WriteString("<li>" + inline(m[1]) + "</li>\n")
+			continue
+		}
+		if m := orderedPattern.FindStringSubmatch(trimmed); m != nil {
+			flushParagraph()
+			openList("ol")
+			out.WriteString("<li>" + inline(m[1]) + "</li>\n")
+			continue
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			flushParagraph()
+			closeList()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			out.WriteString("<blockquote>\n" + ToHTML(strings.Join(quote, "\n")) + "</blockquote>\n")
+			continue
+		}
+
+		closeList()
+		paragraph = append(paragraph, trimmed)
+	}
+	flushParagraph()
+	closeList()
+	return out.String()
+}
+
+// inline renders code spans, links and emphasis within a block. Code spans and links
+// are replaced by placeholders first so emphasis never reaches into them.
+func inline(text string) string {
+	var tokens []string
+	placeholder := func(rendered string) string {
+		tokens = append(tokens, rendered)
+		return fmt.Sprintf("\x00%d\x00", len(tokens)-1)
+	}
+
+	// text between backticks is code, the other parts are escaped text
+	parts := strings.Split(text, "`")
+	var b strings.Builder
+	for i, part := range parts {
+		switch {
+		case i%2 == 1 && i < len(parts)-1:
+			b.WriteString(placeholder("<code>" + html.EscapeString(part) + "</code>"))
+		case i%2 == 1:
+			// an unmatched backtick is kept as it is
+			b.WriteString(html.EscapeString("`" + part))
+		default:
+			b.WriteString(html.EscapeString(part))
+		}
+	}
+	escaped := b.String()
+
+	escaped = linkPattern.ReplaceAllStringFunc(escaped, func(match string) string {
+		m := linkPattern.FindStringSubmatch(match)
+		label := emphasis(m[1])
+		if !safeURL(html.UnescapeString(m[2])) {
+			return placeholder(label)
+		}
+		return placeholder(fmt.Sprintf(`<a href="%s" rel="nofollow noopener">%s</a>`, m[2], label))
+	})
+	escaped = emphasis(escaped)
+
+	return placeholderPattern.ReplaceAllStringFunc(escaped, func(match string) string {
+		var i int
+		fmt.Sscanf(strings.Trim(match, "\x00"), "%d", &i)
+		if i < 0 || i >= len(tokens) {
+			return match
+		}
+		return tokens[i]
+	})
+}
+
+func emphasis(text string) string {
+	text = strongPattern.ReplaceAllString(text, "<strong>$1</strong>")
+	text = emphasisPattern.ReplaceAllString(text, "<em>$1</em>")
+	return underscorePattern.ReplaceAllString(text, "$1<em>$2</em>$3")
+}
+
+// safeURL allows links to web pages and mail addresses only, which keeps
+// javascript: and data: URLs out of the rendered HTML
+func safeURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return true
+	}
+	return false
+}
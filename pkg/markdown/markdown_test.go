@@ -0,0 +1,48 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{"paragraphs", "one\ntwo\n\nthree", "<p>one\ntwo</p>\n<p>three</p>\n"},
+		{"heading", "## Notes", "<h2>Notes</h2>\n"},
+		{"emphasis", "**bold**, *italic* and _also_ but not snake_case_name", "<p><strong>bold</strong>, <em>italic</em> and <em>also</em> but not snake_case_name</p>\n"},
+		{"bullets", "- one\n* two\n\n1. first\n2) second", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n"},
+		{"quote", "> quoted\n> **text**\nafter", "<blockquote>\n<p>quoted\n<strong>text</strong></p>\n</blockquote>\n<p>after</p>\n"},
+		{"code block", "```go\nif a < b {\n```", "<pre><code>if a &lt; b {</code></pre>\n"},
+		{"inline code", "run `rm *.tmp *now*`", "<p>run <code>rm *.tmp *now*</code></p>\n"},
+		{"unmatched backtick", "a ` b", "<p>a ` b</p>\n"},
+		{"link", "see [the *docs*](https://example.com/a?b=1&c=2)", `<p>see <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener">the <em>docs</em></a></p>` + "\n"},
+		{"link with emphasis characters", "[x](https://example.com/*a*)", `<p><a href="https://example.com/*a*" rel="nofollow noopener">x</a></p>` + "\n"},
+		{"mailto", "[mail](mailto:me@example.com)", `<p><a href="mailto:me@example.com" rel="nofollow noopener">mail</a></p>` + "\n"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ToHTML(tt.source), tt.name)
+	}
+}
+
+func TestToHTMLEscapesUnsafeInput(t *testing.T) {
+	tests := []struct {
+		source, want string
+	}{
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"[click](javascript:alert(1))", "<p>click)</p>\n"},
+		{"[click](JaVaScRiPt:alert)", "<p>click</p>\n"},
+		{"[click](data:text/html,hi)", "<p>click</p>\n"},
+		{`[click](https://example.com/"onmouseover="alert)`, `<p><a href="https://example.com/&#34;onmouseover=&#34;alert" rel="nofollow noopener">click</a></p>` + "\n"},
+		{"`<b>`", "<p><code>&lt;b&gt;</code></p>\n"},
+		{"fake \x000\x00 placeholder", "<p>fake 0 placeholder</p>\n"},
+		{"a `\x0099\x00", "<p>a `99</p>\n"},
+		{"`x` and `\x001\x00", "<p><code>x</code> and `1</p>\n"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ToHTML(tt.source), "%q", tt.source)
+	}
+}
@@ -0,0 +1,97 @@
+// Package signedtoken creates the tokens of the account emails. A token carries its
+// purpose, the user and an expiry, signed with HMAC-SHA256. The signature also covers
+// a binding that is not part of the token, such as the current password hash, so a
+// token stops working once the state it was issued for has changed. That makes the
+// tokens single-use without storing them.
+package signedtoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalid = errors.New("signedtoken: invalid token")
+	ErrExpired = errors.New("signedtoken: token expired")
+)
+
+// Signer signs and verifies tokens with one secret
+type Signer struct {
+	secret []byte
+}
+
+func New(secret []byte) *Signer {
+	return &Signer{secret}
+}
+
+// Sign returns a token for a purpose like "password-reset" and a user id, valid until expires
+func (s *Signer) Sign(purpose string, subject uint, expires time.Time, binding string) string {
+	payload := fmt.Sprintf("%s:%d:%d", purpose, subject, expires.Unix())
+	return encode([]byte(payload)) + "." + encode(s.mac(payload, binding))
+}
+
+// Subject returns the user id of a token of the given purpose. It does not check the
+// signature, which needs the binding of that user, so it only tells whom to load for Verify.
+func (s *Signer) Subject(token, purpose string) (uint, error) {
+	_, subject, _, err := parse(token, purpose)
+	return subject, err
+}
+
+// Verify checks the purpose, signature and expiry of a token against the binding of its user
+func (s *Signer) Verify(token, purpose, binding string, now time.Time) error {
+	payload, _, expires, err := parse(token, purpose)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(token[strings.IndexByte(token, '.')+1:])
+	if err != nil || !hmac.Equal(signature, s.mac(payload, binding)) {
+		return ErrInvalid
+	}
+	if !now.Before(expires) {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *Signer) mac(payload, binding string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	mac.Write([]byte{0})
+	mac.Write([]byte(binding))
+	return mac.Sum(nil)
+}
+
+// parse splits a token into its payload, subject and expiry
+func parse(token, purpose string) (string, uint, time.Time, error) {
+	encoded, _, found := strings.Cut(token, ".")
+	if !found {
+		return "", 0, time.Time{}, ErrInvalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", 0, time.Time{}, ErrInvalid
+	}
+	parts := strings.Split(string(data), ":")
+	if len(parts) != 3 || parts[0] != purpose {
+		return "", 0, time.Time{}, ErrInvalid
+	}
+	subject, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return "", 0, time.Time{}, ErrInvalid
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, time.Time{}, ErrInvalid
+	}
+	return string(data), uint(subject), time.Unix(expires, 0), nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,29 @@
+package signedtoken
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	signer := New([]byte("0123456789abcdef0123456789abcdef"))
+	now := time.Unix(1700000000, 0)
+	token := signer.Sign("password-reset", 42, now.Add(time.Hour), "hash-1")
+
+	subject, err := signer.Subject(token, "password-reset")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), subject)
+	assert.NoError(t, signer.Verify(token, "password-reset", "hash-1", now))
+
+	assert.ErrorIs(t, signer.Verify(token, "password-reset", "hash-2", now), ErrInvalid, "a changed binding invalidates the token")
+	assert.ErrorIs(t, signer.Verify(token, "email-verification", "hash-1", now), ErrInvalid)
+	assert.ErrorIs(t, signer.Verify(token, "password-reset", "hash-1", now.Add(time.Hour)), ErrExpired)
+	assert.ErrorIs(t, New([]byte("another secret of thirty-two bytes")).Verify(token, "password-reset", "hash-1", now), ErrInvalid)
+
+	forged := New([]byte("another secret of thirty-two bytes")).Sign("password-reset", 1, now.Add(time.Hour), "hash-1")
+	assert.ErrorIs(t, signer.Verify(forged, "password-reset", "hash-1", now), ErrInvalid)
+	_, err = signer.Subject("garbage", "password-reset")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,35 @@
+// Package storage keeps file contents outside of the database. Blobs are addressed
+// by slash separated keys such as "todos/1/3f2a..." and stored either on the local
+// filesystem or in an S3 compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned when reading a blob that does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores and retrieves blobs by key. Deleting a missing blob is not an error.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey rejects keys that could escape the storage root
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/pkg/jwt"
+	"todo-list/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorLogin(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "totpuser")
+
+	send := func(method, url string, cookie *http.Cookie, payload interface{}) (int, *http.Cookie, map[string]interface{}) {
+		resp := doJSON(t, client, method, url, cookie, payload)
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		for _, c := range resp.Cookies() {
+			if c.Name == "session" {
+				cookie = c
+			}
+		}
+		return resp.StatusCode, cookie, body
+	}
+	password := map[string]interface{}{"username": "totpuser", "password": "password123"}
+	code := func(secret string, step int64) map[string]interface{} {
+		value, err := totp.Code(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]interface{}{"code": value}
+	}
+
+	// enrollment is confirmed with a first code
+	status, _, _ := send("POST", server.URL+"/me/2fa/enable", cookie, map[string]interface{}{"code": "123456"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _, enrollment := send("POST", server.URL+"/me/2fa/enroll", cookie, nil)
+	assert.Equal(t, http.StatusCreated, status)
+	secret, _ := enrollment["secret"].(string)
+	assert.True(t, strings.HasPrefix(enrollment["uri"].(string), "otpauth://totp/Todo%20List:totpuser?"))
+
+	now := totp.Step(time.Now())
+	wrong := code(secret, now+5)
+	status, _, _ = send("POST", server.URL+"/me/2fa/enable", cookie, wrong)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _, enabled := send("POST", server.URL+"/me/2fa/enable", cookie, code(secret, now))
+	assert.Equal(t, http.StatusOK, status)
+	var recovery []string
+	for _, value := range enabled["recovery_codes"].([]interface{}) {
+		recovery = append(recovery, value.(string))
+	}
+	assert.Len(t, recovery, 10)
+	status, _, _ = send("POST", server.URL+"/me/2fa/enroll", cookie, nil)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// the password alone only starts the login
+	status, pending, body := send("POST", server.URL+"/login", nil, password)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, true, body["two_factor_required"])
+	status, _, _ = send("GET", server.URL+"/todos", pending, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _, _ = send("POST", server.URL+"/login/2fa", pending, code(secret, now))
+	assert.Equal(t, http.StatusUnauthorized, status, "codes cannot be used twice")
+	status, loggedIn, _ := send("POST", server.URL+"/login/2fa", pending, code(secret, now+1))
+	assert.Equal(t, http.StatusOK, status)
+	status, _, _ = send("GET", server.URL+"/todos", loggedIn, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	// recovery codes work once
+	_, pending, _ = send("POST", server.URL+"/login", nil, password)
+	status, _, _ = send("POST", server.URL+"/login/2fa", pending, map[string]interface{}{"code": strings.ToUpper(recovery[0])})
+	assert.Equal(t, http.StatusOK, status)
+	_, pending, _ = send("POST", server.URL+"/login", nil, password)
+	status, _, _ = send("POST", server.URL+"/login/2fa", pending, map[string]interface{}{"code": recovery[0]})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// too many wrong codes ask for the password again
+	_, pending, _ = send("POST", server.URL+"/login", nil, password)
+	for i := 0; i < 5; i++ {
+		send("POST", server.URL+"/login/2fa", pending, wrong)
+	}
+	status, _, _ = send("POST", server.URL+"/login/2fa", pending, map[string]interface{}{"code": recovery[1]})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	// and lock the user out, a new login does not start counting again
+	_, pending, _ = send("POST", server.URL+"/login", nil, password)
+	status, _, _ = send("POST", server.URL+"/login/2fa", pending, map[string]interface{}{"code": recovery[1]})
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	db.Model(&models.User{}).Where("username = ?", "totpuser").Update("totp_locked_until", nil)
+
+	status, _, state := send("GET", server.URL+"/me/2fa", loggedIn, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, state["enabled"])
+	assert.Equal(t, float64(9), state["recovery_codes_left"])
+
+	// the jwt auth mode takes the code with the password
+	key, _ := jwt.NewHS256Key("k", bytes.Repeat([]byte("k"), 32))
+	jwtServer := httptest.NewServer(setupRouterWithAuth(db, jwt.NewKeySet(key)))
+	defer jwtServer.Close()
+	status, _, body = send("POST", jwtServer.URL+"/login", nil, password)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, true, body["two_factor_required"])
+	status, _, body = send("POST", jwtServer.URL+"/login", nil, map[string]interface{}{"username": "totpuser", "password": "password123", "code": recovery[1]})
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, body["access_token"])
+
+	// there the user is locked out after too many wrong codes
+	withCode := func(code interface{}) map[string]interface{} {
+		return map[string]interface{}{"username": "totpuser", "password": "password123", "code": code}
+	}
+	for i := 0; i < 5; i++ {
+		status, _, _ = send("POST", jwtServer.URL+"/login", nil, withCode(wrong["code"]))
+		assert.Equal(t, http.StatusUnauthorized, status)
+	}
+	status, _, _ = send("POST", jwtServer.URL+"/login", nil, withCode(recovery[3]))
+	assert.Equal(t, http.StatusTooManyRequests, status)
+
+	// turning it off needs a code as well
+	status, _, _ = send("POST", server.URL+"/me/2fa/disable", loggedIn, map[string]interface{}{"code": recovery[1]})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _, _ = send("POST", server.URL+"/me/2fa/disable", loggedIn, map[string]interface{}{"code": recovery[2]})
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _, _ = send("POST", server.URL+"/login", nil, password)
+	assert.Equal(t, http.StatusOK, status)
+}
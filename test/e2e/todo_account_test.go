@@ -0,0 +1,115 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mailedToken matches the signed tokens in verification and password reset mails
+var mailedToken = regexp.MustCompile(`[A-Za-z0-9_-]{16,}\.[A-Za-z0-9_-]{16,}`)
+
+func TestEmailVerificationAndPasswordReset(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	status := func(method, url string, payload interface{}) int {
+		resp := doJSON(t, client, method, url, nil, payload)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	login := func(password string) (int, *http.Cookie) {
+		resp := doJSON(t, client, "POST", server.URL+"/login", nil, map[string]interface{}{"username": "mailuser", "password": password})
+		resp.Body.Close()
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == "session" {
+				return resp.StatusCode, cookie
+			}
+		}
+		return resp.StatusCode, nil
+	}
+	lastToken := func(to, subject string) string {
+		msg, ok := testMailer.last(to)
+		if !assert.True(t, ok, "no mail to %s", to) || !assert.Equal(t, subject, msg.Subject) {
+			return ""
+		}
+		return mailedToken.FindString(msg.Body)
+	}
+
+	// registering with an address mails a verification token
+	assert.Equal(t, http.StatusBadRequest, status("POST", server.URL+"/register", map[string]interface{}{"username": "mailuser", "password": "password123", "email": "not an address"}))
+	assert.Equal(t, http.StatusCreated, status("POST", server.URL+"/register", map[string]interface{}{"username": "mailuser", "password": "password123", "email": "Mail.User@Example.com"}))
+	assert.Equal(t, http.StatusConflict, status("POST", server.URL+"/register", map[string]interface{}{"username": "mailother", "password": "password123", "email": "mail.user@example.com"}))
+	verification := lastToken("mail.user@example.com", "Confirm your email address")
+
+	// resets are only mailed to verified addresses
+	assert.Equal(t, http.StatusAccepted, status("POST", server.URL+"/password/forgot", map[string]interface{}{"email": "mail.user@example.com"}))
+	assert.Equal(t, verification, lastToken("mail.user@example.com", "Confirm your email address"))
+
+	resp := doJSON(t, client, "POST", server.URL+"/email/verify", nil, map[string]interface{}{"token": verification})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var user models.User
+	json.NewDecoder(resp.Body).Decode(&user)
+	resp.Body.Close()
+	assert.NotNil(t, user.EmailVerifiedAt)
+	assert.Equal(t, http.StatusBadRequest, status("POST", server.URL+"/email/verify", map[string]interface{}{"token": verification}), "tokens work once")
+
+	// forgotten passwords
+	_, session := login("password123")
+	resp = doJSON(t, client, "POST", server.URL+"/me/tokens", session, map[string]interface{}{"name": "Script"})
+	var token models.APIToken
+	json.NewDecoder(resp.Body).Decode(&token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, status("POST", server.URL+"/password/forgot", map[string]interface{}{"email": "nobody@example.com"}))
+	_, mailed := testMailer.last("nobody@example.com")
+	assert.False(t, mailed)
+	assert.Equal(t, http.StatusAccepted, status("POST", server.URL+"/password/forgot", map[string]interface{}{"email": "MAIL.USER@example.com"}))
+	reset := lastToken("mail.user@example.com", "Reset your password")
+
+	assert.Equal(t, http.StatusBadRequest, status("POST", server.URL+"/password/reset", map[string]interface{}{"token": verification, "password": "n3w-password"}))
+	assert.Equal(t, http.StatusBadRequest, status("POST", server.URL+"/password/reset", map[string]interface{}{"token": reset, "password": ""}))
+	assert.Equal(t, http.StatusNoContent, status("POST", server.URL+"/password/reset", map[string]interface{}{"token": reset, "password": "n3w-password"}))
+	assert.Equal(t, http.StatusBadRequest, status("POST", server.URL+"/password/reset", map[string]interface{}{"token": reset, "password": "another"}), "tokens work once")
+
+	resp = doJSON(t, client, "GET", server.URL+"/todos", session, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "resets end the sessions")
+	resp = doBearer(t, client, "GET", server.URL+"/todos", token.Token, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "resets revoke the personal access tokens")
+	code, _ := login("password123")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, session = login("n3w-password")
+	assert.Equal(t, http.StatusOK, code)
+
+	// changing the address needs a new verification
+	other := registerAndLogin(t, client, server.URL, "mailother")
+	resp = doJSON(t, client, "PUT", server.URL+"/me/email", other, map[string]interface{}{"email": "mail.user@example.com"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = doJSON(t, client, "PUT", server.URL+"/me/email", session, map[string]interface{}{"email": "new@example.com"})
+	json.NewDecoder(resp.Body).Decode(&user)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Nil(t, user.EmailVerifiedAt)
+	first := lastToken("new@example.com", "Confirm your email address")
+	resp = doJSON(t, client, "POST", server.URL+"/me/email/verification", session, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, http.StatusOK, status("POST", server.URL+"/email/verify", map[string]interface{}{"token": lastToken("new@example.com", "Confirm your email address")}))
+	assert.Equal(t, http.StatusBadRequest, status("POST", server.URL+"/email/verify", map[string]interface{}{"token": first}))
+	resp = doJSON(t, client, "POST", server.URL+"/me/email/verification", session, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
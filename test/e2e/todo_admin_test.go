@@ -0,0 +1,122 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminUsers(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	admin := registerAndLogin(t, client, server.URL, "adminalice")
+	target := registerAndLogin(t, client, server.URL, "adminbob")
+	db.Model(&models.User{}).Where("username = ?", "adminalice").Update("role", models.RoleAdmin)
+
+	status := func(method, url string, cookie *http.Cookie, payload interface{}) int {
+		resp := doJSON(t, client, method, url, cookie, payload)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	login := func(username, password string) (int, *http.Cookie) {
+		resp := doJSON(t, client, "POST", server.URL+"/login", nil, map[string]interface{}{"username": username, "password": password})
+		resp.Body.Close()
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == "session" {
+				return resp.StatusCode, cookie
+			}
+		}
+		return resp.StatusCode, nil
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, status("GET", server.URL+"/admin/users", nil, nil))
+	assert.Equal(t, http.StatusForbidden, status("GET", server.URL+"/admin/users", target, nil))
+
+	// searching and paging
+	resp := doJSON(t, client, "GET", server.URL+"/admin/users?q=ADMINB", admin, nil)
+	var users []models.User
+	json.NewDecoder(resp.Body).Decode(&users)
+	resp.Body.Close()
+	if !assert.Len(t, users, 1) {
+		return
+	}
+	bob := users[0]
+	assert.Equal(t, "adminbob", bob.Username)
+	assert.Equal(t, models.RoleUser, bob.Role)
+	userURL := fmt.Sprintf("%s/admin/users/%d", server.URL, bob.ID)
+
+	resp = doJSON(t, client, "GET", server.URL+"/admin/users?q=admin&limit=1", admin, nil)
+	json.NewDecoder(resp.Body).Decode(&users)
+	resp.Body.Close()
+	if !assert.Len(t, users, 1) {
+		return
+	}
+	alice := users[0]
+	assert.Equal(t, "adminalice", alice.Username)
+	resp = doJSON(t, client, "GET", fmt.Sprintf("%s/admin/users?q=admin&after=%d", server.URL, alice.ID), admin, nil)
+	json.NewDecoder(resp.Body).Decode(&users)
+	resp.Body.Close()
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, "adminbob", users[0].Username)
+	}
+	assert.Equal(t, http.StatusBadRequest, status("GET", server.URL+"/admin/users?limit=0", admin, nil))
+	assert.Equal(t, http.StatusNotFound, status("GET", server.URL+"/admin/users/999999", admin, nil))
+
+	// force logout ends every session of the user
+	_, second := login("adminbob", "password123")
+	resp = doJSON(t, client, "POST", userURL+"/logout", admin, nil)
+	var ended map[string]int
+	json.NewDecoder(resp.Body).Decode(&ended)
+	resp.Body.Close()
+	assert.Equal(t, 2, ended["sessions"])
+	assert.Equal(t, http.StatusUnauthorized, status("GET", server.URL+"/todos", target, nil))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", server.URL+"/todos", second, nil))
+	assert.Equal(t, http.StatusOK, status("GET", server.URL+"/todos", admin, nil), "other users stay logged in")
+
+	// disabled users are logged out and cannot log in again until enabled
+	_, target = login("adminbob", "password123")
+	resp = doJSON(t, client, "POST", userURL+"/disable", admin, nil)
+	var disabled models.User
+	json.NewDecoder(resp.Body).Decode(&disabled)
+	resp.Body.Close()
+	assert.NotNil(t, disabled.DisabledAt)
+	assert.Equal(t, http.StatusUnauthorized, status("GET", server.URL+"/todos", target, nil))
+	code, _ := login("adminbob", "password123")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, http.StatusBadRequest, status("POST", fmt.Sprintf("%s/admin/users/%d/disable", server.URL, alice.ID), admin, nil), "admins cannot lock themselves out")
+
+	assert.Equal(t, http.StatusOK, status("POST", userURL+"/enable", admin, nil))
+	code, target = login("adminbob", "password123")
+	assert.Equal(t, http.StatusOK, code)
+
+	// password resets log the user out as well
+	resp = doJSON(t, client, "POST", server.URL+"/me/tokens", target, map[string]interface{}{"name": "Script"})
+	var token models.APIToken
+	json.NewDecoder(resp.Body).Decode(&token)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, status("POST", userURL+"/password", admin, map[string]interface{}{"password": ""}))
+	assert.Equal(t, http.StatusNoContent, status("POST", userURL+"/password", admin, map[string]interface{}{"password": "n3w-secret"}))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", server.URL+"/todos", target, nil))
+	resp = doBearer(t, client, "GET", server.URL+"/todos", token.Token, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	code, _ = login("adminbob", "password123")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = login("adminbob", "n3w-secret")
+	assert.Equal(t, http.StatusOK, code)
+
+	// the role is checked on every request
+	db.Model(&models.User{}).Where("username = ?", "adminalice").Update("role", models.RoleUser)
+	assert.Equal(t, http.StatusForbidden, status("GET", server.URL+"/admin/users", admin, nil))
+}
@@ -0,0 +1,151 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoAssignee(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	owner := registerAndLogin(t, client, server.URL, "assignowner")
+	editor := registerAndLogin(t, client, server.URL, "assigneditor")
+	viewer := registerAndLogin(t, client, server.URL, "assignviewer")
+	registerAndLogin(t, client, server.URL, "assignstranger")
+
+	resp := doJSON(t, client, "POST", server.URL+"/todos", owner, map[string]interface{}{"title": "Write release notes"})
+	var todo models.Todo
+	json.NewDecoder(resp.Body).Decode(&todo)
+	resp.Body.Close()
+	todoURL := fmt.Sprintf("%s/todos/%d", server.URL, todo.ID)
+
+	userIDs := map[string]uint{}
+	for username, role := range map[string]string{"assigneditor": "editor", "assignviewer": "viewer"} {
+		resp = doJSON(t, client, "PUT", todoURL+"/shares/"+username, owner, map[string]interface{}{"role": role})
+		var share models.Share
+		json.NewDecoder(resp.Body).Decode(&share)
+		resp.Body.Close()
+		userIDs[username] = share.UserID
+	}
+
+	assign := func(cookie *http.Cookie, username string) (int, models.Todo) {
+		method, payload := "PUT", interface{}(map[string]interface{}{"username": username})
+		if username == "" {
+			method, payload = "DELETE", nil
+		}
+		resp := doJSON(t, client, method, todoURL+"/assignee", cookie, payload)
+		var assigned models.Todo
+		json.NewDecoder(resp.Body).Decode(&assigned)
+		resp.Body.Close()
+		return resp.StatusCode, assigned
+	}
+	assignedIDs := func(cookie *http.Cookie) []uint {
+		resp := doJSON(t, client, "GET", server.URL+"/me/assigned", cookie, nil)
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		resp.Body.Close()
+		ids := []uint{}
+		for _, todo := range todos {
+			ids = append(ids, todo.ID)
+		}
+		return ids
+	}
+	notifications := func(cookie *http.Cookie, query string) []models.Notification {
+		resp := doJSON(t, client, "GET", server.URL+"/me/notifications"+query, cookie, nil)
+		var notifications []models.Notification
+		json.NewDecoder(resp.Body).Decode(&notifications)
+		resp.Body.Close()
+		return notifications
+	}
+
+	code, _ := assign(owner, "nobody")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = assign(owner, "assignstranger")
+	assert.Equal(t, http.StatusBadRequest, code, "the assignee has to be able to see the todo")
+
+	// viewers may not assign, editors may take an unassigned todo
+	code, _ = assign(viewer, "assignviewer")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, assigned := assign(editor, "assigneditor")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.NotNil(t, assigned.AssigneeID) {
+		assert.Equal(t, userIDs["assigneditor"], *assigned.AssigneeID)
+	}
+	assert.Equal(t, []uint{todo.ID}, assignedIDs(editor))
+	assert.Empty(t, assignedIDs(viewer))
+	assert.Empty(t, notifications(editor, ""), "no notification about your own action")
+
+	// updating the todo leaves the assignee alone
+	resp = doJSON(t, client, "PUT", todoURL, owner, map[string]interface{}{"title": "Write the release notes", "assignee_id": nil})
+	json.NewDecoder(resp.Body).Decode(&assigned)
+	resp.Body.Close()
+	if assert.NotNil(t, assigned.AssigneeID) {
+		assert.Equal(t, userIDs["assigneditor"], *assigned.AssigneeID)
+	}
+
+	// the owner may reassign, both assignees are told
+	code, _ = assign(owner, "assignviewer")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []uint{todo.ID}, assignedIDs(viewer))
+	assert.Empty(t, assignedIDs(editor))
+
+	received := notifications(viewer, "?unread=true")
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, models.NotificationAssigned, received[0].Type)
+		assert.Equal(t, todo.ID, received[0].TodoID)
+		assert.Equal(t, `assignowner assigned "Write the release notes" to you`, received[0].Message)
+	}
+	lost := notifications(editor, "")
+	if assert.Len(t, lost, 1) {
+		assert.Equal(t, models.NotificationUnassigned, lost[0].Type)
+		assert.Equal(t, `assignowner reassigned "Write the release notes" to assignviewer`, lost[0].Message)
+	}
+
+	// only the owner and the current assignee may reassign an assigned todo
+	code, _ = assign(editor, "assigneditor")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, assigned = assign(viewer, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, assigned.AssigneeID)
+
+	resp = doJSON(t, client, "GET", todoURL+"/history", owner, nil)
+	var history []models.TodoHistory
+	json.NewDecoder(resp.Body).Decode(&history)
+	resp.Body.Close()
+	assignments := 0
+	for _, entry := range history {
+		if _, ok := entry.Changes["assignee_id"]; ok {
+			assignments++
+		}
+	}
+	assert.Equal(t, 3, assignments)
+
+	// reading notifications
+	resp = doJSON(t, client, "POST", fmt.Sprintf("%s/me/notifications/%d/read", server.URL, received[0].ID), viewer, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	assert.Empty(t, notifications(viewer, "?unread=true"))
+	resp = doJSON(t, client, "POST", fmt.Sprintf("%s/me/notifications/%d/read", server.URL, lost[0].ID), viewer, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "notifications of other users")
+	resp.Body.Close()
+
+	resp = doJSON(t, client, "POST", server.URL+"/me/notifications/read", editor, nil)
+	var marked map[string]int64
+	json.NewDecoder(resp.Body).Decode(&marked)
+	resp.Body.Close()
+	assert.Equal(t, int64(1), marked["marked"])
+	assert.Empty(t, notifications(editor, "?unread=true"))
+	assert.Len(t, notifications(editor, ""), 1)
+}
@@ -0,0 +1,130 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+	"todo-list/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoAttachments(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "attachmentuser")
+	other := registerAndLogin(t, client, server.URL, "attachmentother")
+
+	resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": "With files"})
+	var todo models.Todo
+	json.NewDecoder(resp.Body).Decode(&todo)
+	resp.Body.Close()
+	attachmentsURL := fmt.Sprintf("%s/todos/%d/attachments", server.URL, todo.ID)
+
+	upload := func(cookie *http.Cookie, name string, content []byte) *http.Response {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("note", "ignored")
+		part, _ := form.CreateFormFile("file", name)
+		part.Write(content)
+		form.Close()
+		req, _ := http.NewRequest("POST", attachmentsURL, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.AddCookie(cookie)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("upload failed: %v", err)
+		}
+		return resp
+	}
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+	resp = upload(cookie, `C:\Users\me\screen shot.png`, png)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var attachment models.Attachment
+	json.NewDecoder(resp.Body).Decode(&attachment)
+	resp.Body.Close()
+	assert.Equal(t, "screen shot.png", attachment.FileName)
+	assert.Equal(t, "image/png", attachment.ContentType)
+	assert.Equal(t, int64(len(png)), attachment.Size)
+
+	// the content decides the type, not the file name
+	resp = upload(cookie, "notes.pdf", []byte("<html><script>alert(1)</script></html>"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	resp.Body.Close()
+	resp = upload(cookie, "big.txt", bytes.Repeat([]byte("a"), 1<<20+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = doJSON(t, client, "GET", attachmentsURL, cookie, nil)
+	var attachments []models.Attachment
+	json.NewDecoder(resp.Body).Decode(&attachments)
+	resp.Body.Close()
+	assert.Len(t, attachments, 1)
+
+	attachmentURL := fmt.Sprintf("%s/%d", attachmentsURL, attachment.ID)
+	resp = doJSON(t, client, "GET", attachmentURL, cookie, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="screen shot.png"`, resp.Header.Get("Content-Disposition"))
+	content, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, png, content)
+
+	// attachments of other users' todos stay private
+	resp = upload(other, "x.png", png)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "GET", attachmentURL, other, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = doJSON(t, client, "DELETE", attachmentURL, cookie, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "GET", attachmentURL, cookie, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	// purging a todo leaves its attachments to the cleanup job
+	resp = upload(cookie, "keep.txt", []byte("plain text"))
+	json.NewDecoder(resp.Body).Decode(&attachment)
+	resp.Body.Close()
+	for _, req := range []struct{ method, url string }{
+		{"DELETE", fmt.Sprintf("%s/todos/%d", server.URL, todo.ID)},
+		{"DELETE", fmt.Sprintf("%s/trash/%d", server.URL, todo.ID)},
+	} {
+		resp = doJSON(t, client, req.method, req.url, cookie, nil)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	var stored models.Attachment
+	assert.NoError(t, db.First(&stored, attachment.ID).Error)
+	store := storage.NewLocalStore(filepath.Join(os.TempDir(), "todo-list-e2e-attachments"))
+	cleanup := services.NewAttachmentService(repos.NewAttachmentRepository(db), store, 1<<20)
+	removed, err := cleanup.CleanupOrphans(context.Background())
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, removed, 1)
+	_, err = cleanup.GetAttachment(todo.ID, fmt.Sprint(attachment.ID))
+	assert.Error(t, err)
+	_, err = cleanup.OpenAttachment(context.Background(), &stored)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
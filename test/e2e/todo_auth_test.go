@@ -2,11 +2,16 @@ package e2e
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 	"todo-list/config"
@@ -15,9 +20,13 @@ import (
 	"todo-list/internal/repos"
 	"todo-list/internal/services"
 	"todo-list/pkg/database"
+	"todo-list/pkg/jwt"
+	"todo-list/pkg/mail"
+	"todo-list/pkg/signedtoken"
+	"todo-list/pkg/storage"
 
 	"github.com/alexedwards/scs/v2"
-	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -124,11 +133,45 @@ func setupTestDatabase() (*gorm.DB, error) {
 	}
 
 	// Auto-migrate schemas
-	db.AutoMigrate(&models.User{}, &models.Todo{}, &models.Session{})
+	if err := database.Migrate(db); err != nil {
+		return nil, err
+	}
 	return db, nil
 }
 
+// testMailer keeps the mails of every router so tests can read the tokens sent to users
+var testMailer = &recordingMailer{}
+
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// last returns the latest mail to an address
+func (m *recordingMailer) last(to string) (mail.Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+	return mail.Message{}, false
+}
+
 func setupRouter(db *gorm.DB) http.Handler {
+	return setupRouterWithAuth(db, nil)
+}
+
+// setupRouterWithAuth uses the jwt auth mode with the given keys, the session cookie when keys is nil
+func setupRouterWithAuth(db *gorm.DB, keys *jwt.KeySet) http.Handler {
 	// Set up your chi router and handlers
 	r := chi.NewRouter()
 
@@ -140,25 +183,269 @@ func setupRouter(db *gorm.DB) http.Handler {
 
 	// Initialize handlers
 	userRepo := repos.NewUserRepository(db)
-	authHandler := handlers.NewAuthHandler(userRepo, sessionManager)
+	twoFactorService := services.NewTwoFactorService(repos.NewTwoFactorRepository(db), "Todo List")
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, userRepo)
+	accountService := services.NewAccountService(userRepo, testMailer, signedtoken.New([]byte("e2e email token secret of 32 bytes")))
+	authHandler := handlers.NewAuthHandler(userRepo, sessionManager, twoFactorService, accountService)
+	tokenService := services.NewTokenService(repos.NewTokenRepository(db), userRepo)
+	tokenHandler := handlers.NewTokenHandler(tokenService)
+
+	auth := func(next http.HandlerFunc) http.HandlerFunc {
+		return config.AuthMiddleware(next, sessionManager, tokenService)
+	}
+	loggedIn := func(next http.HandlerFunc) http.HandlerFunc {
+		return config.SessionMiddleware(next, sessionManager)
+	}
+	login, logout := authHandler.Login(), authHandler.Logout()
+	var jwtService *services.JWTService
+	var jwtHandler *handlers.JWTHandler
+	if keys != nil {
+		jwtService = services.NewJWTService(repos.NewRefreshTokenRepository(db), userRepo, keys, time.Minute, time.Hour)
+		jwtHandler = handlers.NewJWTHandler(jwtService, userRepo, twoFactorService)
+		auth = func(next http.HandlerFunc) http.HandlerFunc {
+			return config.JWTMiddleware(next, jwtService, tokenService)
+		}
+		loggedIn = func(next http.HandlerFunc) http.HandlerFunc {
+			return config.JWTMiddleware(next, jwtService, nil)
+		}
+		login, logout = jwtHandler.Login(), jwtHandler.Logout()
+	}
+	adminHandler := handlers.NewAdminHandler(services.NewAdminService(userRepo), sessionManager, jwtService)
+	accountHandler := handlers.NewAccountHandler(accountService, userRepo, sessionManager, jwtService)
 
 	todoRepo := repos.NewTodoRepository(db)
-	todoService := services.NewTodoService(todoRepo)
-	todoHandler := handlers.NewTodoHandler(todoService)
+	listRepo := repos.NewListRepository(db)
+	todoService := services.NewTodoService(todoRepo, listRepo)
+	orgRepo := repos.NewOrganizationRepository(db)
+	shareService := services.NewShareService(repos.NewShareRepository(db), userRepo, orgRepo)
+	todoHandler := handlers.NewTodoHandler(todoService, shareService)
+
+	tagRepo := repos.NewTagRepository(db)
+	tagService := services.NewTagService(tagRepo)
+	tagHandler := handlers.NewTagHandler(tagService, shareService)
+
+	listService := services.NewListService(listRepo)
+	listHandler := handlers.NewListHandler(listService, todoService, shareService)
+	shareHandler := handlers.NewShareHandler(shareService, todoService, listService)
+
+	notificationService := services.NewNotificationService(repos.NewNotificationRepository(db))
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	assignmentService := services.NewAssignmentService(todoService, shareService, userRepo, notificationService)
+	assignmentHandler := handlers.NewAssignmentHandler(assignmentService, todoService, shareService)
+
+	organizationHandler := handlers.NewOrganizationHandler(services.NewOrganizationService(orgRepo, userRepo), sessionManager)
+
+	commentService := services.NewCommentService(repos.NewCommentRepository(db))
+	commentHandler := handlers.NewCommentHandler(commentService, todoService, shareService)
+
+	blobStore := storage.NewLocalStore(filepath.Join(os.TempDir(), "todo-list-e2e-attachments"))
+	attachmentService := services.NewAttachmentService(repos.NewAttachmentRepository(db), blobStore, 1<<20)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, todoService, shareService)
+
+	workflowService := services.NewWorkflowService(repos.NewWorkflowRepository(db), todoRepo)
+	workflowHandler := handlers.NewWorkflowHandler(workflowService, listService, shareService)
 
 	// User routes
 	r.Post("/register", authHandler.Register())
-	r.Post("/login", authHandler.Login())
-	r.Post("/logout", authHandler.Logout())
+	r.Post("/login", login)
+	r.Post("/logout", logout)
+	r.Post("/email/verify", accountHandler.VerifyEmail())
+	r.Post("/password/forgot", accountHandler.ForgotPassword())
+	r.Post("/password/reset", accountHandler.ResetPassword())
+	if jwtHandler != nil {
+		r.Post("/token/refresh", jwtHandler.Refresh())
+	} else {
+		r.Post("/login/2fa", authHandler.LoginTwoFactor())
+	}
 
 	// Todo routes
 	r.Group(func(r chi.Router) {
 		// r.Use(config.SessionMiddleware(sessionManager)) // Protect routes with auth middleware
-		r.Post("/todos", config.SessionMiddleware(todoHandler.CreateTodo(), sessionManager))
-		r.Put("/todos/{id}", config.SessionMiddleware(todoHandler.UpdateTodo(), sessionManager))
-		r.Delete("/todos/{id}", config.SessionMiddleware(todoHandler.DeleteTodo(), sessionManager))
-		r.Get("/todos/{id}", config.SessionMiddleware(todoHandler.GetTodo(), sessionManager))
+		r.Get("/todos", auth(todoHandler.GetTodos()))
+		r.Post("/todos", auth(todoHandler.CreateTodo()))
+		r.Get("/todos/search", auth(todoHandler.SearchTodos()))
+		r.Post("/todos/bulk", auth(todoHandler.BulkTodos()))
+		r.Get("/todos/next", auth(todoHandler.NextTodos()))
+		r.Put("/todos/{id}", auth(todoHandler.UpdateTodo()))
+		r.Delete("/todos/{id}", auth(todoHandler.DeleteTodo()))
+		r.Get("/todos/{id}", auth(todoHandler.GetTodo()))
+		r.Get("/todos/{id}/subtree", auth(todoHandler.GetSubtree()))
+		r.Post("/todos/{id}/move", auth(todoHandler.MoveTodo()))
+		r.Get("/todos/{id}/history", auth(todoHandler.GetHistory()))
+		r.Get("/todos/{id}/dependencies", auth(todoHandler.GetDependencies()))
+		r.Post("/todos/{id}/dependencies", auth(todoHandler.AddDependency()))
+		r.Delete("/todos/{id}/dependencies/{blockerId}", auth(todoHandler.RemoveDependency()))
+	})
+
+	// Comment routes
+	r.Group(func(r chi.Router) {
+		r.Get("/todos/{id}/comments", auth(commentHandler.GetComments()))
+		r.Post("/todos/{id}/comments", auth(commentHandler.CreateComment()))
+		r.Put("/todos/{id}/comments/{commentId}", auth(commentHandler.UpdateComment()))
+		r.Delete("/todos/{id}/comments/{commentId}", auth(commentHandler.DeleteComment()))
+	})
+
+	// Attachment routes
+	r.Group(func(r chi.Router) {
+		r.Get("/todos/{id}/attachments", auth(attachmentHandler.GetAttachments()))
+		r.Post("/todos/{id}/attachments", auth(attachmentHandler.UploadAttachment()))
+		r.Get("/todos/{id}/attachments/{attachmentId}", auth(attachmentHandler.DownloadAttachment()))
+		r.Delete("/todos/{id}/attachments/{attachmentId}", auth(attachmentHandler.DeleteAttachment()))
+	})
+
+	// Share routes
+	r.Group(func(r chi.Router) {
+		r.Get("/todos/{id}/shares", auth(shareHandler.GetTodoShares()))
+		r.Put("/todos/{id}/shares/{username}", auth(shareHandler.ShareTodo()))
+		r.Delete("/todos/{id}/shares/{username}", auth(shareHandler.UnshareTodo()))
+		r.Get("/lists/{id}/shares", auth(shareHandler.GetListShares()))
+		r.Put("/lists/{id}/shares/{username}", auth(shareHandler.ShareList()))
+		r.Delete("/lists/{id}/shares/{username}", auth(shareHandler.UnshareList()))
+	})
+
+	// Assignment routes
+	r.Group(func(r chi.Router) {
+		r.Put("/todos/{id}/assignee", auth(assignmentHandler.AssignTodo()))
+		r.Delete("/todos/{id}/assignee", auth(assignmentHandler.UnassignTodo()))
+		r.Get("/me/assigned", auth(assignmentHandler.GetAssigned()))
+		r.Get("/me/notifications", auth(notificationHandler.GetNotifications()))
+		r.Post("/me/notifications/read", auth(notificationHandler.MarkAllRead()))
+		r.Post("/me/notifications/{id}/read", auth(notificationHandler.MarkRead()))
+	})
+
+	// Organization routes
+	r.Group(func(r chi.Router) {
+		r.Get("/orgs", auth(organizationHandler.GetOrganizations()))
+		r.Post("/orgs", auth(organizationHandler.CreateOrganization()))
+		r.Get("/orgs/{id}", auth(organizationHandler.GetOrganization()))
+		r.Get("/orgs/{id}/members", auth(organizationHandler.GetMembers()))
+		r.Put("/orgs/{id}/members/{username}", auth(organizationHandler.SetMemberRole()))
+		r.Delete("/orgs/{id}/members/{username}", auth(organizationHandler.RemoveMember()))
+		r.Post("/orgs/{id}/invitations", auth(organizationHandler.Invite()))
+		r.Get("/me/invitations", auth(organizationHandler.GetInvitations()))
+		r.Post("/me/invitations/{id}/accept", auth(organizationHandler.AcceptInvitation()))
+		r.Delete("/me/invitations/{id}", auth(organizationHandler.DeclineInvitation()))
+		r.Get("/me/org", auth(organizationHandler.GetActiveOrganization()))
+		r.Put("/me/org", auth(organizationHandler.SwitchOrganization()))
+	})
+
+	// Trash routes
+	r.Group(func(r chi.Router) {
+		r.Get("/trash", auth(todoHandler.GetTrash()))
+		r.Post("/trash/{id}/restore", auth(todoHandler.RestoreTodo()))
+		r.Delete("/trash/{id}", auth(todoHandler.PurgeTodo()))
+	})
+
+	// Undo routes
+	r.Group(func(r chi.Router) {
+		r.Post("/undo", auth(todoHandler.Undo()))
+		r.Post("/redo", auth(todoHandler.Redo()))
+	})
+
+	// Tag routes
+	r.Group(func(r chi.Router) {
+		r.Get("/tags", auth(tagHandler.GetTags()))
+		r.Post("/tags", auth(tagHandler.CreateTag()))
+		r.Get("/tags/{id}", auth(tagHandler.GetTag()))
+		r.Put("/tags/{id}", auth(tagHandler.UpdateTag()))
+		r.Delete("/tags/{id}", auth(tagHandler.DeleteTag()))
+	})
+
+	// List routes
+	r.Group(func(r chi.Router) {
+		r.Get("/lists", auth(listHandler.GetLists()))
+		r.Post("/lists", auth(listHandler.CreateList()))
+		r.Get("/lists/{id}", auth(listHandler.GetList()))
+		r.Put("/lists/{id}", auth(listHandler.UpdateList()))
+		r.Delete("/lists/{id}", auth(listHandler.DeleteList()))
+		r.Get("/lists/{id}/todos", auth(listHandler.GetListTodos()))
+		r.Post("/lists/{id}/todos", auth(listHandler.CreateListTodo()))
+	})
+
+	// Workflow routes
+	r.Group(func(r chi.Router) {
+		r.Get("/workflow", auth(workflowHandler.GetWorkflow()))
+		r.Put("/workflow", auth(workflowHandler.UpdateWorkflow()))
+		r.Get("/board", auth(workflowHandler.GetBoard()))
+	})
+
+	// Token routes, a login only
+	r.Group(func(r chi.Router) {
+		r.Get("/me/tokens", loggedIn(tokenHandler.GetTokens()))
+		r.Post("/me/tokens", loggedIn(tokenHandler.CreateToken()))
+		r.Delete("/me/tokens/{id}", loggedIn(tokenHandler.DeleteToken()))
+	})
+
+	// Two-factor routes, a login only
+	r.Group(func(r chi.Router) {
+		r.Get("/me/2fa", loggedIn(twoFactorHandler.GetStatus()))
+		r.Post("/me/2fa/enroll", loggedIn(twoFactorHandler.Enroll()))
+		r.Post("/me/2fa/enable", loggedIn(twoFactorHandler.Enable()))
+		r.Post("/me/2fa/disable", loggedIn(twoFactorHandler.Disable()))
+		r.Post("/me/2fa/recovery-codes", loggedIn(twoFactorHandler.RegenerateRecoveryCodes()))
+	})
+
+	// Account routes
+	r.Group(func(r chi.Router) {
+		r.Put("/me/email", loggedIn(accountHandler.SetEmail()))
+		r.Post("/me/email/verification", loggedIn(accountHandler.ResendVerification()))
+	})
+
+	// Admin routes
+	r.Group(func(r chi.Router) {
+		admin := func(next http.HandlerFunc) http.HandlerFunc {
+			return loggedIn(config.AdminMiddleware(next, userRepo))
+		}
+		r.Get("/admin/users", admin(adminHandler.GetUsers()))
+		r.Get("/admin/users/{id}", admin(adminHandler.GetUser()))
+		r.Post("/admin/users/{id}/disable", admin(adminHandler.DisableUser()))
+		r.Post("/admin/users/{id}/enable", admin(adminHandler.EnableUser()))
+		r.Post("/admin/users/{id}/logout", admin(adminHandler.LogoutUser()))
+		r.Post("/admin/users/{id}/password", admin(adminHandler.ResetPassword()))
 	})
 
 	return r
 }
+
+// registerAndLogin creates a user and returns the session cookie of its login
+func registerAndLogin(t *testing.T, client *http.Client, serverURL string, username string) *http.Cookie {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"username": username,
+		"password": "password123",
+	})
+
+	registerResp, err := client.Post(serverURL+"/register", "application/json", bytes.NewReader(payload))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, registerResp.StatusCode)
+
+	loginResp, err := client.Post(serverURL+"/login", "application/json", bytes.NewReader(payload))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, loginResp.StatusCode)
+
+	for _, cookie := range loginResp.Cookies() {
+		if cookie.Name == "session" {
+			return cookie
+		}
+	}
+	t.Fatalf("no session cookie returned for %s", username)
+	return nil
+}
+
+// doJSON sends an authenticated request with an optional JSON body
+func doJSON(t *testing.T, client *http.Client, method, url string, cookie *http.Cookie, payload interface{}) *http.Response {
+	var body io.Reader
+	if payload != nil {
+		data, _ := json.Marshal(payload)
+		body = bytes.NewReader(data)
+	}
+	req, _ := http.NewRequest(method, url, body)
+	req.Header.Set("Content-Type", "application/json")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	return resp
+}
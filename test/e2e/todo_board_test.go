@@ -0,0 +1,196 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowBoard(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "boarduser")
+	other := registerAndLogin(t, client, server.URL, "boardother")
+
+	workflow := func(query string) []models.WorkflowStatus {
+		resp := doJSON(t, client, "GET", server.URL+"/workflow"+query, cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var statuses []models.WorkflowStatus
+		json.NewDecoder(resp.Body).Decode(&statuses)
+		return statuses
+	}
+	create := func(payload map[string]interface{}) models.Todo {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo
+	}
+	update := func(todo models.Todo, payload map[string]interface{}) (models.Todo, int) {
+		resp := doJSON(t, client, "PUT", fmt.Sprintf("%s/todos/%d", server.URL, todo.ID), cookie, payload)
+		defer resp.Body.Close()
+		var updated models.Todo
+		json.NewDecoder(resp.Body).Decode(&updated)
+		return updated, resp.StatusCode
+	}
+	board := func(query string) services.Board {
+		resp := doJSON(t, client, "GET", server.URL+"/board"+query, cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var board services.Board
+		json.NewDecoder(resp.Body).Decode(&board)
+		return board
+	}
+	columns := func(board services.Board) map[string][]string {
+		result := map[string][]string{}
+		for _, column := range board.Columns {
+			for _, todo := range column.Todos {
+				result[column.Status.Name] = append(result[column.Status.Name], todo.Title)
+			}
+		}
+		return result
+	}
+
+	// every user starts with the default workflow
+	statuses := workflow("")
+	if assert.Len(t, statuses, 4) {
+		assert.Equal(t, "Backlog", statuses[0].Name)
+		assert.True(t, statuses[3].IsTerminal)
+		assert.ElementsMatch(t, []string{"In Progress", "Review", "Done"}, statuses[0].Transitions)
+	}
+	status := map[string]uint{}
+	for _, s := range statuses {
+		status[s.Name] = s.ID
+	}
+
+	// new todos start in the first status, or the terminal one when completed
+	open := create(map[string]interface{}{"title": "Open"})
+	done := create(map[string]interface{}{"title": "Done already", "is_completed": true})
+	review := create(map[string]interface{}{"title": "In review", "status_id": status["Review"]})
+	assert.Equal(t, status["Backlog"], *open.StatusID)
+	assert.Equal(t, status["Done"], *done.StatusID)
+	assert.Equal(t, status["Review"], *review.StatusID)
+	assert.False(t, review.IsCompleted)
+
+	// moving to the terminal status completes a todo, toggling is_completed moves it
+	updated, code := update(review, map[string]interface{}{"status_id": status["Done"]})
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, updated.IsCompleted)
+	updated, _ = update(updated, map[string]interface{}{"is_completed": false})
+	assert.Equal(t, status["Backlog"], *updated.StatusID)
+	updated, _ = update(updated, map[string]interface{}{"is_completed": true})
+	assert.Equal(t, status["Done"], *updated.StatusID)
+
+	_, code = update(open, map[string]interface{}{"status_id": 999999})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b := board("")
+	assert.Nil(t, b.ListID)
+	assert.Equal(t, map[string][]string{
+		"Backlog": {"Open"},
+		"Done":    {"Done already", "In review"},
+	}, columns(b))
+
+	// a list with its own workflow only allows the configured transitions
+	resp := doJSON(t, client, "POST", server.URL+"/lists", cookie, map[string]interface{}{"name": "Sprint"})
+	var list models.List
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	inList := create(map[string]interface{}{"title": "Planned", "list_id": list.ID})
+
+	query := fmt.Sprintf("?list_id=%d", list.ID)
+	resp = doJSON(t, client, "PUT", server.URL+"/workflow"+query, cookie, []map[string]interface{}{
+		{"name": "Backlog", "transitions": []string{"Doing"}},
+		{"name": "Doing", "transitions": []string{"Shipped"}},
+		{"name": "Shipped", "is_terminal": true},
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var sprint []models.WorkflowStatus
+	json.NewDecoder(resp.Body).Decode(&sprint)
+	resp.Body.Close()
+	assert.Len(t, sprint, 3)
+	assert.Equal(t, []string{"Doing"}, sprint[0].Transitions)
+
+	// the todo of the list keeps the status with the same name
+	b = board(query)
+	assert.Equal(t, map[string][]string{"Backlog": {"Planned"}}, columns(b))
+	assert.Equal(t, sprint[0].ID, *b.Columns[0].Todos[0].StatusID)
+	// and is no longer on the default board
+	assert.NotContains(t, columns(board(""))["Backlog"], "Planned")
+
+	inList.StatusID = b.Columns[0].Todos[0].StatusID
+	_, code = update(inList, map[string]interface{}{"status_id": sprint[2].ID})
+	assert.Equal(t, http.StatusConflict, code)
+	// completing is a status change as well
+	_, code = update(inList, map[string]interface{}{"is_completed": true})
+	assert.Equal(t, http.StatusConflict, code)
+	resp = doJSON(t, client, "POST", server.URL+"/todos/bulk", cookie, map[string]interface{}{
+		"operations": []map[string]interface{}{{"op": "complete", "ids": []uint{inList.ID}}},
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+	updated, code = update(inList, map[string]interface{}{"status_id": sprint[1].ID})
+	assert.Equal(t, http.StatusOK, code)
+	updated, _ = update(updated, map[string]interface{}{"status_id": sprint[2].ID})
+	assert.True(t, updated.IsCompleted)
+	_, code = update(updated, map[string]interface{}{"is_completed": false})
+	assert.Equal(t, http.StatusConflict, code, "Shipped has no way back")
+	// undoing is a status change like any other
+	resp = doJSON(t, client, "POST", server.URL+"/undo", cookie, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+	assert.Equal(t, map[string][]string{"Shipped": {"Planned"}}, columns(board(query)))
+
+	// statuses of another workflow are unknown to the list
+	_, code = update(updated, map[string]interface{}{"status_id": status["Review"]})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// moving back to the inbox keeps the completed state
+	updated, _ = update(updated, map[string]interface{}{"list_id": nil})
+	assert.Equal(t, status["Done"], *updated.StatusID)
+
+	// invalid workflows and removing used statuses are rejected
+	for _, statuses := range [][]map[string]interface{}{
+		{{"name": "Only", "is_terminal": true}},
+		{{"name": "A"}, {"name": "A", "is_terminal": true}},
+		{{"name": "A", "transitions": []string{"Nope"}}, {"name": "B", "is_terminal": true}},
+	} {
+		resp = doJSON(t, client, "PUT", server.URL+"/workflow", cookie, statuses)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp.Body.Close()
+	}
+	resp = doJSON(t, client, "PUT", server.URL+"/workflow", cookie, []map[string]interface{}{
+		{"name": "Todo"}, {"name": "Done", "is_terminal": true},
+	})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	// renaming by id keeps the todos in place
+	resp = doJSON(t, client, "PUT", server.URL+"/workflow", cookie, []map[string]interface{}{
+		{"id": status["Backlog"], "name": "Todo", "transitions": []string{"Done"}},
+		{"name": "In Progress"}, {"name": "Review"},
+		{"name": "Done", "is_terminal": true},
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	assert.Equal(t, []string{"Open"}, columns(board(""))["Todo"])
+
+	// boards of other users' lists stay private
+	resp = doJSON(t, client, "GET", server.URL+"/board"+query, other, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+}
@@ -0,0 +1,156 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bulkResponse struct {
+	Applied bool `json:"applied"`
+	Results []struct {
+		Op     int    `json:"op"`
+		ID     uint   `json:"id"`
+		Status int    `json:"status"`
+		Error  string `json:"error"`
+	} `json:"results"`
+}
+
+func TestBulkTodos(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "bulkuser")
+	other := registerAndLogin(t, client, server.URL, "bulkother")
+
+	create := func(cookie *http.Cookie, payload map[string]interface{}) models.Todo {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		defer resp.Body.Close()
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo
+	}
+	bulk := func(payload map[string]interface{}) (int, bulkResponse) {
+		resp := doJSON(t, client, "POST", server.URL+"/todos/bulk", cookie, payload)
+		defer resp.Body.Close()
+		var result bulkResponse
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+	get := func(id uint) models.Todo {
+		resp := doJSON(t, client, "GET", fmt.Sprintf("%s/todos/%d", server.URL, id), cookie, nil)
+		defer resp.Body.Close()
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo
+	}
+
+	var ids []uint
+	for i := 0; i < 5; i++ {
+		ids = append(ids, create(cookie, map[string]interface{}{"title": fmt.Sprintf("Item %d", i)}).ID)
+	}
+	foreign := create(other, map[string]interface{}{"title": "Not yours"})
+
+	resp := doJSON(t, client, "POST", server.URL+"/lists", cookie, map[string]interface{}{"name": "Errands"})
+	var list models.List
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	resp = doJSON(t, client, "POST", server.URL+"/tags", cookie, map[string]interface{}{"name": "bulk"})
+	var tag models.Tag
+	json.NewDecoder(resp.Body).Decode(&tag)
+	resp.Body.Close()
+
+	status, result := bulk(map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "complete", "ids": ids[:3]},
+			{"op": "move", "ids": ids[3:], "list_id": list.ID},
+			{"op": "tag", "ids": ids[3:], "add_tag_ids": []uint{tag.ID}},
+			{"op": "update", "ids": ids[4:], "fields": map[string]interface{}{"priority": "urgent", "title": "Renamed"}},
+		},
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, result.Applied)
+	assert.Len(t, result.Results, 8)
+	assert.True(t, get(ids[0]).IsCompleted)
+	assert.Equal(t, list.ID, *get(ids[3]).ListID)
+	renamed := get(ids[4])
+	assert.Equal(t, "Renamed", renamed.Title)
+	assert.Equal(t, models.PriorityUrgent, renamed.Priority)
+	if assert.Len(t, renamed.Tags, 1) {
+		assert.Equal(t, "bulk", renamed.Tags[0].Name)
+	}
+
+	// atomic requests are rejected as a whole when one todo is not owned by the user
+	status, result = bulk(map[string]interface{}{
+		"operations": []map[string]interface{}{{"op": "delete", "ids": []uint{ids[0], foreign.ID}}},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.False(t, result.Applied)
+	if assert.Len(t, result.Results, 1) {
+		assert.Equal(t, foreign.ID, result.Results[0].ID)
+		assert.Equal(t, http.StatusUnauthorized, result.Results[0].Status)
+	}
+	assert.Equal(t, ids[0], get(ids[0]).ID)
+
+	// and rolled back when an item fails while applying
+	status, result = bulk(map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "uncomplete", "ids": ids[:1]},
+			{"op": "move", "ids": ids[1:2], "list_id": 999999},
+		},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.False(t, result.Applied)
+	assert.Equal(t, "Unknown list_id", result.Results[len(result.Results)-1].Error)
+	assert.True(t, get(ids[0]).IsCompleted)
+
+	// partial requests apply everything that can be applied
+	status, result = bulk(map[string]interface{}{
+		"mode": "partial",
+		"operations": []map[string]interface{}{
+			{"op": "uncomplete", "ids": ids[:1]},
+			{"op": "move", "ids": ids[1:2], "list_id": 999999},
+			{"op": "delete", "ids": []uint{ids[2], foreign.ID}},
+		},
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, result.Applied)
+	statuses := map[uint]int{}
+	for _, item := range result.Results {
+		statuses[item.ID] = item.Status
+	}
+	assert.Equal(t, map[uint]int{ids[0]: 200, ids[1]: 400, ids[2]: 200, foreign.ID: 401}, statuses)
+	assert.False(t, get(ids[0]).IsCompleted)
+	resp = doJSON(t, client, "GET", fmt.Sprintf("%s/todos/%d", server.URL, ids[2]), cookie, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	// a bulk request is undone as a whole
+	resp = doJSON(t, client, "POST", server.URL+"/undo", cookie, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	assert.True(t, get(ids[0]).IsCompleted)
+	assert.Equal(t, ids[2], get(ids[2]).ID)
+
+	// malformed requests
+	for _, payload := range []map[string]interface{}{
+		{"operations": []map[string]interface{}{}},
+		{"mode": "sometimes", "operations": []map[string]interface{}{{"op": "complete", "ids": ids}}},
+		{"operations": []map[string]interface{}{{"op": "archive", "ids": ids}}},
+		{"operations": []map[string]interface{}{{"op": "move", "ids": ids}}},
+		{"operations": []map[string]interface{}{{"op": "update", "ids": ids, "fields": map[string]interface{}{"priority": "asap"}}}},
+	} {
+		status, _ := bulk(payload)
+		assert.Equal(t, http.StatusBadRequest, status, payload)
+	}
+}
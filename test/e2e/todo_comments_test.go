@@ -0,0 +1,85 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoComments(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "commentuser")
+	other := registerAndLogin(t, client, server.URL, "commentother")
+
+	resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": "Discuss me"})
+	var todo models.Todo
+	json.NewDecoder(resp.Body).Decode(&todo)
+	resp.Body.Close()
+	commentsURL := fmt.Sprintf("%s/todos/%d/comments", server.URL, todo.ID)
+
+	resp = doJSON(t, client, "POST", commentsURL, cookie, map[string]interface{}{
+		"body": "Looks **good**, see [docs](https://example.com) <script>alert(1)</script>",
+	})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var comment models.Comment
+	json.NewDecoder(resp.Body).Decode(&comment)
+	resp.Body.Close()
+	assert.Equal(t, todo.ID, comment.TodoID)
+	assert.Nil(t, comment.EditedAt)
+	assert.Equal(t, `<p>Looks <strong>good</strong>, see <a href="https://example.com" rel="nofollow noopener">docs</a> &lt;script&gt;alert(1)&lt;/script&gt;</p>`+"\n", comment.BodyHTML)
+
+	resp = doJSON(t, client, "POST", commentsURL, cookie, map[string]interface{}{"body": "   "})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	// editing sets edited_at and renders the new body
+	commentURL := fmt.Sprintf("%s/%d", commentsURL, comment.ID)
+	resp = doJSON(t, client, "PUT", commentURL, cookie, map[string]interface{}{"body": "Changed *my* mind"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var edited models.Comment
+	json.NewDecoder(resp.Body).Decode(&edited)
+	resp.Body.Close()
+	assert.NotNil(t, edited.EditedAt)
+	assert.Equal(t, "<p>Changed <em>my</em> mind</p>\n", edited.BodyHTML)
+
+	resp = doJSON(t, client, "GET", commentsURL, cookie, nil)
+	var comments []models.Comment
+	json.NewDecoder(resp.Body).Decode(&comments)
+	resp.Body.Close()
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, "Changed *my* mind", comments[0].Body)
+		assert.NotEmpty(t, comments[0].BodyHTML)
+	}
+
+	// users who cannot see the todo cannot read or write its comments, nor the todo itself
+	for _, req := range []struct{ method, url string }{
+		{"GET", commentsURL},
+		{"POST", commentsURL},
+		{"PUT", commentURL},
+		{"DELETE", commentURL},
+		{"GET", fmt.Sprintf("%s/todos/%d", server.URL, todo.ID)},
+	} {
+		resp = doJSON(t, client, req.method, req.url, other, map[string]interface{}{"body": "hi"})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "%s %s", req.method, req.url)
+		resp.Body.Close()
+	}
+
+	resp = doJSON(t, client, "DELETE", commentURL, cookie, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "PUT", commentURL, cookie, map[string]interface{}{"body": "gone"})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
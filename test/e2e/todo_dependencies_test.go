@@ -0,0 +1,139 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoDependencies(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "dependencyuser")
+	other := registerAndLogin(t, client, server.URL, "dependencyother")
+
+	create := func(cookie *http.Cookie, payload map[string]interface{}) models.Todo {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		defer resp.Body.Close()
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo
+	}
+	block := func(todo, blocker uint) int {
+		resp := doJSON(t, client, "POST", fmt.Sprintf("%s/todos/%d/dependencies", server.URL, todo), cookie,
+			map[string]interface{}{"blocked_by_id": blocker})
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	complete := func(todo uint, query string) int {
+		resp := doJSON(t, client, "PUT", fmt.Sprintf("%s/todos/%d%s", server.URL, todo, query), cookie,
+			map[string]interface{}{"is_completed": true})
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	next := func() []services.NextTodo {
+		resp := doJSON(t, client, "GET", server.URL+"/todos/next", cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var next []services.NextTodo
+		json.NewDecoder(resp.Body).Decode(&next)
+		return next
+	}
+	titles := func(next []services.NextTodo) []string {
+		var titles []string
+		for _, n := range next {
+			titles = append(titles, n.Todo.Title)
+		}
+		return titles
+	}
+
+	// design -> build -> ship, with an urgent todo that is not blocked by anything
+	design := create(cookie, map[string]interface{}{"title": "Design"})
+	build := create(cookie, map[string]interface{}{"title": "Build"})
+	ship := create(cookie, map[string]interface{}{"title": "Ship", "priority": "urgent"})
+	urgent := create(cookie, map[string]interface{}{"title": "Hotfix", "priority": "high"})
+
+	assert.Equal(t, http.StatusCreated, block(build.ID, design.ID))
+	assert.Equal(t, http.StatusCreated, block(ship.ID, build.ID))
+
+	// cycles, self references and other users' todos are rejected
+	assert.Equal(t, http.StatusConflict, block(design.ID, ship.ID))
+	assert.Equal(t, http.StatusConflict, block(design.ID, build.ID))
+	assert.Equal(t, http.StatusBadRequest, block(design.ID, design.ID))
+	foreign := create(other, map[string]interface{}{"title": "Not yours"})
+	assert.Equal(t, http.StatusBadRequest, block(design.ID, foreign.ID))
+
+	resp := doJSON(t, client, "GET", fmt.Sprintf("%s/todos/%d/dependencies", server.URL, build.ID), cookie, nil)
+	var graph services.DependencyGraph
+	json.NewDecoder(resp.Body).Decode(&graph)
+	resp.Body.Close()
+	assert.Equal(t, build.ID, graph.TodoID)
+	assert.Len(t, graph.Todos, 3)
+	var edges []string
+	for _, edge := range graph.Edges {
+		edges = append(edges, fmt.Sprintf("%d<-%d", edge.TodoID, edge.BlockedByID))
+	}
+	assert.ElementsMatch(t, []string{
+		fmt.Sprintf("%d<-%d", build.ID, design.ID),
+		fmt.Sprintf("%d<-%d", ship.ID, build.ID),
+	}, edges)
+
+	resp = doJSON(t, client, "GET", fmt.Sprintf("%s/todos/%d/dependencies", server.URL, build.ID), other, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	// ship is the most urgent but has to wait for its blockers
+	order := next()
+	assert.Equal(t, []string{"Hotfix", "Design", "Build", "Ship"}, titles(order))
+	assert.Equal(t, []uint{}, order[0].BlockedBy)
+	assert.Equal(t, []uint{build.ID}, order[3].BlockedBy)
+
+	// blocked todos can only be completed with force
+	assert.Equal(t, http.StatusConflict, complete(build.ID, ""))
+	assert.Equal(t, http.StatusOK, complete(design.ID, ""))
+	assert.Equal(t, http.StatusOK, complete(build.ID, ""))
+	assert.Equal(t, []string{"Ship", "Hotfix"}, titles(next()))
+
+	assert.Equal(t, http.StatusCreated, block(urgent.ID, ship.ID))
+	assert.Equal(t, http.StatusConflict, complete(urgent.ID, ""))
+	assert.Equal(t, http.StatusOK, complete(urgent.ID, "?force=true"))
+
+	// redoing a completion checks the blockers again
+	replay := func(path string) int {
+		resp := doJSON(t, client, "POST", server.URL+path, cookie, nil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusOK, replay("/undo"))
+	assert.Equal(t, http.StatusConflict, replay("/redo"))
+	assert.Equal(t, []string{"Ship", "Hotfix"}, titles(next()))
+
+	// removing a dependency unblocks the todo
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/todos/%d/dependencies/%d", server.URL, ship.ID, build.ID), cookie, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/todos/%d/dependencies/%d", server.URL, ship.ID, build.ID), cookie, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = doJSON(t, client, "GET", fmt.Sprintf("%s/todos/%d/history", server.URL, ship.ID), cookie, nil)
+	var history []models.TodoHistory
+	json.NewDecoder(resp.Body).Decode(&history)
+	resp.Body.Close()
+	if assert.Len(t, history, 3) {
+		assert.JSONEq(t, fmt.Sprintf("[%d]", build.ID), string(history[2].Changes["blocked_by"].Before))
+		assert.JSONEq(t, "[]", string(history[2].Changes["blocked_by"].After))
+	}
+}
@@ -0,0 +1,104 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoDueDates(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "dueuser")
+
+	now := time.Now().UTC()
+	payloads := []map[string]interface{}{
+		{"title": "Overdue", "due_date": now.Add(-48 * time.Hour).Format(time.RFC3339)},
+		{"title": "Done late", "due_date": now.Add(-24 * time.Hour).Format(time.RFC3339), "is_completed": true},
+		{"title": "Next week", "due_date": now.Add(7 * 24 * time.Hour).Format(time.RFC3339), "due_timezone": "Europe/Berlin"},
+		{"title": "Someday"},
+	}
+	for _, payload := range payloads {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	// an unknown time zone is rejected
+	resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{
+		"title": "Bad zone", "due_date": now.Format(time.RFC3339), "due_timezone": "Mars/Olympus",
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	titles := func(query string) []string {
+		resp := doJSON(t, client, "GET", server.URL+"/todos?"+query, cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		var result []string
+		for _, todo := range todos {
+			result = append(result, todo.Title)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"Overdue"}, titles("overdue=true"))
+	assert.ElementsMatch(t, []string{"Overdue", "Done late"}, titles("due_before="+url.QueryEscape(now.Format(time.RFC3339))))
+	assert.Equal(t, []string{"Next week"}, titles("due_after="+url.QueryEscape(now.Format(time.RFC3339))))
+	assert.Len(t, titles(""), 4)
+
+	resp = doJSON(t, client, "GET", server.URL+"/todos?due_before=yesterday", cookie, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestRecurringTodo(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "recurringuser")
+
+	resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{
+		"title": "Take out bins", "due_date": "2025-01-06T07:00:00Z", "recurrence": "FREQ=WEEKLY;COUNT=2",
+	})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var todo models.Todo
+	json.NewDecoder(resp.Body).Decode(&todo)
+	resp.Body.Close()
+
+	resp = doJSON(t, client, "PUT", server.URL+"/todos/"+strconv.Itoa(int(todo.ID)), cookie, map[string]interface{}{"is_completed": true})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var completed models.Todo
+	json.NewDecoder(resp.Body).Decode(&completed)
+	resp.Body.Close()
+
+	assert.Empty(t, completed.Recurrence)
+	if assert.NotNil(t, completed.Next) {
+		assert.Equal(t, time.Date(2025, 1, 13, 7, 0, 0, 0, time.UTC), completed.Next.DueDate.UTC())
+		assert.Contains(t, completed.Next.Recurrence, "COUNT=1")
+	}
+
+	resp = doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": "Bad", "recurrence": "FREQ=SOMETIMES"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
@@ -0,0 +1,82 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoHistory(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "historyuser")
+	other := registerAndLogin(t, client, server.URL, "historyother")
+
+	resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": "Write report", "priority": "low"})
+	var todo models.Todo
+	json.NewDecoder(resp.Body).Decode(&todo)
+	resp.Body.Close()
+	todoURL := fmt.Sprintf("%s/todos/%d", server.URL, todo.ID)
+
+	resp = doJSON(t, client, "PUT", todoURL, cookie, map[string]interface{}{"title": "Write annual report", "priority": "high"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	// an update without changes is not recorded
+	resp = doJSON(t, client, "PUT", todoURL, cookie, map[string]interface{}{})
+	resp.Body.Close()
+	resp = doJSON(t, client, "PUT", todoURL, cookie, map[string]interface{}{"is_completed": true})
+	resp.Body.Close()
+	resp = doJSON(t, client, "DELETE", todoURL, cookie, nil)
+	resp.Body.Close()
+
+	getHistory := func(cookie *http.Cookie) (*http.Response, []models.TodoHistory) {
+		resp := doJSON(t, client, "GET", todoURL+"/history", cookie, nil)
+		defer resp.Body.Close()
+		var history []models.TodoHistory
+		json.NewDecoder(resp.Body).Decode(&history)
+		return resp, history
+	}
+
+	resp, history := getHistory(cookie)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if !assert.Len(t, history, 4) {
+		return
+	}
+	var actions []string
+	for _, entry := range history {
+		actions = append(actions, entry.Action)
+		if assert.NotNil(t, entry.ActorID) {
+			assert.Equal(t, todo.UserID, *entry.ActorID)
+		}
+	}
+	assert.Equal(t, []string{"created", "updated", "completed", "deleted"}, actions)
+
+	created := history[0].Changes
+	assert.JSONEq(t, `"Write report"`, string(created["title"].After))
+	assert.JSONEq(t, `null`, string(created["title"].Before))
+	assert.NotContains(t, created, "description")
+
+	updated := history[1].Changes
+	assert.Len(t, updated, 2)
+	assert.JSONEq(t, `"Write report"`, string(updated["title"].Before))
+	assert.JSONEq(t, `"Write annual report"`, string(updated["title"].After))
+	assert.JSONEq(t, `"low"`, string(updated["priority"].Before))
+	assert.JSONEq(t, `"high"`, string(updated["priority"].After))
+
+	assert.JSONEq(t, `true`, string(history[2].Changes["is_completed"].After))
+	assert.Empty(t, history[3].Changes)
+
+	resp, _ = getHistory(other)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,158 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+	"todo-list/pkg/jwt"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWTAuthMode(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	previous, _ := jwt.NewHS256Key("2024", bytes.Repeat([]byte("p"), 32))
+	current, _ := jwt.NewEdDSAKey("2025", bytes.Repeat([]byte{2}, 32))
+	server := httptest.NewServer(setupRouterWithAuth(db, jwt.NewKeySet(current, previous)))
+	defer server.Close()
+
+	client := &http.Client{}
+	login := func(username string) services.TokenPair {
+		resp := doJSON(t, client, "POST", server.URL+"/login", nil, map[string]interface{}{"username": username, "password": "password123"})
+		var tokens services.TokenPair
+		json.NewDecoder(resp.Body).Decode(&tokens)
+		resp.Body.Close()
+		return tokens
+	}
+	refresh := func(refreshToken string) (int, services.TokenPair) {
+		resp := doJSON(t, client, "POST", server.URL+"/token/refresh", nil, map[string]interface{}{"refresh_token": refreshToken})
+		var tokens services.TokenPair
+		json.NewDecoder(resp.Body).Decode(&tokens)
+		resp.Body.Close()
+		return resp.StatusCode, tokens
+	}
+	status := func(method, url, token string, payload interface{}) int {
+		resp := doBearer(t, client, method, url, token, payload)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for _, username := range []string{"jwtalice", "jwtbob"} {
+		resp := doJSON(t, client, "POST", server.URL+"/register", nil, map[string]interface{}{"username": username, "password": "password123"})
+		resp.Body.Close()
+	}
+	alice := login("jwtalice")
+	assert.Equal(t, "Bearer", alice.TokenType)
+	assert.Equal(t, 60, alice.ExpiresIn)
+
+	// access tokens replace the session cookie
+	assert.Equal(t, http.StatusUnauthorized, status("GET", server.URL+"/todos", "", nil))
+	resp := doBearer(t, client, "POST", server.URL+"/todos", alice.AccessToken, map[string]interface{}{"title": "Scale out"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var todo models.Todo
+	json.NewDecoder(resp.Body).Decode(&todo)
+	resp.Body.Close()
+	todoURL := fmt.Sprintf("%s/todos/%d", server.URL, todo.ID)
+	assert.Equal(t, http.StatusOK, status("GET", todoURL, alice.AccessToken, nil))
+
+	// tokens of the previous key stay valid after a rotation, others are rejected
+	claims := jwt.Claims{Subject: strconv.FormatUint(uint64(todo.UserID), 10), ExpiresAt: time.Now().Add(time.Minute).Unix()}
+	old, _ := jwt.NewKeySet(previous).Sign(claims)
+	assert.Equal(t, http.StatusOK, status("GET", todoURL, old, nil))
+	stranger, _ := jwt.NewHS256Key("2025", bytes.Repeat([]byte("x"), 32))
+	forged, _ := jwt.NewKeySet(stranger).Sign(claims)
+	assert.Equal(t, http.StatusUnauthorized, status("GET", todoURL, forged, nil))
+	claims.ExpiresAt = time.Now().Add(-time.Second).Unix()
+	expired, _ := jwt.NewKeySet(current).Sign(claims)
+	assert.Equal(t, http.StatusUnauthorized, status("GET", todoURL, expired, nil))
+
+	// refresh tokens rotate, reusing one revokes the whole login
+	code, rotated := refresh(alice.RefreshToken)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEqual(t, alice.RefreshToken, rotated.RefreshToken)
+	assert.Equal(t, http.StatusOK, status("GET", todoURL, rotated.AccessToken, nil))
+	code, _ = refresh(alice.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = refresh(rotated.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code, "the stolen token family is revoked")
+
+	alice = login("jwtalice")
+	resp = doJSON(t, client, "POST", server.URL+"/logout", nil, map[string]interface{}{"refresh_token": alice.RefreshToken})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+	code, _ = refresh(alice.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// the organization is picked per request
+	alice = login("jwtalice")
+	resp = doBearer(t, client, "POST", server.URL+"/orgs", alice.AccessToken, map[string]interface{}{"name": "Scale Inc"})
+	var org models.Organization
+	json.NewDecoder(resp.Body).Decode(&org)
+	resp.Body.Close()
+	orgHeader := map[string]string{"X-Org-ID": strconv.FormatUint(uint64(org.ID), 10)}
+	resp = doBearer(t, client, "POST", server.URL+"/todos", alice.AccessToken, map[string]interface{}{"title": "Hire"}, orgHeader)
+	var orgTodo models.Todo
+	json.NewDecoder(resp.Body).Decode(&orgTodo)
+	resp.Body.Close()
+	if assert.NotNil(t, orgTodo.OrgID) {
+		assert.Equal(t, org.ID, *orgTodo.OrgID)
+	}
+	bob := login("jwtbob")
+	resp = doBearer(t, client, "GET", server.URL+"/todos", bob.AccessToken, nil, orgHeader)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "only members may work in an organization")
+
+	// personal access tokens work as well, but cannot manage tokens
+	resp = doBearer(t, client, "POST", server.URL+"/me/tokens", alice.AccessToken, map[string]interface{}{"name": "CI", "scope": "write"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var personal models.APIToken
+	json.NewDecoder(resp.Body).Decode(&personal)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, status("GET", todoURL, personal.Token, nil))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", server.URL+"/me/tokens", personal.Token, nil))
+
+	// admins log users out by revoking their refresh tokens
+	db.Model(&models.User{}).Where("username = ?", "jwtalice").Update("role", models.RoleAdmin)
+	var bobUser models.User
+	db.Where("username = ?", "jwtbob").First(&bobUser)
+	resp = doBearer(t, client, "POST", fmt.Sprintf("%s/admin/users/%d/logout", server.URL, bobUser.ID), alice.AccessToken, nil)
+	var ended map[string]int
+	json.NewDecoder(resp.Body).Decode(&ended)
+	resp.Body.Close()
+	assert.Equal(t, 1, ended["sessions"])
+	code, _ = refresh(bob.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+// doBearer sends a JSON request authenticated by an "Authorization: Bearer" token, with optional extra headers
+func doBearer(t *testing.T, client *http.Client, method, url, token string, payload interface{}, headers ...map[string]string) *http.Response {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req, _ := http.NewRequest(method, url, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for _, extra := range headers {
+		for name, value := range extra {
+			req.Header.Set(name, value)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	return resp
+}
@@ -0,0 +1,101 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoLists(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "listuser")
+	otherCookie := registerAndLogin(t, client, server.URL, "otherlistuser")
+
+	getLists := func(cookie *http.Cookie) []models.List {
+		resp := doJSON(t, client, "GET", server.URL+"/lists", cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var lists []models.List
+		json.NewDecoder(resp.Body).Decode(&lists)
+		return lists
+	}
+
+	// registering creates the inbox
+	lists := getLists(cookie)
+	if assert.Len(t, lists, 1) {
+		assert.True(t, lists[0].IsInbox)
+	}
+	inbox := lists[0]
+
+	resp := doJSON(t, client, "POST", server.URL+"/lists", cookie, map[string]interface{}{"name": "Work"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var work models.List
+	json.NewDecoder(resp.Body).Decode(&work)
+	resp.Body.Close()
+
+	// todos without a list land in the inbox
+	resp = doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": "Loose"})
+	var loose models.Todo
+	json.NewDecoder(resp.Body).Decode(&loose)
+	resp.Body.Close()
+	if assert.NotNil(t, loose.ListID) {
+		assert.Equal(t, inbox.ID, *loose.ListID)
+	}
+
+	resp = doJSON(t, client, "POST", fmt.Sprintf("%s/lists/%d/todos", server.URL, work.ID), cookie, map[string]interface{}{"title": "Report"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	listTitles := func(listID uint) []string {
+		resp := doJSON(t, client, "GET", fmt.Sprintf("%s/lists/%d/todos", server.URL, listID), cookie, nil)
+		defer resp.Body.Close()
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		var titles []string
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"Report"}, listTitles(work.ID))
+
+	// move a todo between lists
+	resp = doJSON(t, client, "PUT", fmt.Sprintf("%s/todos/%d", server.URL, loose.ID), cookie, map[string]interface{}{"list_id": work.ID})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	assert.ElementsMatch(t, []string{"Report", "Loose"}, listTitles(work.ID))
+
+	// lists of other users are off limits
+	otherInbox := getLists(otherCookie)[0]
+	resp = doJSON(t, client, "PUT", fmt.Sprintf("%s/todos/%d", server.URL, loose.ID), cookie, map[string]interface{}{"list_id": otherInbox.ID})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "GET", fmt.Sprintf("%s/lists/%d/todos", server.URL, otherInbox.ID), cookie, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "GET", server.URL+"/lists/0%20OR%201=1", cookie, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "ids are never run as SQL")
+	resp.Body.Close()
+
+	// deleting a list moves its todos to the inbox, the inbox itself stays
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/lists/%d", server.URL, work.ID), cookie, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+	assert.ElementsMatch(t, []string{"Report", "Loose"}, listTitles(inbox.ID))
+
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/lists/%d", server.URL, inbox.ID), cookie, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+}
@@ -0,0 +1,106 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualOrdering(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "orderuser")
+	other := registerAndLogin(t, client, server.URL, "orderother")
+
+	create := func(cookie *http.Cookie, payload map[string]interface{}) models.Todo {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		defer resp.Body.Close()
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo
+	}
+	move := func(id uint, anchors map[string]interface{}) int {
+		resp := doJSON(t, client, "POST", fmt.Sprintf("%s/todos/%d/move", server.URL, id), cookie, anchors)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	order := func(query string) string {
+		resp := doJSON(t, client, "GET", server.URL+"/todos?sort=position"+query, cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		var titles []string
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		return strings.Join(titles, ",")
+	}
+
+	a := create(cookie, map[string]interface{}{"title": "A"})
+	b := create(cookie, map[string]interface{}{"title": "B"})
+	c := create(cookie, map[string]interface{}{"title": "C"})
+	assert.Equal(t, "A,B,C", order(""))
+
+	assert.Equal(t, http.StatusOK, move(c.ID, map[string]interface{}{"after_id": a.ID}))
+	assert.Equal(t, "A,C,B", order(""))
+	assert.Equal(t, http.StatusOK, move(b.ID, map[string]interface{}{"before_id": a.ID}))
+	assert.Equal(t, "B,A,C", order(""))
+	assert.Equal(t, http.StatusOK, move(b.ID, map[string]interface{}{"after_id": c.ID}))
+	assert.Equal(t, "A,C,B", order(""))
+	assert.Equal(t, http.StatusOK, move(b.ID, map[string]interface{}{"after_id": a.ID, "before_id": c.ID}))
+	assert.Equal(t, "A,B,C", order(""))
+	assert.Equal(t, "C,B,A", order("&order=desc"))
+
+	// pagination works on positions as well
+	resp := doJSON(t, client, "GET", server.URL+"/todos?sort=position&limit=2", cookie, nil)
+	next := resp.Header.Get("X-Next-Cursor")
+	resp.Body.Close()
+	assert.Equal(t, "C", order("&limit=2&cursor="+next))
+
+	// anchors must be other todos of the same user, in the right order
+	assert.Equal(t, http.StatusBadRequest, move(a.ID, map[string]interface{}{"after_id": a.ID}))
+	assert.Equal(t, http.StatusBadRequest, move(a.ID, map[string]interface{}{"after_id": c.ID, "before_id": b.ID}))
+	assert.Equal(t, http.StatusBadRequest, move(a.ID, map[string]interface{}{}))
+	foreign := create(other, map[string]interface{}{"title": "Not yours"})
+	assert.Equal(t, http.StatusBadRequest, move(a.ID, map[string]interface{}{"after_id": foreign.ID}))
+
+	// moving next to a todo of another list moves it into that list
+	resp = doJSON(t, client, "POST", server.URL+"/lists", cookie, map[string]interface{}{"name": "Later"})
+	var list models.List
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	d := create(cookie, map[string]interface{}{"title": "D", "list_id": list.ID})
+	assert.Equal(t, http.StatusOK, move(a.ID, map[string]interface{}{"before_id": d.ID}))
+	assert.Equal(t, "A,D", order(fmt.Sprintf("&list_id=%d", list.ID)))
+
+	// long keys are spread out again by the rebalance, keeping the order
+	db.Model(&models.Todo{}).Where("id = ?", b.ID).Update("position", "V0000000000001")
+	db.Model(&models.Todo{}).Where("id = ?", c.ID).Update("position", "V0000000000002")
+	todoService := services.NewTodoService(repos.NewTodoRepository(db), repos.NewListRepository(db))
+	rebalanced, err := todoService.RebalancePositions()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, rebalanced, 1)
+	resp = doJSON(t, client, "GET", fmt.Sprintf("%s/todos?sort=position&list_id=%d", server.URL, *b.ListID), cookie, nil)
+	var todos []models.Todo
+	json.NewDecoder(resp.Body).Decode(&todos)
+	resp.Body.Close()
+	if assert.Len(t, todos, 2) {
+		assert.Equal(t, "B", todos[0].Title)
+		assert.LessOrEqual(t, len(todos[1].Position), services.MaxPositionLength)
+	}
+}
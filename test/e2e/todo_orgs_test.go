@@ -0,0 +1,182 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganizations(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	owner := registerAndLogin(t, client, server.URL, "orgowner")
+	admin := registerAndLogin(t, client, server.URL, "orgadmin")
+	member := registerAndLogin(t, client, server.URL, "orgmember")
+	outsider := registerAndLogin(t, client, server.URL, "orgoutsider")
+
+	status := func(method, url string, cookie *http.Cookie, payload interface{}) int {
+		resp := doJSON(t, client, method, url, cookie, payload)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	createTodo := func(cookie *http.Cookie, payload map[string]interface{}) models.Todo {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		resp.Body.Close()
+		return todo
+	}
+	visibleIDs := func(cookie *http.Cookie) []uint {
+		resp := doJSON(t, client, "GET", server.URL+"/todos", cookie, nil)
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		resp.Body.Close()
+		ids := []uint{}
+		for _, todo := range todos {
+			ids = append(ids, todo.ID)
+		}
+		return ids
+	}
+	switchTo := func(cookie *http.Cookie, orgID interface{}) int {
+		return status("PUT", server.URL+"/me/org", cookie, map[string]interface{}{"org_id": orgID})
+	}
+
+	personal := createTodo(owner, map[string]interface{}{"title": "Dentist appointment"})
+
+	resp := doJSON(t, client, "POST", server.URL+"/orgs", owner, map[string]interface{}{"name": "Acme"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var org models.Organization
+	json.NewDecoder(resp.Body).Decode(&org)
+	resp.Body.Close()
+	assert.Equal(t, models.OrgOwner, org.Role)
+	orgURL := fmt.Sprintf("%s/orgs/%d", server.URL, org.ID)
+
+	// invitations by username
+	assert.Equal(t, http.StatusNotFound, status("POST", orgURL+"/invitations", owner, map[string]interface{}{"username": "nobody"}))
+	assert.Equal(t, http.StatusBadRequest, status("POST", orgURL+"/invitations", owner, map[string]interface{}{"username": "orgmember", "role": "boss"}))
+	assert.Equal(t, http.StatusCreated, status("POST", orgURL+"/invitations", owner, map[string]interface{}{"username": "orgmember"}))
+	assert.Equal(t, http.StatusCreated, status("POST", orgURL+"/invitations", owner, map[string]interface{}{"username": "orgadmin", "role": "admin"}))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", orgURL, outsider, nil))
+
+	for _, cookie := range []*http.Cookie{member, admin} {
+		resp = doJSON(t, client, "GET", server.URL+"/me/invitations", cookie, nil)
+		var invitations []models.Invitation
+		json.NewDecoder(resp.Body).Decode(&invitations)
+		resp.Body.Close()
+		if assert.Len(t, invitations, 1) {
+			assert.Equal(t, "Acme", invitations[0].OrgName)
+			assert.Equal(t, http.StatusNotFound, status("POST", fmt.Sprintf("%s/me/invitations/%d/accept", server.URL, invitations[0].ID), outsider, nil))
+			assert.Equal(t, http.StatusOK, status("POST", fmt.Sprintf("%s/me/invitations/%d/accept", server.URL, invitations[0].ID), cookie, nil))
+		}
+	}
+	assert.Equal(t, http.StatusConflict, status("POST", orgURL+"/invitations", owner, map[string]interface{}{"username": "orgmember"}))
+
+	resp = doJSON(t, client, "GET", orgURL+"/members", member, nil)
+	var members []models.Membership
+	json.NewDecoder(resp.Body).Decode(&members)
+	resp.Body.Close()
+	roles := map[string]string{}
+	for _, membership := range members {
+		roles[membership.Username] = membership.Role
+	}
+	assert.Equal(t, map[string]string{"orgowner": "owner", "orgadmin": "admin", "orgmember": "member"}, roles)
+
+	// todos created in the active organization land in its inbox and are visible to all members
+	assert.Equal(t, http.StatusNotFound, switchTo(outsider, org.ID))
+	assert.Equal(t, http.StatusOK, switchTo(owner, org.ID))
+	assert.Equal(t, http.StatusOK, switchTo(member, org.ID))
+	assert.Equal(t, http.StatusOK, switchTo(admin, org.ID))
+
+	launch := createTodo(owner, map[string]interface{}{"title": "Plan the launch"})
+	if assert.NotNil(t, launch.OrgID) {
+		assert.Equal(t, org.ID, *launch.OrgID)
+	}
+	resp = doJSON(t, client, "GET", server.URL+"/lists", member, nil)
+	var lists []models.List
+	json.NewDecoder(resp.Body).Decode(&lists)
+	resp.Body.Close()
+	if assert.Len(t, lists, 1) {
+		assert.True(t, lists[0].IsInbox)
+		assert.Equal(t, *launch.ListID, lists[0].ID)
+	}
+
+	assert.Equal(t, []uint{launch.ID}, visibleIDs(owner), "personal todos stay out of the organization")
+	assert.Equal(t, []uint{launch.ID}, visibleIDs(member))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", fmt.Sprintf("%s/todos/%d", server.URL, personal.ID), member, nil))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", fmt.Sprintf("%s/todos/%d", server.URL, launch.ID), outsider, nil))
+
+	// members change todos, only admins manage lists
+	assert.Equal(t, http.StatusOK, status("PUT", fmt.Sprintf("%s/todos/%d", server.URL, launch.ID), member, map[string]interface{}{"title": "Plan the launch party"}))
+	assert.Equal(t, http.StatusUnauthorized, status("DELETE", fmt.Sprintf("%s/todos/%d", server.URL, launch.ID), member, nil))
+	assert.Equal(t, http.StatusUnauthorized, status("POST", server.URL+"/lists", member, map[string]interface{}{"name": "Roadmap"}))
+	resp = doJSON(t, client, "POST", server.URL+"/lists", admin, map[string]interface{}{"name": "Roadmap"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var roadmap models.List
+	json.NewDecoder(resp.Body).Decode(&roadmap)
+	resp.Body.Close()
+	listURL := fmt.Sprintf("%s/lists/%d", server.URL, roadmap.ID)
+
+	resp = doJSON(t, client, "POST", listURL+"/todos", member, map[string]interface{}{"title": "Write the blog post"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var post models.Todo
+	json.NewDecoder(resp.Body).Decode(&post)
+	resp.Body.Close()
+	assert.ElementsMatch(t, []uint{launch.ID, post.ID}, visibleIDs(owner))
+	assert.Equal(t, http.StatusNoContent, status("DELETE", fmt.Sprintf("%s/todos/%d", server.URL, post.ID), member, nil), "members may delete their own todos")
+	assert.Equal(t, http.StatusBadRequest, status("PUT", fmt.Sprintf("%s/todos/%d/shares/orgoutsider", server.URL, launch.ID), owner, map[string]interface{}{"role": "viewer"}))
+
+	// another organization cannot reach into this one
+	resp = doJSON(t, client, "POST", server.URL+"/orgs", outsider, map[string]interface{}{"name": "Globex"})
+	var other models.Organization
+	json.NewDecoder(resp.Body).Decode(&other)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, switchTo(outsider, other.ID))
+	assert.Empty(t, visibleIDs(outsider))
+	assert.Equal(t, http.StatusBadRequest, status("POST", server.URL+"/todos", outsider, map[string]interface{}{"title": "Steal", "list_id": roadmap.ID}))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", listURL+"/todos", outsider, nil))
+	resp = doJSON(t, client, "GET", server.URL+"/todos/search?q=launch", outsider, nil)
+	var results []map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&results)
+	resp.Body.Close()
+	assert.Empty(t, results)
+
+	// role changes are up to owners, the last owner has to stay
+	assert.Equal(t, http.StatusForbidden, status("DELETE", orgURL+"/members/orgowner", member, nil))
+	assert.Equal(t, http.StatusForbidden, status("POST", orgURL+"/invitations", admin, map[string]interface{}{"username": "orgoutsider", "role": "admin"}))
+	assert.Equal(t, http.StatusForbidden, status("PUT", orgURL+"/members/orgmember", admin, map[string]interface{}{"role": "admin"}))
+	assert.Equal(t, http.StatusConflict, status("PUT", orgURL+"/members/orgowner", owner, map[string]interface{}{"role": "member"}))
+	assert.Equal(t, http.StatusConflict, status("DELETE", orgURL+"/members/orgowner", owner, nil))
+	assert.Equal(t, http.StatusNotFound, status("PUT", orgURL+"/members/orgoutsider", owner, map[string]interface{}{"role": "admin"}))
+
+	// leaving switches back to the personal todos, removed members lose access
+	assert.Equal(t, http.StatusNoContent, status("DELETE", orgURL+"/members/orgmember", member, nil))
+	resp = doJSON(t, client, "GET", server.URL+"/me/org", member, nil)
+	var active *models.Organization
+	json.NewDecoder(resp.Body).Decode(&active)
+	resp.Body.Close()
+	assert.Nil(t, active)
+	assert.NotContains(t, visibleIDs(member), launch.ID)
+	assert.Equal(t, http.StatusUnauthorized, status("GET", fmt.Sprintf("%s/todos/%d", server.URL, launch.ID), member, nil))
+	// nor can they undo their changes to the todos of the organization
+	assert.Equal(t, http.StatusUnauthorized, status("POST", server.URL+"/undo", member, nil))
+	assert.Equal(t, []uint{launch.ID}, visibleIDs(owner), "the deleted todo stays in the trash")
+
+	assert.Equal(t, http.StatusNoContent, status("DELETE", orgURL+"/members/orgadmin", owner, nil))
+	assert.Equal(t, http.StatusForbidden, status("GET", server.URL+"/todos", admin, nil))
+	assert.Equal(t, http.StatusOK, switchTo(admin, nil))
+	assert.Equal(t, http.StatusOK, status("GET", server.URL+"/todos", admin, nil))
+
+	assert.Equal(t, http.StatusOK, switchTo(owner, nil))
+	assert.Equal(t, []uint{personal.ID}, visibleIDs(owner))
+}
@@ -0,0 +1,101 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoPagination(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "pageuser")
+
+	// seven todos, two of them without a due date and two sharing one
+	base := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	dueOffsets := []int{3, -1, 1, 1, -1, 0, 2}
+	for i, offset := range dueOffsets {
+		payload := map[string]interface{}{"title": fmt.Sprintf("todo %d", i), "is_completed": i%2 == 0}
+		if offset >= 0 {
+			payload["due_date"] = base.AddDate(0, 0, offset).Format(time.RFC3339)
+		}
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp.Body.Close()
+	}
+	expected := []string{"todo 5", "todo 2", "todo 3", "todo 6", "todo 0", "todo 1", "todo 4"}
+
+	fetch := func(query string) ([]string, http.Header) {
+		resp := doJSON(t, client, "GET", server.URL+"/todos?"+query, cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		titles := []string{}
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles, resp.Header
+	}
+
+	// walk forwards three at a time
+	var seen []string
+	var pages []http.Header
+	query := "sort=due_date&limit=3"
+	for {
+		titles, header := fetch(query)
+		seen = append(seen, titles...)
+		pages = append(pages, header)
+		next := header.Get("X-Next-Cursor")
+		if next == "" {
+			break
+		}
+		assert.Contains(t, header.Get("Link"), `rel="next"`)
+		query = "sort=due_date&limit=3&cursor=" + url.QueryEscape(next)
+	}
+	assert.Equal(t, expected, seen)
+	assert.Len(t, pages, 3)
+	assert.Empty(t, pages[0].Get("X-Prev-Cursor"))
+
+	// and back from the last page
+	titles, header := fetch("sort=due_date&limit=3&cursor=" + url.QueryEscape(pages[2].Get("X-Prev-Cursor")))
+	assert.Equal(t, expected[3:6], titles)
+	titles, header = fetch("sort=due_date&limit=3&cursor=" + url.QueryEscape(header.Get("X-Prev-Cursor")))
+	assert.Equal(t, expected[0:3], titles)
+	assert.Empty(t, header.Get("X-Prev-Cursor"))
+
+	// descending order keeps todos without a due date last, ties are broken by id
+	titles, _ = fetch("sort=due_date&order=desc&limit=10")
+	assert.Equal(t, []string{"todo 0", "todo 6", "todo 3", "todo 2", "todo 5", "todo 4", "todo 1"}, titles)
+
+	titles, _ = fetch("completed=true")
+	assert.Equal(t, []string{"todo 0", "todo 2", "todo 4", "todo 6"}, titles)
+	titles, _ = fetch("created_after=" + url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339)))
+	assert.Empty(t, titles)
+	// dates with an offset are compared as the same instant in UTC
+	titles, _ = fetch("created_before=" + url.QueryEscape(time.Now().Add(time.Hour).In(time.FixedZone("", -12*3600)).Format(time.RFC3339)))
+	assert.Len(t, titles, 7)
+	titles, _ = fetch("updated_after=" + url.QueryEscape(time.Now().Add(-time.Hour).In(time.FixedZone("", 14*3600)).Format(time.RFC3339)))
+	assert.Len(t, titles, 7)
+
+	// a cursor only works with the sort order it was issued for
+	resp := doJSON(t, client, "GET", server.URL+"/todos?sort=priority&cursor="+url.QueryEscape(pages[0].Get("X-Next-Cursor")), cookie, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "GET", server.URL+"/todos?limit=1000", cookie, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
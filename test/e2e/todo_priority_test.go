@@ -0,0 +1,55 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoPrioritySorting(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "priorityuser")
+
+	for _, payload := range []map[string]interface{}{
+		{"title": "Low", "priority": "low"},
+		{"title": "Urgent", "priority": "urgent"},
+		{"title": "Unset"},
+		{"title": "High", "priority": "high"},
+	} {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": "Bad", "priority": "critical"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = doJSON(t, client, "GET", server.URL+"/todos?sort=priority&order=desc", cookie, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var todos []models.Todo
+	json.NewDecoder(resp.Body).Decode(&todos)
+	resp.Body.Close()
+
+	var titles []string
+	for _, todo := range todos {
+		titles = append(titles, todo.Title)
+	}
+	assert.Equal(t, []string{"Urgent", "High", "Low", "Unset"}, titles)
+	assert.Equal(t, models.PriorityUrgent, todos[0].Priority)
+
+	resp = doJSON(t, client, "GET", server.URL+"/todos?sort=title", cookie, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
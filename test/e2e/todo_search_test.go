@@ -0,0 +1,80 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"todo-list/internal/repos"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoSearch(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "searchuser")
+	otherCookie := registerAndLogin(t, client, server.URL, "othersearchuser")
+
+	for _, payload := range []map[string]interface{}{
+		{"title": "Renew passport <asap>", "description": "Book an appointment at the town hall"},
+		{"title": "Groceries", "description": "Milk, bread and passport photos"},
+		{"title": "Call mum"},
+	} {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp.Body.Close()
+	}
+	resp := doJSON(t, client, "POST", server.URL+"/todos", otherCookie, map[string]interface{}{"title": "Passport of someone else"})
+	resp.Body.Close()
+
+	search := func(q string) []repos.SearchResult {
+		resp := doJSON(t, client, "GET", server.URL+"/todos/search?q="+url.QueryEscape(q), cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var results []repos.SearchResult
+		json.NewDecoder(resp.Body).Decode(&results)
+		return results
+	}
+
+	results := search("passport")
+	if assert.Len(t, results, 2) {
+		// the title match ranks first
+		assert.Equal(t, "Renew passport <asap>", results[0].Todo.Title)
+		assert.Contains(t, results[0].Snippet, "<mark>passport</mark>")
+		assert.Contains(t, results[0].Snippet, "&lt;asap&gt;")
+		assert.Greater(t, results[0].Rank, results[1].Rank)
+	}
+
+	results = search("passport appointment")
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "Renew passport <asap>", results[0].Todo.Title)
+	}
+	assert.Empty(t, search("dentist"))
+
+	// todos shared with the user are found like they are listed
+	resp = doJSON(t, client, "POST", server.URL+"/todos", otherCookie, map[string]interface{}{"title": "Dentist appointment"})
+	var shared struct{ ID uint }
+	json.NewDecoder(resp.Body).Decode(&shared)
+	resp.Body.Close()
+	resp = doJSON(t, client, "PUT", fmt.Sprintf("%s/todos/%d/shares/searchuser", server.URL, shared.ID), otherCookie, map[string]interface{}{"role": "viewer"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	results = search("dentist")
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, shared.ID, results[0].Todo.ID)
+	}
+	assert.Len(t, search("passport"), 2)
+
+	resp = doJSON(t, client, "GET", server.URL+"/todos/search", cookie, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
@@ -0,0 +1,152 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoSharing(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	owner := registerAndLogin(t, client, server.URL, "shareowner")
+	friend := registerAndLogin(t, client, server.URL, "sharefriend")
+	stranger := registerAndLogin(t, client, server.URL, "sharestranger")
+
+	create := func(payload map[string]interface{}) models.Todo {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", owner, payload)
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		resp.Body.Close()
+		return todo
+	}
+	status := func(method, url string, cookie *http.Cookie, payload interface{}) int {
+		resp := doJSON(t, client, method, url, cookie, payload)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	visibleIDs := func(cookie *http.Cookie) []uint {
+		resp := doJSON(t, client, "GET", server.URL+"/todos", cookie, nil)
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		resp.Body.Close()
+		ids := []uint{}
+		for _, todo := range todos {
+			ids = append(ids, todo.ID)
+		}
+		return ids
+	}
+
+	parent := create(map[string]interface{}{"title": "Plan the trip"})
+	child := create(map[string]interface{}{"title": "Book flights", "parent_id": parent.ID})
+	private := create(map[string]interface{}{"title": "Birthday present"})
+	todoURL := fmt.Sprintf("%s/todos/%d", server.URL, parent.ID)
+	childURL := fmt.Sprintf("%s/todos/%d", server.URL, child.ID)
+
+	assert.Equal(t, http.StatusUnauthorized, status("GET", todoURL, friend, nil))
+	assert.Equal(t, http.StatusNotFound, status("PUT", todoURL+"/shares/nobody", owner, map[string]interface{}{"role": "viewer"}))
+	assert.Equal(t, http.StatusBadRequest, status("PUT", todoURL+"/shares/sharefriend", owner, map[string]interface{}{"role": "admin"}))
+	assert.Equal(t, http.StatusBadRequest, status("PUT", todoURL+"/shares/shareowner", owner, map[string]interface{}{"role": "viewer"}))
+
+	// a viewer sees the todo with its subtasks, may comment but not change it
+	resp := doJSON(t, client, "PUT", todoURL+"/shares/sharefriend", owner, map[string]interface{}{"role": "viewer"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var share models.Share
+	json.NewDecoder(resp.Body).Decode(&share)
+	resp.Body.Close()
+	assert.Equal(t, "sharefriend", share.Username)
+	assert.Equal(t, models.ShareViewer, share.Role)
+
+	assert.ElementsMatch(t, []uint{parent.ID, child.ID}, visibleIDs(friend))
+	assert.NotContains(t, visibleIDs(stranger), parent.ID)
+	assert.Equal(t, http.StatusOK, status("GET", childURL, friend, nil))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", fmt.Sprintf("%s/todos/%d", server.URL, private.ID), friend, nil))
+	assert.Equal(t, http.StatusCreated, status("POST", todoURL+"/comments", friend, map[string]interface{}{"body": "Window seat please"}))
+	assert.Equal(t, http.StatusUnauthorized, status("PUT", todoURL, friend, map[string]interface{}{"title": "Mine now"}))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", todoURL+"/shares", friend, nil))
+
+	// sharing again changes the role, editors may change the todo but not its owner
+	assert.Equal(t, http.StatusOK, status("PUT", todoURL+"/shares/sharefriend", owner, map[string]interface{}{"role": "editor"}))
+	resp = doJSON(t, client, "PUT", childURL, friend, map[string]interface{}{"title": "Book flights and hotel", "user_id": 999})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var edited models.Todo
+	json.NewDecoder(resp.Body).Decode(&edited)
+	resp.Body.Close()
+	assert.Equal(t, "Book flights and hotel", edited.Title)
+	assert.Equal(t, child.UserID, edited.UserID)
+	assert.Equal(t, http.StatusUnauthorized, status("DELETE", todoURL, friend, nil))
+
+	resp = doJSON(t, client, "POST", server.URL+"/todos/bulk", friend, map[string]interface{}{
+		"mode":       "partial",
+		"operations": []map[string]interface{}{{"op": "complete", "ids": []uint{child.ID, private.ID}}},
+	})
+	var bulk struct {
+		Results []struct {
+			ID     uint `json:"id"`
+			Status int  `json:"status"`
+		} `json:"results"`
+	}
+	json.NewDecoder(resp.Body).Decode(&bulk)
+	resp.Body.Close()
+	statuses := map[uint]int{}
+	for _, result := range bulk.Results {
+		statuses[result.ID] = result.Status
+	}
+	assert.Equal(t, map[uint]int{private.ID: http.StatusUnauthorized, child.ID: http.StatusOK}, statuses)
+
+	resp = doJSON(t, client, "GET", todoURL+"/shares", owner, nil)
+	var shares []models.Share
+	json.NewDecoder(resp.Body).Decode(&shares)
+	resp.Body.Close()
+	if assert.Len(t, shares, 1) {
+		assert.Equal(t, models.ShareEditor, shares[0].Role)
+	}
+
+	// the recipient may leave a share, but not remove anyone else's
+	assert.Equal(t, http.StatusOK, status("PUT", todoURL+"/shares/sharestranger", owner, map[string]interface{}{"role": "viewer"}))
+	assert.Equal(t, http.StatusUnauthorized, status("DELETE", todoURL+"/shares/sharestranger", friend, nil))
+	assert.Equal(t, http.StatusNoContent, status("DELETE", todoURL+"/shares/sharefriend", friend, nil))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", todoURL, friend, nil))
+	assert.Equal(t, http.StatusNoContent, status("DELETE", todoURL+"/shares/sharestranger", owner, nil))
+	assert.Equal(t, http.StatusNotFound, status("DELETE", todoURL+"/shares/sharestranger", owner, nil))
+
+	// sharing a list gives access to every todo in it
+	resp = doJSON(t, client, "POST", server.URL+"/lists", owner, map[string]interface{}{"name": "Groceries"})
+	var list models.List
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	listURL := fmt.Sprintf("%s/lists/%d", server.URL, list.ID)
+	milk := create(map[string]interface{}{"title": "Milk", "list_id": list.ID})
+
+	assert.Equal(t, http.StatusUnauthorized, status("GET", listURL+"/todos", friend, nil))
+	assert.Equal(t, http.StatusOK, status("PUT", listURL+"/shares/sharefriend", owner, map[string]interface{}{"role": "viewer"}))
+	assert.Equal(t, []uint{milk.ID}, visibleIDs(friend))
+	assert.Equal(t, http.StatusOK, status("GET", listURL+"/todos", friend, nil))
+	assert.Equal(t, http.StatusOK, status("GET", fmt.Sprintf("%s/todos/%d/attachments", server.URL, milk.ID), friend, nil))
+	assert.Equal(t, http.StatusUnauthorized, status("POST", listURL+"/todos", friend, map[string]interface{}{"title": "Eggs"}))
+	assert.Equal(t, http.StatusUnauthorized, status("PUT", listURL, friend, map[string]interface{}{"name": "Mine"}))
+
+	assert.Equal(t, http.StatusOK, status("PUT", listURL+"/shares/sharefriend", owner, map[string]interface{}{"role": "editor"}))
+	resp = doJSON(t, client, "POST", listURL+"/todos", friend, map[string]interface{}{"title": "Eggs"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var eggs models.Todo
+	json.NewDecoder(resp.Body).Decode(&eggs)
+	resp.Body.Close()
+	assert.Equal(t, list.UserID, eggs.UserID, "todos added to a shared list belong to its owner")
+	assert.ElementsMatch(t, []uint{milk.ID, eggs.ID}, visibleIDs(friend))
+
+	// deleting the list removes its shares, the todos move to the owner's inbox
+	assert.Equal(t, http.StatusNoContent, status("DELETE", listURL, owner, nil))
+	assert.Empty(t, visibleIDs(friend))
+}
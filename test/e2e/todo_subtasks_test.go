@@ -0,0 +1,76 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoSubtasks(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "subtaskuser")
+
+	create := func(payload map[string]interface{}) models.Todo {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo
+	}
+	root := create(map[string]interface{}{"title": "Move house"})
+	pack := create(map[string]interface{}{"title": "Pack", "parent_id": root.ID})
+	create(map[string]interface{}{"title": "Books", "parent_id": pack.ID})
+	create(map[string]interface{}{"title": "Kitchen", "parent_id": pack.ID, "is_completed": true})
+	create(map[string]interface{}{"title": "Book van", "parent_id": root.ID})
+
+	getSubtree := func() models.Todo {
+		resp := doJSON(t, client, "GET", fmt.Sprintf("%s/todos/%d/subtree", server.URL, root.ID), cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo
+	}
+	tree := getSubtree()
+	if assert.Len(t, tree.Children, 2) {
+		assert.Len(t, tree.Children[0].Children, 2)
+		assert.Equal(t, models.Progress{Completed: 1, Total: 2}, *tree.Children[0].Progress)
+	}
+	assert.Equal(t, models.Progress{Completed: 0, Total: 2}, *tree.Progress)
+
+	// a todo cannot become a subtask of its own subtask
+	resp := doJSON(t, client, "PUT", fmt.Sprintf("%s/todos/%d", server.URL, root.ID), cookie, map[string]interface{}{"parent_id": pack.ID})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	// completing the root with complete_children completes the whole tree
+	resp = doJSON(t, client, "PUT", fmt.Sprintf("%s/todos/%d?complete_children=true", server.URL, root.ID), cookie, map[string]interface{}{"is_completed": true})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	tree = getSubtree()
+	assert.Equal(t, models.Progress{Completed: 2, Total: 2}, *tree.Progress)
+	assert.Equal(t, models.Progress{Completed: 2, Total: 2}, *tree.Children[0].Progress)
+
+	// deleting the root removes the subtasks too
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/todos/%d", server.URL, root.ID), cookie, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "GET", server.URL+"/todos", cookie, nil)
+	var todos []models.Todo
+	json.NewDecoder(resp.Body).Decode(&todos)
+	resp.Body.Close()
+	assert.Empty(t, todos)
+}
@@ -0,0 +1,107 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoTags(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "taguser")
+	otherCookie := registerAndLogin(t, client, server.URL, "othertaguser")
+
+	createTag := func(cookie *http.Cookie, name string) models.Tag {
+		resp := doJSON(t, client, "POST", server.URL+"/tags", cookie, map[string]interface{}{"name": name})
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var tag models.Tag
+		json.NewDecoder(resp.Body).Decode(&tag)
+		return tag
+	}
+	work := createTag(cookie, "work")
+	urgent := createTag(cookie, "urgent")
+	foreign := createTag(otherCookie, "private")
+
+	resp := doJSON(t, client, "POST", server.URL+"/tags", cookie, map[string]interface{}{"name": "work"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	createTodo := func(title string, tagIDs []uint) models.Todo {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": title, "tag_ids": tagIDs})
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo
+	}
+	both := createTodo("Both", []uint{work.ID, urgent.ID})
+	createTodo("Work only", []uint{work.ID})
+	createTodo("Untagged", nil)
+	assert.Len(t, both.Tags, 2)
+
+	// tags of other users cannot be attached
+	resp = doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": "Sneaky", "tag_ids": []uint{foreign.ID}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	titles := func(query string) []string {
+		resp := doJSON(t, client, "GET", server.URL+"/todos?"+query, cookie, nil)
+		defer resp.Body.Close()
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		var result []string
+		for _, todo := range todos {
+			result = append(result, todo.Title)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"Both", "Work only"}, titles("tag=work&tag=urgent"))
+	assert.Equal(t, []string{"Both"}, titles("tag=work&tag=urgent&tag_mode=all"))
+
+	// detach one tag on update
+	resp = doJSON(t, client, "PUT", fmt.Sprintf("%s/todos/%d", server.URL, both.ID), cookie, map[string]interface{}{"tag_ids": []uint{urgent.ID}})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	assert.Equal(t, []string{"Work only"}, titles("tag=work"))
+
+	// deleting a tag detaches it everywhere
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/tags/%d", server.URL, urgent.ID), cookie, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+	assert.Empty(t, titles("tag=urgent"))
+
+	// shared todos match the tags their owner gave them
+	otherWork := createTag(otherCookie, "work")
+	resp = doJSON(t, client, "POST", server.URL+"/todos", otherCookie, map[string]interface{}{"title": "Team offsite", "tag_ids": []uint{otherWork.ID}})
+	var offsite models.Todo
+	json.NewDecoder(resp.Body).Decode(&offsite)
+	resp.Body.Close()
+	resp = doJSON(t, client, "POST", server.URL+"/todos", otherCookie, map[string]interface{}{"title": "Not shared", "tag_ids": []uint{otherWork.ID}})
+	resp.Body.Close()
+	resp = doJSON(t, client, "PUT", fmt.Sprintf("%s/todos/%d/shares/taguser", server.URL, offsite.ID), otherCookie, map[string]interface{}{"role": "viewer"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	assert.Equal(t, []string{"Work only", "Team offsite"}, titles("tag=work"))
+
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/tags/%d", server.URL, foreign.ID), cookie, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	// ids are never run as SQL
+	resp = doJSON(t, client, "GET", server.URL+"/tags/0%20OR%201=1", cookie, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
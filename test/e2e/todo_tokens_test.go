@@ -0,0 +1,94 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo-list/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersonalTokens(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "tokenuser")
+
+	createToken := func(payload map[string]interface{}) (int, models.APIToken) {
+		resp := doJSON(t, client, "POST", server.URL+"/me/tokens", cookie, payload)
+		var token models.APIToken
+		json.NewDecoder(resp.Body).Decode(&token)
+		resp.Body.Close()
+		return resp.StatusCode, token
+	}
+	status := func(method, url, token string, payload interface{}) int {
+		resp := doBearer(t, client, method, url, token, payload)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	code, _ := createToken(map[string]interface{}{"name": " "})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = createToken(map[string]interface{}{"name": "CI", "scope": "admin"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = createToken(map[string]interface{}{"name": "CI", "expires_at": time.Now().Add(-time.Hour)})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, write := createToken(map[string]interface{}{"name": "CI", "scope": "write", "expires_at": time.Now().Add(time.Hour)})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.True(t, strings.HasPrefix(write.Token, write.Prefix))
+	code, read := createToken(map[string]interface{}{"name": "Dashboard"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, models.TokenRead, read.Scope)
+
+	// the token is shown once, listing only returns the prefix
+	resp := doJSON(t, client, "GET", server.URL+"/me/tokens", cookie, nil)
+	var tokens []map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&tokens)
+	resp.Body.Close()
+	if assert.Len(t, tokens, 2) {
+		for _, token := range tokens {
+			assert.NotContains(t, token, "token")
+			assert.NotContains(t, token, "hash")
+		}
+	}
+
+	// write tokens act as the user
+	resp = doBearer(t, client, "POST", server.URL+"/todos", write.Token, map[string]interface{}{"title": "Nightly build"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var todo models.Todo
+	json.NewDecoder(resp.Body).Decode(&todo)
+	resp.Body.Close()
+	todoURL := fmt.Sprintf("%s/todos/%d", server.URL, todo.ID)
+
+	// read tokens can only read
+	assert.Equal(t, http.StatusOK, status("GET", todoURL, read.Token, nil))
+	assert.Equal(t, http.StatusForbidden, status("PUT", todoURL, read.Token, map[string]interface{}{"title": "Changed"}))
+	assert.Equal(t, http.StatusForbidden, status("DELETE", todoURL, read.Token, nil))
+
+	assert.Equal(t, http.StatusUnauthorized, status("GET", todoURL, "tdl_unknown", nil))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", server.URL+"/me/tokens", write.Token, nil), "tokens cannot manage tokens")
+
+	// revoked, expired and disabled users' tokens stop working
+	assert.Equal(t, http.StatusNoContent, doJSON(t, client, "DELETE", fmt.Sprintf("%s/me/tokens/%d", server.URL, read.ID), cookie, nil).StatusCode)
+	assert.Equal(t, http.StatusNotFound, doJSON(t, client, "DELETE", fmt.Sprintf("%s/me/tokens/%d", server.URL, read.ID), cookie, nil).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, status("GET", todoURL, read.Token, nil))
+
+	db.Model(&models.APIToken{}).Where("id = ?", write.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	assert.Equal(t, http.StatusUnauthorized, status("GET", todoURL, write.Token, nil))
+	db.Model(&models.APIToken{}).Where("id = ?", write.ID).Update("expires_at", nil)
+	assert.Equal(t, http.StatusOK, status("GET", todoURL, write.Token, nil))
+
+	db.Model(&models.User{}).Where("username = ?", "tokenuser").Update("disabled_at", time.Now())
+	assert.Equal(t, http.StatusUnauthorized, status("GET", todoURL, write.Token, nil))
+}
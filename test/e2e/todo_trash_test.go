@@ -0,0 +1,127 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo-list/internal/models"
+	"todo-list/internal/repos"
+	"todo-list/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoTrash(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "trashuser")
+	other := registerAndLogin(t, client, server.URL, "trashother")
+
+	create := func(payload map[string]interface{}) models.Todo {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, payload)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo
+	}
+	listTodos := func(path string) []models.Todo {
+		resp := doJSON(t, client, "GET", server.URL+path, cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		return todos
+	}
+	titles := func(todos []models.Todo) []string {
+		var titles []string
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+
+	project := create(map[string]interface{}{"title": "Project"})
+	draft := create(map[string]interface{}{"title": "Draft", "parent_id": project.ID})
+	outline := create(map[string]interface{}{"title": "Outline", "parent_id": project.ID})
+
+	// a subtask deleted on its own, then the whole project
+	resp := doJSON(t, client, "DELETE", fmt.Sprintf("%s/todos/%d", server.URL, outline.ID), cookie, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/todos/%d", server.URL, project.ID), cookie, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+
+	assert.Empty(t, listTodos("/todos"))
+	resp = doJSON(t, client, "GET", fmt.Sprintf("%s/todos/%d", server.URL, project.ID), cookie, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	trash := listTodos("/trash")
+	assert.ElementsMatch(t, []string{"Project", "Draft", "Outline"}, titles(trash))
+	for _, todo := range trash {
+		assert.True(t, todo.DeletedAt.Valid)
+	}
+
+	// other users can neither see nor restore the trash
+	resp = doJSON(t, client, "POST", fmt.Sprintf("%s/trash/%d/restore", server.URL, project.ID), other, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	// restoring the project brings back the subtask deleted with it only
+	resp = doJSON(t, client, "POST", fmt.Sprintf("%s/trash/%d/restore", server.URL, project.ID), cookie, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var restored models.Todo
+	json.NewDecoder(resp.Body).Decode(&restored)
+	resp.Body.Close()
+	assert.False(t, restored.DeletedAt.Valid)
+	assert.Equal(t, models.Progress{Completed: 0, Total: 1}, *restored.Progress)
+	assert.ElementsMatch(t, []string{"Project", "Draft"}, titles(listTodos("/todos")))
+	assert.Equal(t, []string{"Outline"}, titles(listTodos("/trash")))
+
+	// restoring a todo from the trash twice is not possible
+	resp = doJSON(t, client, "POST", fmt.Sprintf("%s/trash/%d/restore", server.URL, draft.ID), cookie, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	// a subtask whose parent is still trashed is restored as a top level todo
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/todos/%d", server.URL, project.ID), cookie, nil)
+	resp.Body.Close()
+	resp = doJSON(t, client, "POST", fmt.Sprintf("%s/trash/%d/restore", server.URL, draft.ID), cookie, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	json.NewDecoder(resp.Body).Decode(&restored)
+	resp.Body.Close()
+	assert.Nil(t, restored.ParentID)
+
+	// purging deletes permanently
+	resp = doJSON(t, client, "DELETE", fmt.Sprintf("%s/trash/%d", server.URL, project.ID), cookie, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+	assert.Equal(t, []string{"Outline"}, titles(listTodos("/trash")))
+	resp = doJSON(t, client, "POST", fmt.Sprintf("%s/trash/%d/restore", server.URL, project.ID), cookie, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+	resp = doJSON(t, client, "POST", server.URL+"/trash/0%20OR%201=1/restore", cookie, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "ids are never run as SQL")
+	resp.Body.Close()
+
+	// the background purge removes todos older than the retention period
+	old := time.Now().Add(-48 * time.Hour)
+	db.Model(&models.Todo{}).Unscoped().Where("id = ?", outline.ID).Update("deleted_at", old)
+	todoService := services.NewTodoService(repos.NewTodoRepository(db), repos.NewListRepository(db))
+	purged, err := todoService.PurgeTrash(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Empty(t, listTodos("/trash"))
+	assert.Equal(t, []string{"Draft"}, titles(listTodos("/todos")))
+}
@@ -0,0 +1,171 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list/internal/models"
+	"todo-list/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoRedo(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	cookie := registerAndLogin(t, client, server.URL, "undouser")
+	other := registerAndLogin(t, client, server.URL, "undoother")
+
+	replay := func(path string, cookie *http.Cookie, status int) services.UndoResult {
+		resp := doJSON(t, client, "POST", server.URL+path, cookie, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, path)
+		var result services.UndoResult
+		if status == http.StatusOK {
+			json.NewDecoder(resp.Body).Decode(&result)
+		}
+		return result
+	}
+	listTitles := func() []string {
+		resp := doJSON(t, client, "GET", server.URL+"/todos", cookie, nil)
+		defer resp.Body.Close()
+		var todos []models.Todo
+		json.NewDecoder(resp.Body).Decode(&todos)
+		var titles []string
+		for _, todo := range todos {
+			titles = append(titles, fmt.Sprintf("%s/%v", todo.Title, todo.IsCompleted))
+		}
+		return titles
+	}
+
+	replay("/undo", cookie, http.StatusConflict)
+
+	resp := doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": "Buy milk"})
+	var todo models.Todo
+	json.NewDecoder(resp.Body).Decode(&todo)
+	resp.Body.Close()
+	todoURL := fmt.Sprintf("%s/todos/%d", server.URL, todo.ID)
+	resp = doJSON(t, client, "POST", server.URL+"/todos", cookie, map[string]interface{}{"title": "Child", "parent_id": todo.ID})
+	resp.Body.Close()
+	resp = doJSON(t, client, "PUT", todoURL, cookie, map[string]interface{}{"title": "Buy oat milk"})
+	resp.Body.Close()
+	resp = doJSON(t, client, "PUT", todoURL+"?complete_children=true", cookie, map[string]interface{}{"is_completed": true})
+	resp.Body.Close()
+	resp = doJSON(t, client, "DELETE", todoURL, cookie, nil)
+	resp.Body.Close()
+	assert.Empty(t, listTitles())
+
+	// other users have their own stack
+	replay("/undo", other, http.StatusConflict)
+
+	// undo walks back through the changes, the delete took the subtask with it
+	result := replay("/undo", cookie, http.StatusOK)
+	assert.Equal(t, "deleted", result.Action)
+	assert.Len(t, result.Todos, 2)
+	assert.ElementsMatch(t, []string{"Buy oat milk/true", "Child/true"}, listTitles())
+
+	result = replay("/undo", cookie, http.StatusOK)
+	assert.Equal(t, "completed", result.Action)
+	assert.ElementsMatch(t, []string{"Buy oat milk/false", "Child/false"}, listTitles())
+
+	replay("/undo", cookie, http.StatusOK)
+	assert.ElementsMatch(t, []string{"Buy milk/false", "Child/false"}, listTitles())
+
+	// redo applies the last undone change again
+	result = replay("/redo", cookie, http.StatusOK)
+	assert.Equal(t, "updated", result.Action)
+	assert.ElementsMatch(t, []string{"Buy oat milk/false", "Child/false"}, listTitles())
+
+	// undoing the creation of the subtask puts it in the trash
+	replay("/undo", cookie, http.StatusOK)
+	result = replay("/undo", cookie, http.StatusOK)
+	assert.Equal(t, "created", result.Action)
+	assert.Equal(t, []string{"Buy milk/false"}, listTitles())
+	replay("/redo", cookie, http.StatusOK)
+	assert.ElementsMatch(t, []string{"Buy milk/false", "Child/false"}, listTitles())
+
+	// a new change clears the redo stack
+	resp = doJSON(t, client, "PUT", todoURL, cookie, map[string]interface{}{"priority": "high"})
+	resp.Body.Close()
+	replay("/redo", cookie, http.StatusConflict)
+
+	// undoing the completion of a recurring todo removes the next occurrence again
+	resp = doJSON(t, client, "PUT", todoURL, cookie, map[string]interface{}{"due_date": "2025-01-06T09:00:00Z", "recurrence": "FREQ=DAILY"})
+	resp.Body.Close()
+	resp = doJSON(t, client, "PUT", todoURL, cookie, map[string]interface{}{"is_completed": true})
+	resp.Body.Close()
+	assert.ElementsMatch(t, []string{"Buy milk/true", "Buy milk/false", "Child/false"}, listTitles())
+	replay("/undo", cookie, http.StatusOK)
+	assert.ElementsMatch(t, []string{"Buy milk/false", "Child/false"}, listTitles())
+	resp = doJSON(t, client, "GET", todoURL, cookie, nil)
+	json.NewDecoder(resp.Body).Decode(&todo)
+	resp.Body.Close()
+	assert.Equal(t, "FREQ=DAILY", todo.Recurrence)
+}
+
+func TestUndoSharedTodo(t *testing.T) {
+	db, err := setupTestDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(db))
+	defer server.Close()
+
+	client := &http.Client{}
+	owner := registerAndLogin(t, client, server.URL, "undoowner")
+	friend := registerAndLogin(t, client, server.URL, "undofriend")
+
+	status := func(method, url string, cookie *http.Cookie, payload interface{}) int {
+		resp := doJSON(t, client, method, url, cookie, payload)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	create := func(title string) string {
+		resp := doJSON(t, client, "POST", server.URL+"/todos", owner, map[string]interface{}{"title": title})
+		defer resp.Body.Close()
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		url := fmt.Sprintf("%s/todos/%d", server.URL, todo.ID)
+		assert.Equal(t, http.StatusOK, status("PUT", url+"/shares/undofriend", owner, map[string]interface{}{"role": "editor"}))
+		return url
+	}
+	title := func(url string) string {
+		resp := doJSON(t, client, "GET", url, owner, nil)
+		defer resp.Body.Close()
+		var todo models.Todo
+		json.NewDecoder(resp.Body).Decode(&todo)
+		return todo.Title
+	}
+
+	// the owner changed the todo after the friend, undoing must not overwrite it
+	todoURL := create("Book venue")
+	assert.Equal(t, http.StatusOK, status("PUT", todoURL, friend, map[string]interface{}{"title": "Book the venue"}))
+	assert.Equal(t, http.StatusOK, status("PUT", todoURL, owner, map[string]interface{}{"title": "Book the hall"}))
+	assert.Equal(t, http.StatusConflict, status("POST", server.URL+"/undo", friend, nil))
+	assert.Equal(t, "Book the hall", title(todoURL))
+	// the stale change was dropped from the stack
+	assert.Equal(t, http.StatusConflict, status("POST", server.URL+"/undo", friend, nil))
+
+	// a revoked share ends undo and redo of the friend's changes
+	todoURL = create("Order catering")
+	assert.Equal(t, http.StatusOK, status("PUT", todoURL, friend, map[string]interface{}{"title": "Order vegan catering"}))
+	assert.Equal(t, http.StatusOK, status("PUT", todoURL, owner, map[string]interface{}{"description": "for 40 people"}))
+	assert.Equal(t, http.StatusNoContent, status("DELETE", todoURL+"/shares/undofriend", owner, nil))
+	assert.Equal(t, http.StatusUnauthorized, status("POST", server.URL+"/undo", friend, nil))
+	assert.Equal(t, "Order vegan catering", title(todoURL))
+
+	todoURL = create("Send invites")
+	assert.Equal(t, http.StatusOK, status("PUT", todoURL, friend, map[string]interface{}{"title": "Send the invites"}))
+	assert.Equal(t, http.StatusOK, status("POST", server.URL+"/undo", friend, nil))
+	assert.Equal(t, http.StatusNoContent, status("DELETE", todoURL+"/shares/undofriend", owner, nil))
+	assert.Equal(t, http.StatusUnauthorized, status("POST", server.URL+"/redo", friend, nil))
+	assert.Equal(t, "Send invites", title(todoURL))
+}